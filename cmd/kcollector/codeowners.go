@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/github"
+)
+
+// codeOwnersLoader はCODEOWNERSをGitHubまたはローカルチェックアウトから取得します
+//
+// 同じコミットのCODEOWNERSは一度だけ取得します。
+type codeOwnersLoader struct {
+	ghWrapper   *github.GHWrapper
+	checkoutDir string
+
+	mu    sync.Mutex
+	cache map[string]*loadedCodeOwners
+}
+
+// loadedCodeOwners は取得したCODEOWNERSとそのパスです
+type loadedCodeOwners struct {
+	path       string
+	codeOwners *collector.CodeOwners
+}
+
+// newCodeOwnersLoader は新しいcodeOwnersLoaderを作成します
+func newCodeOwnersLoader(ghWrapper *github.GHWrapper, checkoutDir string) *codeOwnersLoader {
+	return &codeOwnersLoader{
+		ghWrapper:   ghWrapper,
+		checkoutDir: checkoutDir,
+		cache:       make(map[string]*loadedCodeOwners),
+	}
+}
+
+// Load は指定したコミット時点のCODEOWNERSを取得します
+//
+// refが空の場合はデフォルトブランチ（ローカルチェックアウトの場合は作業ツリー）を参照します。
+// CODEOWNERSが存在しないことを確認できた場合はnilを返し、ネットワーク・認証・レート制限などで
+// 取得に失敗した場合はエラーを返します（失敗は次回に再取得できるようキャッシュしません）。
+func (l *codeOwnersLoader) Load(ctx context.Context, ref string) (*loadedCodeOwners, error) {
+	// PRを並行に取得するため、同じコミットの取得が重複しないようロックする
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if loaded, ok := l.cache[ref]; ok {
+		return loaded, nil
+	}
+
+	var loaded *loadedCodeOwners
+	for _, path := range collector.CodeOwnersLocations {
+		content, err := l.readFile(ctx, path, ref)
+		if isFileNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		loaded = &loadedCodeOwners{path: path, codeOwners: collector.ParseCodeOwners(content)}
+		break
+	}
+
+	l.cache[ref] = loaded
+	return loaded, nil
+}
+
+// readFile はローカルチェックアウトまたはGitHubからファイルを読み込みます
+//
+// ファイルが存在しない場合は github.ErrNotFound または fs.ErrNotExist を返します。
+func (l *codeOwnersLoader) readFile(ctx context.Context, path, ref string) ([]byte, error) {
+	if l.checkoutDir == "" {
+		return l.ghWrapper.GetFileContent(ctx, path, ref)
+	}
+
+	if ref != "" {
+		cmd := exec.CommandContext(ctx, "git", "-C", l.checkoutDir, "show", ref+":"+path)
+		output, err := cmd.Output()
+		if err == nil {
+			return output, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && isMissingPath(string(exitErr.Stderr)) {
+			return nil, fmt.Errorf("%s at %s: %w", path, ref, fs.ErrNotExist)
+		}
+		// コミットが取得されていない場合は作業ツリーの内容を使用する
+	}
+	return os.ReadFile(filepath.Join(l.checkoutDir, filepath.FromSlash(path)))
+}
+
+// isMissingPath は git show のエラーがコミットにパスが存在しないことによるものかどうかを判定します
+func isMissingPath(stderr string) bool {
+	return strings.Contains(stderr, "does not exist in") || strings.Contains(stderr, "exists on disk, but not in")
+}
+
+// isFileNotFound はファイルが存在しないことを確認できたエラーかどうかを判定します
+func isFileNotFound(err error) bool {
+	return errors.Is(err, github.ErrNotFound) || errors.Is(err, fs.ErrNotExist)
+}
+
+// refreshOwnership はCODEOWNERSが変更されていれば既存ドキュメントのオーナーを再解決します
+//
+// ドキュメントのオーナーは最新のCODEOWNERSで解決した現在のオーナーです（resolveOwners と同じ）。
+// loaded が nil の場合は、CODEOWNERSが存在しないことを確認できたものとしてオーナーを空にするため、
+// 取得に失敗した場合は呼び出さないでください。再解決したドキュメント数と、変更があったかどうかを返します。
+func refreshOwnership(ctx context.Context, db *sql.DB, repository string, loaded *loadedCodeOwners) (int, bool, error) {
+	var storedHash string
+	err := db.QueryRowContext(ctx, `SELECT content_hash FROM codeowners WHERE repository = ?`, repository).Scan(&storedHash)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to load codeowners state: %w", err)
+	}
+
+	currentHash := ""
+	currentPath := ""
+	if loaded != nil {
+		currentHash = loaded.codeOwners.Hash()
+		currentPath = loaded.path
+	}
+	if err == nil && storedHash == currentHash {
+		return 0, false, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, file_path FROM documents WHERE repository = ?`, repository)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to query documents: %w", err)
+	}
+
+	owners := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var filePath string
+		if err := rows.Scan(&id, &filePath); err != nil {
+			rows.Close()
+			return 0, false, fmt.Errorf("failed to scan document: %w", err)
+		}
+		if loaded != nil {
+			owners[id] = formatOwners(loaded.codeOwners.Owners(filePath))
+		} else {
+			owners[id] = ""
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, false, fmt.Errorf("error iterating documents: %w", err)
+	}
+	rows.Close()
+
+	for id, owner := range owners {
+		if _, err := tx.ExecContext(ctx, `UPDATE documents SET owners = ? WHERE id = ?`, owner, id); err != nil {
+			return 0, false, fmt.Errorf("failed to update owners: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO codeowners (repository, path, content_hash, resolved_at)
+	VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(repository) DO UPDATE SET
+		path = excluded.path,
+		content_hash = excluded.content_hash,
+		resolved_at = excluded.resolved_at
+	`, repository, currentPath, currentHash)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to save codeowners state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("failed to commit ownership: %w", err)
+	}
+
+	return len(owners), true, nil
+}
+
+// resolveOwners は最新のCODEOWNERSで現在のオーナーを解決します
+//
+// 最新のCODEOWNERSの取得に失敗した場合のみ base（PRのbaseコミット時点のCODEOWNERS）を使用し、
+// 次に最新のCODEOWNERSを取得できた収集で refreshOwnership が現在のオーナーに更新します。
+func resolveOwners(current, base *loadedCodeOwners, filePath string) []string {
+	if current != nil {
+		return current.codeOwners.Owners(filePath)
+	}
+	if base != nil {
+		return base.codeOwners.Owners(filePath)
+	}
+	return nil
+}
+
+// formatOwners はオーナーをDB保存用の空白区切り文字列に変換します
+func formatOwners(owners []string) string {
+	return strings.Join(owners, " ")
+}
@@ -7,15 +7,18 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/pankona/knowledges/internal/collector"
 	"github.com/pankona/knowledges/internal/database"
 	"github.com/pankona/knowledges/internal/github"
 	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
 	"github.com/pankona/knowledges/pkg/config"
 	"github.com/pankona/knowledges/pkg/models"
 )
@@ -29,6 +32,11 @@ func main() {
 		excludeBots    = flag.Bool("exclude-bots", true, "Exclude PRs created by bots")
 		skipProcessed  = flag.Bool("skip-processed", true, "Skip already processed PRs (default: true)")
 		prURL          = flag.String("pr-url", "", "Process specific PR by URL (forces reprocessing)")
+		checkoutDir    = flag.String("checkout", "", "Path to a local checkout of the repository (used instead of the GitHub API for CODEOWNERS and project detection)")
+		fetchContent   = flag.Bool("fetch-content", true, "Fetch file contents at the PR head for language, file role and symbol detection")
+		refreshCache   = flag.Bool("refresh-cache", false, "Ignore cached LLM analyses and overwrite them with fresh results")
+		clearCache     = flag.Bool("clear-cache", false, "Delete all cached LLM analyses before collecting")
+		timeout        = flag.Duration("timeout", 0, "Overall time limit for the collection, e.g. 2h (default: no limit; LLM calls use the per-driver timeout)")
 	)
 	flag.Parse()
 
@@ -111,12 +119,44 @@ func main() {
 
 	// Initialize components
 	ghWrapper := github.NewGHWrapper(targetRepo)
-	llmDriver := llm.NewDriver("claude", []string{"-p"})
+	if _, err := llm.NewChainFromConfig(cfg.LLM); err != nil {
+		log.Fatalf("Invalid llm config: %v", err)
+	}
+	fmt.Printf("🤖 LLM drivers: %s\n", strings.Join(cfg.LLM.DriverChain(), " → "))
+	var cache *llm.SQLCache
+	if !cfg.LLM.Cache.Disabled {
+		cache, err = openCache(context.Background(), db, cfg.LLM.Cache, *refreshCache, *clearCache)
+		if err != nil {
+			log.Fatalf("Failed to prepare LLM cache: %v", err)
+		}
+	}
+	prompts, err := prompt.NewSet(cfg.Prompts)
+	if err != nil {
+		log.Fatalf("Invalid prompts config: %v", err)
+	}
+	for _, t := range prompts.Templates() {
+		fmt.Printf("📝 Prompt template: %s (version %s)\n", t.Name(), t.Version())
+	}
 	commentFilter := collector.NewCommentFilter()
+	commentFilter.SetAuthorReplyPolicy(cfg.Collection.AuthorReplies, *cfg.Collection.AuthorReplyWeight)
+	commentFilter.AddExcludePhrases(cfg.Filter.LearnedPatterns)
 	fileInfoExtractor := collector.NewFileInfoExtractor()
+	if err := configureLanguages(fileInfoExtractor, cfg.Languages); err != nil {
+		log.Fatalf("Invalid languages config: %v", err)
+	}
+	heuristicClassifier := collector.NewHeuristicClassifier()
+
+	// Collections over many PRs take hours, so there is no overall deadline unless -timeout is given
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+	// Ctrl-C stops new fetches and analyses; results already analyzed are still saved
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Step 1: Fetch PRs
 	var prs []github.PullRequest
@@ -196,144 +236,118 @@ func main() {
 		}
 	}
 
-	// Step 2: Process each PR and its comments
-	var totalDocuments int
-
-	for i, pr := range prs {
-		fmt.Printf("\n🔍 Processing PR #%d (%d/%d): %s\n", pr.Number, i+1, len(prs), pr.Title)
-		fmt.Printf("👤 Author: %s\n", pr.Author.Login)
-		fmt.Printf("📅 Created: %s\n", pr.CreatedAt.Format("2006-01-02 15:04:05"))
-
-		// Fetch actual PR comments
-		fmt.Printf("📥 Fetching PR comments...\n")
-		comments, err := ghWrapper.GetPRComments(ctx, pr.Number)
+	// Resolve ownership from the latest CODEOWNERS and re-resolve existing documents when it changed
+	codeOwners := newCodeOwnersLoader(ghWrapper, *checkoutDir)
+	currentCodeOwners, err := codeOwners.Load(ctx, "")
+	// The latest CODEOWNERS could not be loaded: keep the stored ownership and fall back to the PR's base commit
+	var baseCodeOwners *codeOwnersLoader
+	switch {
+	case err != nil:
+		fmt.Printf("⚠️  Failed to load CODEOWNERS, keeping the stored ownership: %v\n", err)
+		baseCodeOwners = codeOwners
+	case currentCodeOwners != nil:
+		fmt.Printf("👥 Loaded CODEOWNERS: %s\n", currentCodeOwners.path)
+	default:
+		fmt.Println("ℹ️  No CODEOWNERS found")
+	}
+	if baseCodeOwners == nil {
+		resolvedCount, changed, err := refreshOwnership(ctx, db, targetRepo, currentCodeOwners)
 		if err != nil {
-			fmt.Printf("⚠️  Failed to fetch comments for PR #%d: %v\n", pr.Number, err)
-			continue
+			log.Fatalf("Failed to refresh ownership: %v", err)
 		}
-
-		if len(comments) == 0 {
-			fmt.Printf("ℹ️  No comments found for PR #%d\n", pr.Number)
-			continue
+		if changed {
+			fmt.Printf("🔄 CODEOWNERS changed, re-resolved ownership for %d documents\n", resolvedCount)
 		}
+	}
 
-		fmt.Printf("✅ Found %d comments\n", len(comments))
-
-		// Filter useful comments
-		fmt.Printf("🔍 Filtering useful comments...\n")
-		filteredComments := commentFilter.FilterComments(comments)
-
-		if len(filteredComments) == 0 {
-			fmt.Printf("ℹ️  No useful comments found after filtering\n")
-			continue
+	// Detect monorepo project roots (go.mod, package.json, ... or configured prefixes)
+	// When detection fails, projects are left empty so that the next collection backfills them
+	if _, err := collector.NewProjectResolver(nil, nil, cfg.Projects.Prefixes); err != nil {
+		log.Fatalf("Invalid projects config: %v", err)
+	}
+	projectResolver, err := loadProjectResolver(ctx, ghWrapper, *checkoutDir, cfg.Projects)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to detect projects, leaving them unset: %v\n", err)
+		projectResolver = nil
+	} else {
+		fmt.Printf("🗂️  Detected %d project roots\n", projectResolver.Roots())
+		if backfilled, err := backfillProjects(ctx, db, targetRepo, projectResolver); err != nil {
+			fmt.Printf("⚠️  Failed to backfill projects: %v\n", err)
+		} else if backfilled > 0 {
+			fmt.Printf("🗂️  Assigned projects to %d existing documents\n", backfilled)
 		}
+	}
 
-		fmt.Printf("✅ %d useful comments after filtering\n", len(filteredComments))
-
-		// Process each filtered comment
-		for j, comment := range filteredComments {
-			fmt.Printf("\n🤖 Analyzing comment %d/%d...\n", j+1, len(filteredComments))
-			fmt.Printf("💬 Author: %s\n", comment.Author.Login)
-			fmt.Printf("📂 File: %s:%d\n", comment.FilePath, comment.LineNumber)
-			fmt.Printf("📝 Content: %.100s...\n", comment.Body)
-
-			// Extract file information
-			language := fileInfoExtractor.ExtractLanguage(comment.FilePath)
-			directory := fileInfoExtractor.ExtractDirectory(comment.FilePath)
-
-			// Create prompt for LLM analysis
-			prompt := fmt.Sprintf(`
-Analyze this code review comment and provide structured output in JSON format:
-
-Context:
-- Repository: %s  
-- PR #%d: %s
-- File: %s (line %d)
-- Language: %s
-- Author: %s
-
-Comment:
-%s
-
-Please provide:
-{
-  "summary": "Detailed actionable review guidance (3-8 sentences) that includes: 1) What to check/ensure, 2) Why it matters (context/reasoning), 3) Specific implementation details or patterns, 4) Code examples if relevant (before/after snippets)",
-  "type": "implementation|security|testing|business|design|maintenance|explanation|bug|noise",
-  "tags": ["relevant", "keywords", "max-5-tags"],
-  "relevance_score": 0.0-1.0
-}
-
-Type definitions:
-- implementation: Code improvement suggestions (performance, refactoring, code quality)
-- security: Security-related concerns or suggestions
-- testing: Test-related comments (test methods, coverage, test cases)
-- business: Business logic, domain knowledge, specifications
-- design: Architecture, design patterns, structure
-- maintenance: Maintainability, readability, naming, code style
-- explanation: Explanations, questions, information sharing
-- bug: Bug reports or issue identification
-- noise: Low-value comments (use relevance_score 0.1-0.3)
-
-Summary guidelines:
-- Start with actionable language: "When reviewing X, ensure...", "Check that...", "Verify..."
-- Explain the reasoning: why this matters, what problems it prevents
-- Include specific technical details: patterns, methods, configurations
-- Add code examples when helpful (use backticks for inline code, triple backticks for blocks)
-- Reference specific files, functions, or patterns mentioned in the comment
-- Extract generalizable principles that apply to similar situations
-- Make it comprehensive enough that a reviewer can apply the knowledge without reading the original comment
-
-Return only the JSON, no other text.
-`, targetRepo, pr.Number, pr.Title, comment.FilePath, comment.LineNumber, language, comment.Author.Login, comment.Body)
-
-			// Analyze with LLM
-			result, err := llmDriver.AnalyzeComment(ctx, prompt)
+	// Load near-duplicate index from existing documents
+	duplicateIndex, err := loadDuplicateIndex(ctx, db, *cfg.Collection.DuplicateDistance)
+	if err != nil {
+		log.Fatalf("Failed to load duplicate index: %v", err)
+	}
+	fmt.Printf("🧬 Loaded %d documents into duplicate index\n", duplicateIndex.Len())
+
+	// Step 2: Fetch and analyze comments in parallel, saving the results in PR order
+	usageRecorder := llm.NewUsageRecorder()
+	startedAt := time.Now()
+	fmt.Printf("⚙️  Processing with %d parallel workers, up to %d comments per LLM call\n", cfg.LLM.Parallel, cfg.Collection.BatchSize)
+	p := &pipeline{
+		db:                  db,
+		out:                 os.Stdout,
+		repository:          targetRepo,
+		parallel:            cfg.LLM.Parallel,
+		batchSize:           cfg.Collection.BatchSize,
+		prompts:             prompts,
+		commentFilter:       commentFilter,
+		fileInfoExtractor:   fileInfoExtractor,
+		heuristicClassifier: heuristicClassifier,
+		projectResolver:     projectResolver,
+		currentCodeOwners:   currentCodeOwners,
+		duplicateIndex:      duplicateIndex,
+		duplicateDistance:   *cfg.Collection.DuplicateDistance,
+		cache:               cache,
+		fetch: func(ctx context.Context, pr github.PullRequest) *prFetch {
+			return fetchPR(ctx, ghWrapper, commentFilter, baseCodeOwners, *fetchContent, pr)
+		},
+		newAnalyzer: func(logf func(format string, args ...interface{})) (llm.BatchAnalyzer, error) {
+			chain, err := llm.NewChainFromConfig(cfg.LLM)
 			if err != nil {
-				fmt.Printf("⚠️  LLM analysis failed: %v\n", err)
-				fmt.Println("📝 Creating fallback analysis...")
-
-				// Create fallback result
-				result = &llm.AnalysisResult{
-					Summary:        fmt.Sprintf("Review comment about %s", comment.FilePath),
-					Type:           "suggestion",
-					Tags:           []string{"review", "feedback"},
-					RelevanceScore: 0.7,
-				}
-			} else {
-				fmt.Println("✅ LLM analysis completed")
+				return nil, err
 			}
-
-			// Create document
-			document := &models.Document{
-				Summary:         result.Summary,
-				OriginalComment: comment.Body,
-				FilePath:        comment.FilePath,
-				DirectoryPath:   directory,
-				Language:        language,
-				Repository:      targetRepo,
-				PRNumber:        pr.Number,
-				PRTitle:         pr.Title,
-				PRURL:           pr.URL,
-				CommentURL:      comment.URL,
-				Author:          comment.Author.Login,
-				CommentType:     result.Type,
-				Tags:            result.Tags,
-				RelevanceScore:  result.RelevanceScore,
-				CommentedAt:     comment.CreatedAt,
-				CollectedAt:     time.Now(),
-				UpdatedAt:       time.Now(),
+			chain.SetFallbackHandler(func(failed *llm.Driver, err error) {
+				logf("⚠️  LLM driver %s failed: %v", failed.Name(), err)
+				logf("🔁 Falling back to the next driver...")
+			})
+			chain.SetRetryHandler(func(driver *llm.Driver, attempt, maxAttempts int, delay time.Duration, err error) {
+				logf("⏳ LLM driver %s attempt %d/%d failed: %v (retrying in %s)", driver.Name(), attempt, maxAttempts, err, delay.Round(time.Millisecond))
+			})
+			chain.SetRepairHandler(func(driver *llm.Driver, repair, maxRepairs int, err error) {
+				logf("🩹 LLM driver %s returned invalid output, asking for a repair (%d/%d): %v", driver.Name(), repair, maxRepairs, err)
+			})
+			if cache != nil {
+				chain.SetCache(cache)
 			}
-
-			// Save to database
-			err = saveDocument(ctx, db, document)
-			if err != nil {
-				fmt.Printf("⚠️  Failed to save document: %v\n", err)
-				continue
-			}
-
-			totalDocuments++
-			fmt.Printf("✅ Document %d saved\n", totalDocuments)
-		}
+			chain.SetUsageHandler(usageRecorder.Record)
+			return chain, nil
+		},
+	}
+	stats, err := p.run(ctx, prs)
+	if err != nil {
+		log.Printf("⚠️  Collection interrupted: %v", err)
+	}
+	totalDocuments, reusedAnalyses := stats.documents, stats.reusedAnalyses
+
+	// 中断後も保存済みの件数を確認できるよう、以降の処理はキャンセルしない
+	ctx = context.WithoutCancel(ctx)
+
+	run := &llm.Run{
+		Repository: targetRepo,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Documents:  totalDocuments,
+		Cancelled:  err != nil,
+		Usages:     usageRecorder.Totals(),
+	}
+	if _, err := llm.SaveRun(ctx, db, run); err != nil {
+		fmt.Printf("⚠️  Failed to save LLM usage: %v\n", err)
 	}
 
 	// Step 3: Final verification
@@ -352,58 +366,262 @@ Return only the JSON, no other text.
 	fmt.Println("====================================")
 	fmt.Printf("✅ Processed %d PRs\n", len(prs))
 	fmt.Printf("✅ Created %d documents\n", totalDocuments)
+	fmt.Printf("✅ Reused %d analyses from near-duplicates\n", reusedAnalyses)
+	if cache != nil {
+		cacheStats := cache.Stats()
+		fmt.Printf("✅ LLM cache hits: %d (misses: %d)\n", cacheStats.Hits, cacheStats.Misses)
+		if cacheStats.Errors > 0 {
+			fmt.Printf("⚠️  LLM cache errors: %d (last: %v)\n", cacheStats.Errors, cache.LastError())
+		}
+	}
+	printUsage(os.Stdout, run.Usages, cfg.Pricing)
 	fmt.Printf("✅ Saved to database: %s\n", dbPath)
 	fmt.Println("\nNext steps:")
-	fmt.Println("- Add parallel processing")
 	fmt.Println("- Implement REST API")
 	fmt.Println("- Add batch processing for large repositories")
 	fmt.Println("- Enhance LLM prompts for better analysis")
 }
 
+// openCache はLLMの分析結果のキャッシュを用意し、期限切れの結果を削除します
+//
+// clear の場合は保存済みの全ての結果を削除し、refresh の場合は保存済みの結果を使わずに上書きします。
+func openCache(ctx context.Context, db *sql.DB, cfg config.CacheConfig, refresh, clear bool) (*llm.SQLCache, error) {
+	cache := llm.NewSQLCache(db, cfg.TTL)
+	cache.SetRefresh(refresh)
+	// SQLiteへの書き込みはパイプラインの保存を行うgoroutineに限る
+	cache.SetDeferWrites(true)
+
+	if clear {
+		cleared, err := cache.Clear(ctx)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("🧹 Cleared %d cached LLM analyses\n", cleared)
+	}
+	pruned, err := cache.Prune(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if refresh {
+		fmt.Printf("💾 LLM cache: refreshing (ttl %s, %d expired entries removed)\n", cfg.TTL, pruned)
+	} else {
+		fmt.Printf("💾 LLM cache: enabled (ttl %s, %d expired entries removed)\n", cfg.TTL, pruned)
+	}
+	return cache, nil
+}
+
 // saveDocument はドキュメントをデータベースに保存します
 func saveDocument(ctx context.Context, db *sql.DB, document *models.Document) error {
 	query := `
 	INSERT INTO documents (
-		summary, original_comment, file_path, directory_path, language,
+		summary, original_comment, file_path, line_number, directory_path, project, symbol, language, file_role, owners,
 		repository, pr_number, pr_title, pr_url, comment_url,
-		author, comment_type, tags, relevance_score,
+		author, comment_type, tags, relevance_score, severity, analysis_method, llm_driver, llm_model, prompt_version, repair_count,
+		llm_input_tokens, llm_output_tokens, llm_tokens_estimated, llm_latency_ms,
+		comment_role, pr_author,
+		text_hash, duplicate_group, duplicate_of,
 		commented_at, collected_at, updated_at
 	) VALUES (
+		?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
 		?, ?, ?, ?, ?,
-		?, ?, ?, ?, ?,
+		?, ?, ?, ?, ?, ?, ?, ?, ?,
 		?, ?, ?, ?,
+		?, ?,
+		?, ?, ?,
 		?, ?, ?
 	) ON CONFLICT(repository, pr_number, comment_url) DO UPDATE SET
 		summary = excluded.summary,
 		original_comment = excluded.original_comment,
 		file_path = excluded.file_path,
+		line_number = excluded.line_number,
 		directory_path = excluded.directory_path,
+		project = excluded.project,
+		symbol = excluded.symbol,
 		language = excluded.language,
+		file_role = excluded.file_role,
+		owners = excluded.owners,
 		pr_title = excluded.pr_title,
 		author = excluded.author,
 		comment_type = excluded.comment_type,
 		tags = excluded.tags,
 		relevance_score = excluded.relevance_score,
+		severity = excluded.severity,
+		analysis_method = excluded.analysis_method,
+		llm_driver = excluded.llm_driver,
+		llm_model = excluded.llm_model,
+		prompt_version = excluded.prompt_version,
+		repair_count = excluded.repair_count,
+		llm_input_tokens = excluded.llm_input_tokens,
+		llm_output_tokens = excluded.llm_output_tokens,
+		llm_tokens_estimated = excluded.llm_tokens_estimated,
+		llm_latency_ms = excluded.llm_latency_ms,
+		comment_role = excluded.comment_role,
+		pr_author = excluded.pr_author,
+		text_hash = excluded.text_hash,
+		duplicate_group = excluded.duplicate_group,
+		duplicate_of = excluded.duplicate_of,
 		updated_at = excluded.updated_at
 	`
 
+	analysisMethod := document.AnalysisMethod
+	if analysisMethod == "" {
+		analysisMethod = models.AnalysisMethodLLM
+	}
+
 	tagsStr := ""
 	if len(document.Tags) > 0 {
 		tagsStr = fmt.Sprintf("%v", document.Tags) // Simple serialization
 	}
 
 	_, err := db.ExecContext(ctx, query,
-		document.Summary, document.OriginalComment, document.FilePath,
-		document.DirectoryPath, document.Language,
+		document.Summary, document.OriginalComment, document.FilePath, document.LineNumber,
+		document.DirectoryPath, document.Project, document.Symbol, document.Language, document.FileRole, formatOwners(document.Owners),
 		document.Repository, document.PRNumber, document.PRTitle,
 		document.PRURL, document.CommentURL,
-		document.Author, document.CommentType, tagsStr, document.RelevanceScore,
+		document.Author, document.CommentType, tagsStr, document.RelevanceScore, document.Severity, analysisMethod, document.LLMDriver, document.LLMModel, document.PromptVersion, document.RepairCount,
+		document.LLMInputTokens, document.LLMOutputTokens, document.LLMTokensEstimated, document.LLMLatencyMS,
+		document.CommentRole, document.PRAuthor,
+		document.TextHash, document.DuplicateGroup, document.DuplicateOf,
 		document.CommentedAt, document.CollectedAt, document.UpdatedAt,
 	)
 
 	return err
 }
 
+// lineNumber はコメント対象の行番号を保存用に変換します（行に紐づかないコメントは nil）
+func lineNumber(line int) *int {
+	if line <= 0 {
+		return nil
+	}
+	return &line
+}
+
+// fetchFileContent はPRのheadコミット時点のファイル内容を取得します
+//
+// パスやコミットが不明な場合は取得せずにnilを返します。
+func fetchFileContent(ctx context.Context, ghWrapper *github.GHWrapper, filePath, ref string) ([]byte, error) {
+	if filePath == "" || ref == "" {
+		return nil, nil
+	}
+	return ghWrapper.GetFileContent(ctx, filePath, ref)
+}
+
+// configureLanguages は設定ファイルの言語判定ルールを適用します
+func configureLanguages(extractor *collector.FileInfoExtractor, languages config.LanguagesConfig) error {
+	extractor.AddLanguageExtensions(languages.Extensions)
+	extractor.AddLanguageFilenames(languages.Filenames)
+
+	// 設定ファイルで先に書かれたルールが優先されるよう逆順に追加する
+	for i := len(languages.Paths) - 1; i >= 0; i-- {
+		rule := languages.Paths[i]
+		if err := extractor.AddLanguagePathRule(rule.Pattern, rule.Language); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// heuristicAnalysis はLLM分析に失敗した場合のルールベース分類結果を作成します
+func heuristicAnalysis(classifier *collector.HeuristicClassifier, comment github.Comment) *llm.AnalysisResult {
+	c := classifier.Classify(comment)
+	return &llm.AnalysisResult{
+		Summary:        c.Summary,
+		Type:           c.Type,
+		Tags:           c.Tags,
+		RelevanceScore: c.Confidence,
+		Severity:       c.Severity,
+	}
+}
+
+// loadDuplicateIndex は既存ドキュメントからニアデュプリケート検索用のインデックスを作成します
+func loadDuplicateIndex(ctx context.Context, db *sql.DB, maxDistance int) (*collector.DuplicateIndex, error) {
+	index := collector.NewDuplicateIndex(maxDistance)
+
+	query := `SELECT id, repository, pr_number, comment_url, text_hash, duplicate_group, analysis_method FROM documents WHERE text_hash != ''`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document hashes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var prNumber int
+		var repository, commentURL, textHash, group, analysisMethod string
+		if err := rows.Scan(&id, &repository, &prNumber, &commentURL, &textHash, &group, &analysisMethod); err != nil {
+			return nil, fmt.Errorf("failed to scan document hash: %w", err)
+		}
+
+		hash, err := collector.ParseHash(textHash)
+		if err != nil {
+			continue
+		}
+		index.Add(collector.DuplicateEntry{
+			DocumentID: id,
+			Key:        collector.DocumentKey(repository, prNumber, commentURL),
+			Hash:       hash,
+			Group:      group,
+			Reusable:   analysisMethod == models.AnalysisMethodLLM,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating document hashes: %w", err)
+	}
+
+	return index, nil
+}
+
+// reusedAnalysis は既存ドキュメントの分析結果と、それを生成したドライバー・プロンプトです
+type reusedAnalysis struct {
+	result        *llm.AnalysisResult
+	driver        string
+	model         string
+	promptVersion string
+}
+
+// loadAnalysis は既存ドキュメントの分析結果を読み込みます
+func loadAnalysis(ctx context.Context, db *sql.DB, documentID int64) (*reusedAnalysis, error) {
+	var result llm.AnalysisResult
+	var tagsStr sql.NullString
+	reused := &reusedAnalysis{result: &result}
+
+	query := `SELECT summary, comment_type, tags, relevance_score, severity, llm_driver, llm_model, prompt_version FROM documents WHERE id = ?`
+	err := db.QueryRowContext(ctx, query, documentID).Scan(&result.Summary, &result.Type, &tagsStr, &result.RelevanceScore, &result.Severity,
+		&reused.driver, &reused.model, &reused.promptVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis: %w", err)
+	}
+
+	result.Tags = parseTags(tagsStr.String)
+	return reused, nil
+}
+
+// describeDriver はドライバー名とモデル名を表示用に整形します
+func describeDriver(driver, model string) string {
+	if model == "" {
+		return driver
+	}
+	return driver + "/" + model
+}
+
+// parseTags はsaveDocumentでシリアライズしたタグ文字列を分解します
+func parseTags(tagsStr string) []string {
+	return strings.Fields(strings.Trim(tagsStr, "[]"))
+}
+
+// findDocumentID は保存済みドキュメントのIDを取得します
+func findDocumentID(ctx context.Context, db *sql.DB, document *models.Document) (int64, error) {
+	var id int64
+	query := `SELECT id FROM documents WHERE repository = ? AND pr_number = ? AND comment_url = ?`
+	err := db.QueryRowContext(ctx, query, document.Repository, document.PRNumber, document.CommentURL).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query document id: %w", err)
+	}
+	return id, nil
+}
+
 // getProcessedPRNumbers は指定されたリポジトリで既に処理済みのPR番号リストを取得します
 func getProcessedPRNumbers(ctx context.Context, db *sql.DB, repository string) (map[int]bool, error) {
 	query := `SELECT DISTINCT pr_number FROM documents WHERE repository = ?`
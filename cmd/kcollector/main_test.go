@@ -1,12 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/pankona/knowledges/internal/collector"
 	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/internal/database/dbtest"
 	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/pkg/config"
 	"github.com/pankona/knowledges/pkg/models"
 )
 
@@ -219,4 +227,215 @@ func TestDeletePRData_Success(t *testing.T) {
 	if count124 != 1 {
 		t.Errorf("expected 1 document for PR 124 after deletion, got %d", count124)
 	}
-}
\ No newline at end of file
+}
+
+func TestLoadDuplicateIndex_ReusesSavedAnalysis(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	normalized := collector.NormalizeComment("Please wrap errors with %w so callers can inspect them.")
+	hash := collector.SimHash(normalized)
+
+	ctx := context.Background()
+	doc := &models.Document{
+		Summary:         "Wrap errors with %w",
+		OriginalComment: "Please wrap errors with %w so callers can inspect them.",
+		FilePath:        "main.go",
+		DirectoryPath:   ".",
+		Language:        "go",
+		Repository:      "owner/repo",
+		PRNumber:        1,
+		PRTitle:         "PR 1",
+		PRURL:           "https://github.com/owner/repo/pull/1",
+		CommentURL:      "https://github.com/owner/repo/pull/1#discussion_r1",
+		Author:          "reviewer1",
+		CommentType:     "maintenance",
+		Tags:            []string{"errors", "wrapping"},
+		RelevanceScore:  0.9,
+		LLMDriver:       "claude",
+		LLMModel:        "sonnet",
+		TextHash:        collector.FormatHash(hash),
+		DuplicateGroup:  collector.NewDuplicateGroup(hash),
+	}
+	if err := saveDocument(ctx, db, doc); err != nil {
+		t.Fatalf("Failed to save test document: %v", err)
+	}
+
+	// Act
+	index, err := loadDuplicateIndex(ctx, db, collector.DefaultDuplicateDistance)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	match, found := index.Find(normalized, hash, "")
+	if !found {
+		t.Fatal("expected saved document to be found as a near-duplicate")
+	}
+	reused, err := loadAnalysis(ctx, db, match.DocumentID)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if match.Group != doc.DuplicateGroup {
+		t.Errorf("expected group %q, got %q", doc.DuplicateGroup, match.Group)
+	}
+	result := reused.result
+	if result.Type != "maintenance" || result.Summary != "Wrap errors with %w" {
+		t.Errorf("unexpected analysis: %+v", result)
+	}
+	if len(result.Tags) != 2 || result.Tags[0] != "errors" || result.Tags[1] != "wrapping" {
+		t.Errorf("expected tags [errors wrapping], got %v", result.Tags)
+	}
+	if reused.driver != "claude" || reused.model != "sonnet" {
+		t.Errorf("expected the reused analysis to keep its driver, got %s/%s", reused.driver, reused.model)
+	}
+}
+
+func TestRefreshOwnership_ReResolvesWhenCodeOwnersChanges(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	ctx := context.Background()
+	doc := &models.Document{
+		Summary:         "Validate amounts",
+		OriginalComment: "Please validate the amount before charging.",
+		FilePath:        "services/payments/charge.go",
+		DirectoryPath:   "services/payments",
+		Language:        "go",
+		Repository:      "owner/repo",
+		PRNumber:        1,
+		PRTitle:         "PR 1",
+		PRURL:           "https://github.com/owner/repo/pull/1",
+		CommentURL:      "https://github.com/owner/repo/pull/1#discussion_r1",
+		Author:          "reviewer1",
+		CommentType:     "business",
+		RelevanceScore:  0.9,
+	}
+	if err := saveDocument(ctx, db, doc); err != nil {
+		t.Fatalf("Failed to save test document: %v", err)
+	}
+
+	ownersOf := func() string {
+		var owners string
+		if err := db.QueryRow(`SELECT owners FROM documents WHERE comment_url = ?`, doc.CommentURL).Scan(&owners); err != nil {
+			t.Fatalf("Failed to query owners: %v", err)
+		}
+		return owners
+	}
+
+	first := &loadedCodeOwners{path: "CODEOWNERS", codeOwners: collector.ParseCodeOwners([]byte("/services/payments/ @org/payments\n"))}
+	second := &loadedCodeOwners{path: "CODEOWNERS", codeOwners: collector.ParseCodeOwners([]byte("/services/payments/ @org/billing @alice\n"))}
+
+	// Act & Assert: 初回は解決される
+	count, changed, err := refreshOwnership(ctx, db, "owner/repo", first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !changed || count != 1 {
+		t.Errorf("expected 1 document re-resolved, got changed=%v count=%d", changed, count)
+	}
+	if got := ownersOf(); got != "@org/payments" {
+		t.Errorf("expected owners %q, got %q", "@org/payments", got)
+	}
+
+	// Act & Assert: 同じ内容では再解決しない
+	_, changed, err = refreshOwnership(ctx, db, "owner/repo", first)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed {
+		t.Error("expected no re-resolution when CODEOWNERS is unchanged")
+	}
+
+	// Act & Assert: 変更されたら再解決される
+	_, changed, err = refreshOwnership(ctx, db, "owner/repo", second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !changed {
+		t.Error("expected re-resolution when CODEOWNERS changed")
+	}
+	if got := ownersOf(); got != "@org/billing @alice" {
+		t.Errorf("expected owners %q, got %q", "@org/billing @alice", got)
+	}
+}
+
+// fileExecutor はGitHub APIのファイル取得をモックします（存在しないファイルは404）
+type fileExecutor struct {
+	files map[string]string
+	err   error
+}
+
+func (e *fileExecutor) Execute(ctx context.Context, cmd string, args ...string) ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	for path, content := range e.files {
+		if strings.HasSuffix(args[1], "/contents/"+path) {
+			return []byte(content), nil
+		}
+	}
+	return nil, errors.New("gh: Not Found (HTTP 404)")
+}
+
+func TestCodeOwnersLoader_Load_DistinguishesMissingFromFailure(t *testing.T) {
+	ctx := context.Background()
+	newLoader := func(executor *fileExecutor) *codeOwnersLoader {
+		ghWrapper := github.NewGHWrapper("owner/repo")
+		ghWrapper.SetExecutor(executor)
+		return newCodeOwnersLoader(ghWrapper, "")
+	}
+
+	// Act & Assert: 見つかった最初の場所のCODEOWNERSを読み込む
+	loaded, err := newLoader(&fileExecutor{files: map[string]string{"CODEOWNERS": "/services/ @org/services\n"}}).Load(ctx, "")
+	if err != nil || loaded == nil || loaded.path != "CODEOWNERS" {
+		t.Fatalf("expected CODEOWNERS to be loaded, got %+v, %v", loaded, err)
+	}
+
+	// Act & Assert: 全ての場所で404の場合は存在しない
+	loaded, err = newLoader(&fileExecutor{}).Load(ctx, "")
+	if err != nil || loaded != nil {
+		t.Errorf("expected no CODEOWNERS without an error, got %+v, %v", loaded, err)
+	}
+
+	// Act & Assert: レート制限などの失敗はエラーとして返し、キャッシュしない
+	executor := &fileExecutor{files: map[string]string{".github/CODEOWNERS": "* @org/all\n"}, err: errors.New("gh: API rate limit exceeded (HTTP 403)")}
+	loader := newLoader(executor)
+	if loaded, err = loader.Load(ctx, ""); err == nil || loaded != nil {
+		t.Errorf("expected the failure to be returned, got %+v, %v", loaded, err)
+	}
+	executor.err = nil
+	if loaded, err = loader.Load(ctx, ""); err != nil || loaded == nil || loaded.path != ".github/CODEOWNERS" {
+		t.Errorf("expected the failure not to be cached, got %+v, %v", loaded, err)
+	}
+
+	// Act & Assert: ローカルチェックアウトに存在しない場合も存在しない
+	loaded, err = newCodeOwnersLoader(nil, t.TempDir()).Load(ctx, "")
+	if err != nil || loaded != nil {
+		t.Errorf("expected no CODEOWNERS in an empty checkout, got %+v, %v", loaded, err)
+	}
+}
+
+func TestPrintUsage(t *testing.T) {
+	// Arrange
+	usages := []llm.DriverUsage{
+		{Driver: "claude", Model: "sonnet", Usage: llm.Usage{Calls: 2, InputTokens: 1500, OutputTokens: 300, Latency: 2 * time.Second}},
+		{Driver: "local", Usage: llm.Usage{Calls: 1, InputTokens: 50, OutputTokens: 10, EstimatedCalls: 1}},
+	}
+	pricing := config.PricingConfig{Currency: "USD", Models: map[string]config.Price{"sonnet": {Input: 3, Output: 15}}}
+
+	// Act
+	var out bytes.Buffer
+	printUsage(&out, usages, pricing)
+
+	// Assert
+	for _, want := range []string{
+		"claude/sonnet: 2 calls, 1500 input / 300 output tokens, 2s, 0.0090 USD",
+		"local: 1 calls, 50 input / 10 output tokens (estimated for 1 calls), 0s, no price configured",
+		"Total cost: 0.0090 USD",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// pipeline はPRの取得・コメントの分析・保存を並行に行います
+//
+// PRの取得とLLM分析はそれぞれ parallel 個まで並行に実行し、LLMには batchSize 件までの
+// コメントをまとめて送ります。重複判定と保存・出力は
+// PRとコメントの順に1つのgoroutineで行うため、結果は並行数に関わらず同じになります。
+type pipeline struct {
+	db         *sql.DB
+	out        io.Writer
+	repository string
+	parallel   int
+	batchSize  int
+	prompts    *prompt.Set
+
+	commentFilter       *collector.CommentFilter
+	fileInfoExtractor   *collector.FileInfoExtractor
+	heuristicClassifier *collector.HeuristicClassifier
+	projectResolver     *collector.ProjectResolver
+	currentCodeOwners   *loadedCodeOwners
+	duplicateIndex      *collector.DuplicateIndex
+	duplicateDistance   int
+	// cache は書き込みを保留するLLMのキャッシュです（書き込みは保存と同じgoroutineで行う）
+	cache *llm.SQLCache
+
+	// fetch はPRのコメントとファイル内容を取得します
+	fetch func(ctx context.Context, pr github.PullRequest) *prFetch
+	// newAnalyzer はワーカーごとのLLMを作成します。logf は分析中のジョブのログに追記します
+	newAnalyzer func(logf func(format string, args ...interface{})) (llm.BatchAnalyzer, error)
+}
+
+// prFetch はPRごとに取得したデータです
+type prFetch struct {
+	pr             github.PullRequest
+	log            []string
+	comments       []github.Comment
+	contents       map[string][]byte
+	baseCodeOwners *loadedCodeOwners
+}
+
+// logf はPRのログに追記します
+func (f *prFetch) logf(format string, args ...interface{}) {
+	f.log = append(f.log, fmt.Sprintf(format, args...))
+}
+
+// prBlock は1つのPRの出力と分析ジョブです
+type prBlock struct {
+	index int
+	fetch *prFetch
+	jobs  []*commentJob
+}
+
+// commentJob は1件のコメントの分析ジョブです
+type commentJob struct {
+	pr               github.PullRequest
+	comment          github.Comment
+	document         *models.Document
+	template         *prompt.Template
+	commentContext   string
+	prompt           string
+	explicitSeverity string
+	textHash         uint64
+
+	// reused は既存ドキュメントから再利用する分析結果です
+	reused     *reusedAnalysis
+	reusedFrom int64
+	// leader は同じ実行内で先に分析するニアデュプリケートです
+	leader *commentJob
+
+	// ワーカーが設定する分析結果
+	analysis *llm.AnalysisResult
+	driver   *llm.Driver
+	err      error
+	log      []string
+	done     chan struct{}
+
+	// 保存後に設定する値
+	analysisMethod string
+	documentID     int64
+}
+
+// needsAnalysis はLLMによる分析が必要なジョブかどうかを返します
+func (j *commentJob) needsAnalysis() bool {
+	return j.reused == nil && j.leader == nil && j.err == nil
+}
+
+// pipelineStats は実行結果の集計です
+type pipelineStats struct {
+	documents      int
+	reusedAnalyses int
+	cancelled      int
+}
+
+// run はPRを処理し、全てのドキュメントを保存するかキャンセルされるまで待ちます
+func (p *pipeline) run(ctx context.Context, prs []github.PullRequest) (pipelineStats, error) {
+	parallel := p.parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	// Stage 1: PRのコメントとファイル内容を並行に取得
+	fetched := make([]chan *prFetch, len(prs))
+	for i := range prs {
+		fetched[i] = make(chan *prFetch, 1)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.fetchAll(ctx, prs, fetched, parallel)
+	}()
+
+	// Stage 2: LLM分析のワーカー
+	analyzers := make([]*jobAnalyzer, parallel)
+	for i := range analyzers {
+		analyzer := &jobAnalyzer{}
+		var err error
+		analyzer.analyzer, err = p.newAnalyzer(analyzer.logf)
+		if err != nil {
+			return pipelineStats{}, fmt.Errorf("failed to create LLM analyzer: %w", err)
+		}
+		analyzers[i] = analyzer
+	}
+	jobs := make(chan []*commentJob)
+	for _, analyzer := range analyzers {
+		wg.Add(1)
+		go func(analyzer *jobAnalyzer) {
+			defer wg.Done()
+			for batch := range jobs {
+				analyzer.analyze(ctx, batch)
+			}
+		}(analyzer)
+	}
+
+	// 重複判定はPRとコメントの順に行い、分析が必要なジョブをワーカーへ渡す
+	blocks := make(chan *prBlock, len(prs))
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		defer close(blocks)
+		p.plan(ctx, prs, fetched, blocks, jobs)
+	}()
+
+	// Stage 3: 結果を順に出力・保存（SQLiteへの書き込みはこのgoroutineのみ）
+	var stats pipelineStats
+	for block := range blocks {
+		p.write(ctx, block, len(prs), &stats)
+	}
+	if ctx.Err() != nil {
+		// 取得前にキャンセルされたPRのコメントは件数が分からないため、取得済みのものだけを数える
+		fmt.Fprintf(p.out, "\n⏹️  Cancelled: %d fetched comments were not analyzed and not saved\n", stats.cancelled)
+	}
+	wg.Wait()
+	// 最後のPRの後に終わった分析の書き込み
+	p.flushCache(context.WithoutCancel(ctx))
+
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// fetchAll はPRを parallel 個まで並行に取得します
+func (p *pipeline) fetchAll(ctx context.Context, prs []github.PullRequest, fetched []chan *prFetch, parallel int) {
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, pr := range prs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			// 取得しないPRも後段が待たないよう空の結果を渡す
+			fetch := &prFetch{pr: pr}
+			fetch.logf("⏹️  Skipped: %v", ctx.Err())
+			fetched[i] <- fetch
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, pr github.PullRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fetched[i] <- p.fetch(ctx, pr)
+		}(i, pr)
+	}
+	wg.Wait()
+}
+
+// plan はPRの順に分析ジョブを作成し、ニアデュプリケートの再利用を決定します
+//
+// 分析が必要なジョブはPRをまたいで、同じプロンプトテンプレートのもの batchSize 件ずつまとめて
+// ワーカーへ渡します。
+func (p *pipeline) plan(ctx context.Context, prs []github.PullRequest, fetched []chan *prFetch, blocks chan<- *prBlock, jobs chan<- []*commentJob) {
+	// 今回の実行で分析するコメントの索引（DocumentID にはジョブの通し番号を使用）
+	runIndex := collector.NewDuplicateIndex(p.duplicateDistance)
+	var runJobs []*commentJob
+
+	batchSize := p.batchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	// 共通の指示はテンプレートごとに異なるため、テンプレートごとにまとめる
+	pending := make(map[*prompt.Template][]*commentJob)
+	var order []*prompt.Template
+	dispatch := func(t *prompt.Template) {
+		batch := pending[t]
+		if len(batch) == 0 {
+			return
+		}
+		select {
+		case jobs <- batch:
+		case <-ctx.Done():
+			for _, job := range batch {
+				job.err = ctx.Err()
+				close(job.done)
+			}
+		}
+		pending[t] = nil
+	}
+	defer func() {
+		for _, t := range order {
+			dispatch(t)
+		}
+	}()
+
+	for i := range prs {
+		fetch := <-fetched[i]
+		block := &prBlock{index: i, fetch: fetch}
+
+		for _, comment := range fetch.comments {
+			job := p.newJob(fetch, comment)
+
+			normalized := collector.NormalizeComment(comment.Body)
+			// 再収集したコメントが保存済みの自身と一致しないよう、自身のキーを除外する
+			key := collector.DocumentKey(job.document.Repository, job.document.PRNumber, job.document.CommentURL)
+			if match, found := p.duplicateIndex.Find(normalized, job.textHash, key); found {
+				job.document.DuplicateGroup = match.Group
+				if match.Reusable {
+					reused, err := loadAnalysis(ctx, p.db, match.DocumentID)
+					if err != nil {
+						job.log = append(job.log, fmt.Sprintf("⚠️  Failed to load analysis of document #%d: %v", match.DocumentID, err))
+					} else {
+						job.reused = reused
+						job.reusedFrom = match.DocumentID
+					}
+				}
+			}
+			if job.reused == nil {
+				if match, found := runIndex.Find(normalized, job.textHash, ""); found {
+					job.leader = runJobs[match.DocumentID]
+					job.document.DuplicateGroup = job.leader.document.DuplicateGroup
+				} else {
+					runIndex.Add(collector.DuplicateEntry{
+						DocumentID: int64(len(runJobs)),
+						Hash:       job.textHash,
+						Group:      job.document.DuplicateGroup,
+						Reusable:   true,
+					})
+					runJobs = append(runJobs, job)
+				}
+			}
+
+			block.jobs = append(block.jobs, job)
+		}
+
+		blocks <- block
+
+		for _, job := range block.jobs {
+			if !job.needsAnalysis() {
+				close(job.done)
+				continue
+			}
+			if _, ok := pending[job.template]; !ok {
+				order = append(order, job.template)
+			}
+			pending[job.template] = append(pending[job.template], job)
+			if len(pending[job.template]) == batchSize {
+				dispatch(job.template)
+			}
+		}
+	}
+}
+
+// newJob はコメントのファイル情報とプロンプトからジョブを作成します
+func (p *pipeline) newJob(fetch *prFetch, comment github.Comment) *commentJob {
+	pr := fetch.pr
+	content := fetch.contents[comment.FilePath]
+	language := p.fileInfoExtractor.DetectLanguage(comment.FilePath, content)
+	symbol := collector.ExtractSymbol(language, content, comment.LineNumber, comment.DiffHunk)
+	explicitSeverity := collector.DetectSeverity(comment.Body)
+	textHash := collector.SimHash(collector.NormalizeComment(comment.Body))
+	// プロジェクトの検出に失敗した場合は空のままにする（次に検出できた収集で設定される）
+	project := ""
+	if p.projectResolver != nil {
+		project = p.projectResolver.Project(comment.FilePath)
+	}
+
+	job := &commentJob{
+		pr:               pr,
+		comment:          comment,
+		template:         p.prompts.Select(p.repository, language),
+		explicitSeverity: explicitSeverity,
+		textHash:         textHash,
+		done:             make(chan struct{}),
+		document: &models.Document{
+			OriginalComment: comment.Body,
+			FilePath:        comment.FilePath,
+			LineNumber:      lineNumber(comment.LineNumber),
+			DirectoryPath:   p.fileInfoExtractor.ExtractDirectory(comment.FilePath),
+			Project:         project,
+			Symbol:          symbol,
+			Language:        language,
+			FileRole:        p.fileInfoExtractor.ClassifyFileRole(comment.FilePath, content),
+			Owners:          resolveOwners(p.currentCodeOwners, fetch.baseCodeOwners, comment.FilePath),
+			Repository:      p.repository,
+			PRNumber:        pr.Number,
+			PRTitle:         pr.Title,
+			PRURL:           pr.URL,
+			CommentURL:      comment.URL,
+			PRAuthor:        pr.Author.Login,
+			Author:          comment.Author.Login,
+			CommentRole:     comment.Role,
+			TextHash:        collector.FormatHash(textHash),
+			DuplicateGroup:  collector.NewDuplicateGroup(textHash),
+			CommentedAt:     comment.CreatedAt,
+		},
+	}
+
+	// テンプレートを実行できない場合は分析せずにルールベースで分類する
+	data := prompt.Comment{
+		Repository: p.repository,
+		PRNumber:   pr.Number,
+		PRTitle:    pr.Title,
+		FilePath:   comment.FilePath,
+		LineNumber: comment.LineNumber,
+		Symbol:     symbol,
+		Language:   language,
+		Author:     comment.Author.Login,
+		Role:       comment.Role,
+		Severity:   explicitSeverity,
+		Body:       comment.Body,
+	}.Data()
+	if job.commentContext, job.err = job.template.Context(data); job.err == nil {
+		job.prompt, job.err = job.template.Single(data)
+	}
+	return job
+}
+
+// write はPRのジョブを順に待ち、結果を出力してドキュメントを保存します
+func (p *pipeline) write(ctx context.Context, block *prBlock, total int, stats *pipelineStats) {
+	pr := block.fetch.pr
+	fmt.Fprintf(p.out, "\n🔍 Processing PR #%d (%d/%d): %s\n", pr.Number, block.index+1, total, pr.Title)
+	fmt.Fprintf(p.out, "👤 Author: %s\n", pr.Author.Login)
+	fmt.Fprintf(p.out, "📅 Created: %s\n", pr.CreatedAt.Format("2006-01-02 15:04:05"))
+	for _, line := range block.fetch.log {
+		fmt.Fprintln(p.out, line)
+	}
+
+	// キャンセル後も分析済みの結果は保存できるよう、書き込みはキャンセルしない
+	writeCtx := context.WithoutCancel(ctx)
+
+	for j, job := range block.jobs {
+		<-job.done
+
+		if isCancelled(ctx, job) {
+			stats.cancelled++
+			continue
+		}
+
+		fmt.Fprintf(p.out, "\n🤖 Analyzing comment %d/%d...\n", j+1, len(block.jobs))
+		fmt.Fprintf(p.out, "💬 Author: %s (%s)\n", job.comment.Author.Login, job.comment.Role)
+		fmt.Fprintf(p.out, "📂 File: %s:%d\n", job.comment.FilePath, job.comment.LineNumber)
+		fmt.Fprintf(p.out, "📝 Content: %.100s...\n", job.comment.Body)
+		if job.document.Symbol != "" {
+			fmt.Fprintf(p.out, "🔣 Symbol: %s\n", job.document.Symbol)
+		}
+		for _, line := range job.log {
+			fmt.Fprintln(p.out, line)
+		}
+
+		p.resolveAnalysis(job, stats)
+		if err := p.save(writeCtx, job); err != nil {
+			fmt.Fprintf(p.out, "⚠️  Failed to save document: %v\n", err)
+			continue
+		}
+
+		stats.documents++
+		fmt.Fprintf(p.out, "✅ Document %d saved\n", stats.documents)
+	}
+	p.flushCache(writeCtx)
+}
+
+// flushCache はワーカーが保留したLLMのキャッシュの書き込みを行います
+//
+// 失敗は SQLCache の Stats に記録されるため、ここでは出力しません。
+func (p *pipeline) flushCache(ctx context.Context) {
+	if p.cache == nil {
+		return
+	}
+	_ = p.cache.Flush(ctx)
+}
+
+// isCancelled はキャンセルにより分析結果が得られなかったジョブかどうかを判定します
+//
+// キャンセルで中断した分析をルールベース分類で保存しないよう、保存対象から外します。
+func isCancelled(ctx context.Context, job *commentJob) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	switch {
+	case job.reused != nil:
+		return false
+	case job.leader != nil:
+		return job.leader.analysisMethod != models.AnalysisMethodLLM || job.leader.documentID == 0
+	default:
+		return job.err != nil
+	}
+}
+
+// resolveAnalysis はジョブの分析結果（再利用・LLM・ルールベース）をドキュメントに設定します
+func (p *pipeline) resolveAnalysis(job *commentJob, stats *pipelineStats) {
+	document := job.document
+	document.AnalysisMethod = models.AnalysisMethodLLM
+
+	var result llm.AnalysisResult
+	switch {
+	case job.reused != nil:
+		result = copyAnalysis(job.reused.result)
+		document.LLMDriver, document.LLMModel = job.reused.driver, job.reused.model
+		document.PromptVersion = job.reused.promptVersion
+		sourceID := job.reusedFrom
+		document.DuplicateOf = &sourceID
+		stats.reusedAnalyses++
+		fmt.Fprintf(p.out, "♻️  Near-duplicate of document #%d, reusing its analysis\n", sourceID)
+
+	case job.leader != nil && job.leader.analysisMethod == models.AnalysisMethodLLM && job.leader.documentID != 0:
+		result = copyAnalysis(job.leader.analysis)
+		document.LLMDriver, document.LLMModel = job.leader.document.LLMDriver, job.leader.document.LLMModel
+		document.PromptVersion = job.leader.document.PromptVersion
+		sourceID := job.leader.documentID
+		document.DuplicateOf = &sourceID
+		stats.reusedAnalyses++
+		fmt.Fprintf(p.out, "♻️  Near-duplicate of document #%d, reusing its analysis\n", sourceID)
+
+	case job.leader == nil && job.err == nil && job.analysis != nil:
+		result = copyAnalysis(job.analysis)
+		document.LLMDriver, document.LLMModel = job.driver.Name(), job.driver.Model()
+		document.PromptVersion = job.template.Version()
+		document.RepairCount = job.analysis.Repairs
+		usage := job.analysis.Usage
+		document.LLMInputTokens, document.LLMOutputTokens = usage.InputTokens, usage.OutputTokens
+		document.LLMTokensEstimated = usage.Estimated()
+		document.LLMLatencyMS = usage.Latency.Milliseconds()
+		details := describeDriver(document.LLMDriver, document.LLMModel)
+		if document.RepairCount > 0 {
+			details += fmt.Sprintf(", repaired %d times", document.RepairCount)
+		}
+		if job.analysis.Cached {
+			details += ", from cache"
+		}
+		fmt.Fprintf(p.out, "✅ LLM analysis completed (%s)\n", details)
+
+	default:
+		// LLM分析の失敗時（同じ実行内の重複元が失敗した場合を含む）はルールベースで分類する
+		if job.err != nil {
+			fmt.Fprintf(p.out, "⚠️  LLM analysis failed: %v\n", job.err)
+		} else {
+			fmt.Fprintf(p.out, "⚠️  LLM analysis of near-duplicate comment failed\n")
+		}
+		fmt.Fprintln(p.out, "📝 Falling back to heuristic classification...")
+		result = *heuristicAnalysis(p.heuristicClassifier, job.comment)
+		document.AnalysisMethod = models.AnalysisMethodHeuristic
+		fmt.Fprintf(p.out, "✅ Heuristic classification: %s (confidence %.2f)\n", result.Type, result.RelevanceScore)
+	}
+
+	// 明示的なマーカーがあればLLMの判定より優先する
+	severity := job.explicitSeverity
+	if severity == "" && models.IsValidSeverity(result.Severity) {
+		severity = result.Severity
+	}
+
+	// PR作成者の返信は設定に応じて関連度を下げる
+	result.RelevanceScore *= p.commentFilter.RelevanceWeight(job.comment)
+
+	document.Summary = result.Summary
+	document.CommentType = result.Type
+	document.Tags = result.Tags
+	document.RelevanceScore = result.RelevanceScore
+	document.Severity = severity
+	job.analysisMethod = document.AnalysisMethod
+}
+
+// save はドキュメントを保存し、ドキュメントIDをジョブに記録します
+func (p *pipeline) save(ctx context.Context, job *commentJob) error {
+	now := time.Now()
+	job.document.CollectedAt = now
+	job.document.UpdatedAt = now
+
+	if err := saveDocument(ctx, p.db, job.document); err != nil {
+		return err
+	}
+
+	documentID, err := findDocumentID(ctx, p.db, job.document)
+	if err != nil {
+		fmt.Fprintf(p.out, "⚠️  Failed to look up saved document: %v\n", err)
+		return nil
+	}
+	job.documentID = documentID
+	return nil
+}
+
+// copyAnalysis は関連度の補正で元の結果を変更しないよう分析結果を複製します
+func copyAnalysis(result *llm.AnalysisResult) llm.AnalysisResult {
+	copied := *result
+	copied.Tags = append([]string(nil), result.Tags...)
+	return copied
+}
+
+// jobAnalyzer はワーカーが使うLLMと、分析中のジョブです
+type jobAnalyzer struct {
+	analyzer llm.BatchAnalyzer
+	current  []*commentJob
+}
+
+// logf は分析中のジョブのログに追記します（リトライ・フォールバックの通知用）
+//
+// バッチで分析している場合は、バッチ内の全てのジョブに追記します。
+func (a *jobAnalyzer) logf(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	for _, job := range a.current {
+		job.log = append(job.log, line)
+	}
+}
+
+// analyze はジョブをまとめて分析し、完了を通知します
+func (a *jobAnalyzer) analyze(ctx context.Context, batch []*commentJob) {
+	defer func() {
+		for _, job := range batch {
+			close(job.done)
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		for _, job := range batch {
+			job.err = err
+		}
+		return
+	}
+
+	// IDはバッチ内の位置から決め、応答の結果をジョブに対応付ける
+	items := make([]llm.BatchItem, len(batch))
+	for i, job := range batch {
+		items[i] = llm.BatchItem{
+			ID:      fmt.Sprintf("c%d", i+1),
+			Context: job.commentContext,
+			Prompt:  job.prompt,
+		}
+	}
+
+	// バッチ内のジョブは同じテンプレートを使う（plan でまとめる）
+	a.current = batch
+	results := a.analyzer.AnalyzeBatch(ctx, batch[0].template.Instructions(), items)
+	a.current = nil
+
+	for i, job := range batch {
+		result := results[i]
+		job.analysis, job.driver, job.err = result.Result, result.Driver, result.Err
+		if result.Single {
+			job.log = append(job.log, "↩️  Missing from the batch response, analyzed individually")
+		}
+	}
+}
+
+// fetchPR はPRのコメントを取得し、分析対象のコメントとファイル内容を準備します
+func fetchPR(ctx context.Context, ghWrapper *github.GHWrapper, commentFilter *collector.CommentFilter, codeOwners *codeOwnersLoader, fetchContent bool, pr github.PullRequest) *prFetch {
+	fetch := &prFetch{pr: pr, contents: make(map[string][]byte)}
+
+	fetch.logf("📥 Fetching PR comments...")
+	comments, err := ghWrapper.GetPRComments(ctx, pr.Number)
+	if err != nil {
+		fetch.logf("⚠️  Failed to fetch comments for PR #%d: %v", pr.Number, err)
+		return fetch
+	}
+
+	if len(comments) == 0 {
+		fetch.logf("ℹ️  No comments found for PR #%d", pr.Number)
+		return fetch
+	}
+
+	fetch.logf("✅ Found %d comments", len(comments))
+
+	// Label comment roles (reviewer / PR author / third party)
+	comments = collector.LabelRoles(comments, pr.Author.Login)
+
+	// Filter useful comments
+	fetch.logf("🔍 Filtering useful comments...")
+	filtered := commentFilter.FilterComments(comments)
+	if len(filtered) == 0 {
+		fetch.logf("ℹ️  No useful comments found after filtering")
+		return fetch
+	}
+	fetch.logf("✅ %d useful comments after filtering", len(filtered))
+	fetch.comments = filtered
+
+	// Ownership falls back to CODEOWNERS at the PR's base commit only when the latest one failed to load
+	if codeOwners != nil {
+		baseCodeOwners, err := codeOwners.Load(ctx, pr.BaseRefOid)
+		if err != nil {
+			fetch.logf("⚠️  Failed to load CODEOWNERS at %s: %v", pr.BaseRefOid, err)
+		}
+		fetch.baseCodeOwners = baseCodeOwners
+	}
+
+	// File contents at the PR head, fetched once per file
+	if fetchContent {
+		for _, comment := range filtered {
+			if _, ok := fetch.contents[comment.FilePath]; ok {
+				continue
+			}
+			content, err := fetchFileContent(ctx, ghWrapper, comment.FilePath, pr.HeadRefOid)
+			if err != nil {
+				fetch.logf("⚠️  Failed to fetch %s: %v", comment.FilePath, err)
+			}
+			fetch.contents[comment.FilePath] = content
+		}
+	}
+
+	return fetch
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/database/dbtest"
+	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
+	"github.com/pankona/knowledges/pkg/config"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// fakeAnalyzer はコメント本文ごとに遅延を変えて分析結果を返すテスト用のLLMです
+type fakeAnalyzer struct {
+	driver  *llm.Driver
+	delays  map[string]time.Duration
+	calls   *atomic.Int32
+	items   *atomic.Int32
+	analyze func(ctx context.Context, body string) (*llm.AnalysisResult, error)
+}
+
+func newFakeAnalyzer() *fakeAnalyzer {
+	return &fakeAnalyzer{
+		driver: llm.NewDriver("fake", nil),
+		calls:  &atomic.Int32{},
+		items:  &atomic.Int32{},
+	}
+}
+
+func (a *fakeAnalyzer) AnalyzeBatch(ctx context.Context, instructions string, items []llm.BatchItem) []llm.BatchResult {
+	a.calls.Add(1)
+	a.items.Add(int32(len(items)))
+
+	results := make([]llm.BatchResult, len(items))
+	for i, item := range items {
+		body := item.Context[strings.Index(item.Context, "Comment:\n")+len("Comment:\n"):]
+		body = body[:strings.Index(body, "\n")]
+		results[i].ID = item.ID
+
+		if a.analyze != nil {
+			results[i].Result, results[i].Err = a.analyze(ctx, body)
+		} else {
+			time.Sleep(a.delays[body])
+			results[i].Result = &llm.AnalysisResult{
+				Summary:        "Summary of " + body,
+				Type:           "implementation",
+				Tags:           []string{"fake"},
+				RelevanceScore: 0.8,
+			}
+		}
+		if results[i].Err == nil {
+			results[i].Driver = a.driver
+		}
+	}
+	return results
+}
+
+func newTestPipeline(t *testing.T, analyzer *fakeAnalyzer, parallel, batchSize int, prComments map[int][]string) (*pipeline, *sql.DB, *bytes.Buffer) {
+	t.Helper()
+
+	db := dbtest.New(t)
+
+	projectResolver, err := collector.NewProjectResolver(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create project resolver: %v", err)
+	}
+	prompts, err := prompt.NewSet(config.PromptsConfig{})
+	if err != nil {
+		t.Fatalf("Failed to load prompts: %v", err)
+	}
+
+	var out bytes.Buffer
+	p := &pipeline{
+		db:                  db,
+		out:                 &out,
+		repository:          "owner/repo",
+		parallel:            parallel,
+		batchSize:           batchSize,
+		prompts:             prompts,
+		commentFilter:       collector.NewCommentFilter(),
+		fileInfoExtractor:   collector.NewFileInfoExtractor(),
+		heuristicClassifier: collector.NewHeuristicClassifier(),
+		projectResolver:     projectResolver,
+		duplicateIndex:      collector.NewDuplicateIndex(collector.DefaultDuplicateDistance),
+		duplicateDistance:   collector.DefaultDuplicateDistance,
+		fetch: func(ctx context.Context, pr github.PullRequest) *prFetch {
+			fetch := &prFetch{pr: pr}
+			for i, body := range prComments[pr.Number] {
+				fetch.comments = append(fetch.comments, github.Comment{
+					Author:     github.Author{Login: "reviewer1"},
+					Body:       body,
+					URL:        fmt.Sprintf("%s#discussion_r%d", pr.URL, i),
+					FilePath:   "internal/service.go",
+					LineNumber: 10 + i,
+					Role:       models.CommentRoleReviewer,
+				})
+			}
+			return fetch
+		},
+		newAnalyzer: func(logf func(format string, args ...interface{})) (llm.BatchAnalyzer, error) {
+			return analyzer, nil
+		},
+	}
+	return p, db, &out
+}
+
+func testPRs(numbers ...int) []github.PullRequest {
+	var prs []github.PullRequest
+	for _, number := range numbers {
+		prs = append(prs, github.PullRequest{
+			Number: number,
+			Title:  fmt.Sprintf("PR %d", number),
+			URL:    fmt.Sprintf("https://github.com/owner/repo/pull/%d", number),
+			Author: github.Author{Login: "author1"},
+		})
+	}
+	return prs
+}
+
+// savedRows は保存されたドキュメントを保存順に文字列化します
+func savedRows(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT d.comment_url, d.summary, d.analysis_method, d.prompt_version, COALESCE(src.comment_url, '')
+		FROM documents d LEFT JOIN documents src ON src.id = d.duplicate_of ORDER BY d.id`)
+	if err != nil {
+		t.Fatalf("Failed to query documents: %v", err)
+	}
+	defer rows.Close()
+
+	var saved []string
+	for rows.Next() {
+		var commentURL, summary, analysisMethod, promptVersion, duplicateOf string
+		if err := rows.Scan(&commentURL, &summary, &analysisMethod, &promptVersion, &duplicateOf); err != nil {
+			t.Fatalf("Failed to scan document: %v", err)
+		}
+		saved = append(saved, strings.Join([]string{commentURL, summary, analysisMethod, promptVersion, duplicateOf}, " | "))
+	}
+	return saved
+}
+
+func TestPipeline_OutputIsDeterministicAcrossParallelism(t *testing.T) {
+	// Arrange
+	duplicate := "Please wrap errors with %w so callers can inspect them with errors.Is."
+	prComments := map[int][]string{
+		1: {"Validate the request body before calling the repository.", duplicate},
+		2: {"This query runs inside a loop and causes N+1 queries on large orders."},
+		3: {duplicate, "Add a test for the empty cart case."},
+	}
+	// 先のコメントほど分析に時間がかかるようにし、完了順を入れ替える
+	delays := map[string]time.Duration{
+		prComments[1][0]: 40 * time.Millisecond,
+		duplicate:        30 * time.Millisecond,
+		prComments[2][0]: 20 * time.Millisecond,
+		prComments[3][1]: 0,
+	}
+
+	run := func(parallel, batchSize int) (string, []string, *fakeAnalyzer) {
+		analyzer := newFakeAnalyzer()
+		analyzer.delays = delays
+		p, db, out := newTestPipeline(t, analyzer, parallel, batchSize, prComments)
+
+		stats, err := p.run(context.Background(), testPRs(1, 2, 3))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stats.documents != 5 || stats.reusedAnalyses != 1 {
+			t.Errorf("expected 5 documents with 1 reused analysis, got %+v", stats)
+		}
+		return out.String(), savedRows(t, db), analyzer
+	}
+
+	// Act
+	sequentialOut, sequentialRows, sequential := run(1, 1)
+	parallelOut, parallelRows, parallel := run(4, 1)
+	batchedOut, batchedRows, batched := run(4, 3)
+
+	// Assert
+	if sequentialOut != parallelOut {
+		t.Errorf("expected identical output, got\n%s\nand\n%s", sequentialOut, parallelOut)
+	}
+	if strings.Join(sequentialRows, "\n") != strings.Join(parallelRows, "\n") {
+		t.Errorf("expected identical saved documents, got\n%v\nand\n%v", sequentialRows, parallelRows)
+	}
+	if sequentialOut != batchedOut || strings.Join(sequentialRows, "\n") != strings.Join(batchedRows, "\n") {
+		t.Errorf("expected batching not to change the results, got\n%s\nand\n%s", sequentialOut, batchedOut)
+	}
+	if sequential.items.Load() != 4 || parallel.items.Load() != 4 || batched.items.Load() != 4 {
+		t.Errorf("expected the near-duplicate not to be analyzed again, got %d, %d and %d comments",
+			sequential.items.Load(), parallel.items.Load(), batched.items.Load())
+	}
+	if sequential.calls.Load() != 4 || batched.calls.Load() != 2 {
+		t.Errorf("expected 4 single calls and 2 batched calls, got %d and %d", sequential.calls.Load(), batched.calls.Load())
+	}
+
+	want := "https://github.com/owner/repo/pull/3#discussion_r0 | Summary of " + duplicate + " | llm | " + prompt.Default().Version() + " | https://github.com/owner/repo/pull/1#discussion_r1"
+	if len(parallelRows) != 5 || parallelRows[3] != want {
+		t.Errorf("expected the in-run duplicate to reuse the earlier analysis, got %v", parallelRows)
+	}
+}
+
+func TestPipeline_CancelSavesOnlyAnalyzedComments(t *testing.T) {
+	// Arrange
+	prComments := map[int][]string{
+		1: {"Validate the request body before calling the repository."},
+		2: {"This call blocks until the analysis is cancelled.", "Add a test for the empty cart case."},
+		3: {"Close the response body to avoid leaking connections."},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var once sync.Once
+	analyzer := newFakeAnalyzer()
+	analyzer.analyze = func(ctx context.Context, body string) (*llm.AnalysisResult, error) {
+		if body == prComments[2][0] {
+			once.Do(cancel)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &llm.AnalysisResult{Summary: "Summary of " + body, Type: "implementation", RelevanceScore: 0.8}, nil
+	}
+	p, db, out := newTestPipeline(t, analyzer, 2, 1, prComments)
+
+	// Act
+	type result struct {
+		stats pipelineStats
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stats, err := p.run(ctx, testPRs(1, 2, 3))
+		done <- result{stats, err}
+	}()
+
+	var got result
+	select {
+	case got = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline did not stop after cancellation")
+	}
+
+	// Assert
+	if got.err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", got.err)
+	}
+	if got.stats.cancelled == 0 {
+		t.Errorf("expected cancelled comments to be counted, got %+v", got.stats)
+	}
+	for _, row := range savedRows(t, db) {
+		if strings.Contains(row, models.AnalysisMethodHeuristic) || strings.Contains(row, "blocks until") {
+			t.Errorf("expected cancelled comments not to be saved, got %s", row)
+		}
+	}
+	if !strings.Contains(out.String(), "⏹️  Cancelled:") {
+		t.Errorf("expected a cancellation notice, got\n%s", out.String())
+	}
+}
+
+func TestPipeline_SavesLLMUsagePerDocument(t *testing.T) {
+	// Arrange
+	duplicate := "Please wrap errors with %w so callers can inspect them with errors.Is."
+	prComments := map[int][]string{
+		1: {duplicate},
+		2: {duplicate},
+	}
+	analyzer := newFakeAnalyzer()
+	analyzer.analyze = func(ctx context.Context, body string) (*llm.AnalysisResult, error) {
+		return &llm.AnalysisResult{
+			Summary:        "Summary of " + body,
+			Type:           "maintenance",
+			RelevanceScore: 0.8,
+			Usage:          llm.Usage{Calls: 1, InputTokens: 120, OutputTokens: 30, EstimatedCalls: 1, Latency: 1500 * time.Millisecond},
+		}, nil
+	}
+	p, db, _ := newTestPipeline(t, analyzer, 1, 1, prComments)
+
+	// Act
+	_, err := p.run(context.Background(), testPRs(1, 2))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := db.Query("SELECT llm_input_tokens, llm_output_tokens, llm_tokens_estimated, llm_latency_ms FROM documents ORDER BY pr_number")
+	if err != nil {
+		t.Fatalf("failed to query documents: %v", err)
+	}
+	defer rows.Close()
+
+	type usage struct {
+		input, output int
+		estimated     bool
+		latency       int64
+	}
+	var got []usage
+	for rows.Next() {
+		var u usage
+		if err := rows.Scan(&u.input, &u.output, &u.estimated, &u.latency); err != nil {
+			t.Fatalf("failed to scan document: %v", err)
+		}
+		got = append(got, u)
+	}
+	want := []usage{{120, 30, true, 1500}, {0, 0, false, 0}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected the usage on the analyzed document only (the duplicate reuses it), got %+v", got)
+	}
+}
+
+func TestPipeline_RecollectDoesNotMatchItself(t *testing.T) {
+	// Arrange
+	prComments := map[int][]string{
+		1: {
+			"Please wrap errors with %w so callers can inspect them with errors.Is.",
+			"This query runs inside a loop and causes N+1 queries on large orders.",
+		},
+	}
+	analyzer := newFakeAnalyzer()
+	p, db, _ := newTestPipeline(t, analyzer, 1, 1, prComments)
+	if _, err := p.run(context.Background(), testPRs(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// -skip-processed=false と同じく、保存済みのドキュメントを索引に読み込んで再収集する
+	index, err := loadDuplicateIndex(context.Background(), db, collector.DefaultDuplicateDistance)
+	if err != nil {
+		t.Fatalf("failed to load duplicate index: %v", err)
+	}
+	p.duplicateIndex = index
+
+	// Act
+	stats, err := p.run(context.Background(), testPRs(1))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.reusedAnalyses != 0 || analyzer.items.Load() != 4 {
+		t.Errorf("expected the stored comments to be analyzed again, got %+v and %d analyzed comments", stats, analyzer.items.Load())
+	}
+	var selfReferences int
+	if err := db.QueryRow("SELECT COUNT(*) FROM documents WHERE duplicate_of = id").Scan(&selfReferences); err != nil {
+		t.Fatalf("failed to query documents: %v", err)
+	}
+	if selfReferences != 0 {
+		t.Errorf("expected no document to be a duplicate of itself, got %d", selfReferences)
+	}
+}
+
+func TestPipeline_LeavesProjectEmptyWhenDetectionFailed(t *testing.T) {
+	// Arrange
+	prComments := map[int][]string{1: {"Validate the request body before calling the repository."}}
+	p, db, _ := newTestPipeline(t, newFakeAnalyzer(), 1, 1, prComments)
+	p.projectResolver = nil
+	projectOf := func() string {
+		var project string
+		if err := db.QueryRow("SELECT project FROM documents").Scan(&project); err != nil {
+			t.Fatalf("failed to query project: %v", err)
+		}
+		return project
+	}
+
+	// Act
+	if _, err := p.run(context.Background(), testPRs(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	if got := projectOf(); got != "" {
+		t.Errorf("expected the project to be left empty, got %q", got)
+	}
+
+	// 次に検出できた収集で設定される
+	resolver, err := collector.NewProjectResolver([]string{"go.mod", "internal/go.mod"}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create project resolver: %v", err)
+	}
+	if _, err := backfillProjects(context.Background(), db, "owner/repo", resolver); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := projectOf(); got != "internal" {
+		t.Errorf("expected the project to be backfilled, got %q", got)
+	}
+}
+
+func TestPipeline_SavesLineNumber(t *testing.T) {
+	// Arrange
+	prComments := map[int][]string{1: {"Validate the request body before calling the repository."}}
+	p, db, _ := newTestPipeline(t, newFakeAnalyzer(), 1, 1, prComments)
+
+	// Act
+	_, err := p.run(context.Background(), testPRs(1))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var line int
+	if err := db.QueryRow("SELECT line_number FROM documents").Scan(&line); err != nil {
+		t.Fatalf("failed to query line number: %v", err)
+	}
+	if line != 10 {
+		t.Errorf("expected the commented line to be saved for re-analysis, got %d", line)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// checkoutSkipDirs はローカルチェックアウトの走査で除外するディレクトリです
+var checkoutSkipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// loadProjectResolver はリポジトリのファイル一覧からプロジェクト判定器を作成します
+//
+// ローカルチェックアウトが指定されていればそれを走査し、なければGitHubからデフォルトブランチの
+// ファイル一覧を取得します。
+func loadProjectResolver(ctx context.Context, ghWrapper *github.GHWrapper, checkoutDir string, projects config.ProjectsConfig) (*collector.ProjectResolver, error) {
+	var files []string
+	var err error
+	if checkoutDir != "" {
+		files, err = listCheckoutFiles(checkoutDir)
+	} else {
+		files, err = ghWrapper.ListFiles(ctx, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return collector.NewProjectResolver(files, projects.Markers, projects.Prefixes)
+}
+
+// listCheckoutFiles はローカルチェックアウト内のファイルパスを列挙します
+func listCheckoutFiles(checkoutDir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(checkoutDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != checkoutDir && checkoutSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(checkoutDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk checkout %s: %w", checkoutDir, err)
+	}
+	return files, nil
+}
+
+// backfillProjects はプロジェクト未設定の既存ドキュメントにプロジェクトを設定します
+func backfillProjects(ctx context.Context, db *sql.DB, repository string, resolver *collector.ProjectResolver) (int, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, file_path FROM documents WHERE repository = ? AND project = ''`, repository)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query documents: %w", err)
+	}
+
+	projects := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var filePath string
+		if err := rows.Scan(&id, &filePath); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan document: %w", err)
+		}
+		projects[id] = resolver.Project(filePath)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("error iterating documents: %w", err)
+	}
+	rows.Close()
+
+	for id, project := range projects {
+		if _, err := db.ExecContext(ctx, `UPDATE documents SET project = ? WHERE id = ?`, project, id); err != nil {
+			return 0, fmt.Errorf("failed to update project: %w", err)
+		}
+	}
+
+	return len(projects), nil
+}
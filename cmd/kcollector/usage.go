@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// printUsage はドライバー・モデルごとの使用量と料金表による料金を出力します
+func printUsage(w io.Writer, usages []llm.DriverUsage, pricing config.PricingConfig) {
+	if len(usages) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "💰 LLM usage:")
+	var total float64
+	priced := false
+	for _, usage := range usages {
+		line := fmt.Sprintf("   %s: %d calls, %d input / %d output tokens",
+			describeDriver(usage.Driver, usage.Model), usage.Calls, usage.InputTokens, usage.OutputTokens)
+		if usage.Estimated() {
+			line += fmt.Sprintf(" (estimated for %d calls)", usage.EstimatedCalls)
+		}
+		line += fmt.Sprintf(", %s", usage.Latency.Round(time.Millisecond))
+		if cost, ok := pricing.Cost(usage.Driver, usage.Model, usage.InputTokens, usage.OutputTokens); ok {
+			line += fmt.Sprintf(", %.4f %s", cost, pricing.Currency)
+			total += cost
+			priced = true
+		} else {
+			line += ", no price configured"
+		}
+		fmt.Fprintln(w, line)
+	}
+	if priced {
+		fmt.Fprintf(w, "   Total cost: %.4f %s\n", total, pricing.Currency)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+func main() {
+	var (
+		configPath = flag.String("config", "config.yaml", "Path to config file")
+		repo       = flag.String("repo", "", "Only report usage of this repository (default: all repositories)")
+		since      = flag.String("since", "", "Only report usage from this month on (YYYY-MM)")
+		format     = flag.String("format", "text", "Output format: text or json")
+	)
+	flag.Parse()
+
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Invalid -format: %q (expected text or json)", *format)
+	}
+
+	var sinceMonth time.Time
+	if *since != "" {
+		var err error
+		sinceMonth, err = time.Parse("2006-01", *since)
+		if err != nil {
+			log.Fatalf("Invalid -since: %q (expected YYYY-MM)", *since)
+		}
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	usages, err := loadUsage(context.Background(), db, *repo, sinceMonth)
+	if err != nil {
+		log.Fatalf("Failed to load LLM usage: %v", err)
+	}
+	report := buildCostReport(usages, cfg.Pricing)
+
+	switch *format {
+	case "json":
+		err = renderJSON(os.Stdout, report)
+	default:
+		err = renderText(os.Stdout, report)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database/dbtest"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// insertRun は1回の収集の実行とドライバーごとの使用量を保存します
+func insertRun(t *testing.T, db *sql.DB, repository string, startedAt time.Time, usages ...usageRow) {
+	t.Helper()
+	result, err := db.Exec(`
+		INSERT INTO collection_runs (repository, started_at, finished_at, documents)
+		VALUES (?, ?, ?, 1)`, repository, startedAt.UTC(), startedAt.Add(time.Minute).UTC())
+	if err != nil {
+		t.Fatalf("Failed to insert run: %v", err)
+	}
+	runID, _ := result.LastInsertId()
+	for _, usage := range usages {
+		_, err := db.Exec(`
+			INSERT INTO llm_usage (run_id, driver, model, calls, input_tokens, output_tokens, estimated_calls, latency_ms)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, usage.Driver, usage.Model, usage.Calls, usage.InputTokens, usage.OutputTokens, usage.EstimatedCalls, usage.LatencyMS)
+		if err != nil {
+			t.Fatalf("Failed to insert usage: %v", err)
+		}
+	}
+}
+
+func TestBuildCostReport_GroupsByMonthRepositoryAndDriver(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	march := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	april := time.Date(2026, 4, 2, 9, 0, 0, 0, time.UTC)
+	sonnet := usageRow{Driver: "claude", Model: "sonnet", Calls: 10, InputTokens: 100_000, OutputTokens: 10_000, LatencyMS: 30_000}
+	local := usageRow{Driver: "local", Model: "llama3", Calls: 2, InputTokens: 5_000, OutputTokens: 500, EstimatedCalls: 2}
+	insertRun(t, db, "owner/api", march, sonnet, local)
+	insertRun(t, db, "owner/api", march.Add(24*time.Hour), sonnet)
+	insertRun(t, db, "owner/web", march, sonnet)
+	insertRun(t, db, "owner/api", april, sonnet)
+	pricing := config.PricingConfig{Currency: "USD", Models: map[string]config.Price{"sonnet": {Input: 3, Output: 15}}}
+
+	// Act
+	usages, err := loadUsage(context.Background(), db, "", time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report := buildCostReport(usages, pricing)
+
+	// Assert
+	var got []string
+	for _, line := range report.Lines {
+		got = append(got, line.Month+" "+line.Repository+" "+line.Driver)
+	}
+	want := []string{"2026-03 owner/api claude", "2026-03 owner/api local", "2026-03 owner/web claude", "2026-04 owner/api claude"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("expected lines %v, got %v", want, got)
+	}
+
+	march2Runs := report.Lines[0]
+	if march2Runs.Runs != 2 || march2Runs.Calls != 20 || march2Runs.InputTokens != 200_000 || march2Runs.Cost == nil {
+		t.Fatalf("expected the two March runs to be added up, got %+v", march2Runs)
+	}
+	if math.Abs(*march2Runs.Cost-0.9) > 1e-9 {
+		t.Errorf("expected cost 0.9 (200k input × 3 + 20k output × 15 per 1M), got %v", *march2Runs.Cost)
+	}
+	if report.Lines[1].Cost != nil || report.Unpriced != 1 {
+		t.Errorf("expected the unpriced driver to have no cost, got %+v", report.Lines[1])
+	}
+	if math.Abs(report.TotalCost-0.45*4) > 1e-9 {
+		t.Errorf("expected total cost 1.8, got %v", report.TotalCost)
+	}
+}
+
+func TestLoadUsage_FiltersByRepositoryAndMonth(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	usage := usageRow{Driver: "claude", Calls: 1, InputTokens: 10, OutputTokens: 1}
+	insertRun(t, db, "owner/api", time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC), usage)
+	insertRun(t, db, "owner/api", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), usage)
+	insertRun(t, db, "owner/web", time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), usage)
+
+	// Act
+	usages, err := loadUsage(context.Background(), db, "owner/api", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(usages) != 1 || usages[0].Repository != "owner/api" || usages[0].StartedAt.Month() != time.March {
+		t.Errorf("expected only the March run of owner/api, got %+v", usages)
+	}
+}
+
+func TestRender(t *testing.T) {
+	// Arrange
+	cost := 0.9
+	report := &costReport{
+		Currency: "USD",
+		Lines: []costLine{
+			{Month: "2026-03", Repository: "owner/api", Driver: "claude", Model: "sonnet", Runs: 2, Calls: 20, InputTokens: 200_000, OutputTokens: 20_000, LatencyMS: 61_000, Cost: &cost},
+			{Month: "2026-03", Repository: "owner/api", Driver: "local", Runs: 1, Calls: 2, InputTokens: 5_000, OutputTokens: 500, EstimatedCalls: 2},
+		},
+		TotalCost: cost,
+		Unpriced:  1,
+	}
+
+	// Act
+	var text, jsonOut bytes.Buffer
+	if err := renderText(&text, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := renderJSON(&jsonOut, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	for _, want := range []string{
+		"📅 2026-03",
+		"owner/api  claude/sonnet  2 runs, 20 calls, 200000 input / 20000 output tokens, 1m1s  0.9000 USD",
+		"owner/api  local  1 runs, 2 calls, 5000 input / 500 output tokens (estimated), 0s  no price configured",
+		"Subtotal: 0.9000 USD",
+		"💰 Total: 0.9000 USD",
+		"1 lines have no price configured (add them to pricing in the config): local",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("expected %q in\n%s", want, text.String())
+		}
+	}
+
+	var decoded costReport
+	if err := json.Unmarshal(jsonOut.Bytes(), &decoded); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if len(decoded.Lines) != 2 || decoded.Lines[1].Cost != nil || decoded.TotalCost != cost {
+		t.Errorf("unexpected JSON report: %s", jsonOut.String())
+	}
+}
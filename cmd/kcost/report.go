@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// usageRow は1回の収集の実行における、ドライバー・モデルごとのLLMの使用量です
+type usageRow struct {
+	Repository     string
+	StartedAt      time.Time
+	Driver         string
+	Model          string
+	Calls          int
+	InputTokens    int
+	OutputTokens   int
+	EstimatedCalls int
+	LatencyMS      int64
+}
+
+// costLine は月・リポジトリ・ドライバー・モデルごとの集計です
+type costLine struct {
+	Month          string `json:"month"`
+	Repository     string `json:"repository"`
+	Driver         string `json:"driver"`
+	Model          string `json:"model,omitempty"`
+	Runs           int    `json:"runs"`
+	Calls          int    `json:"calls"`
+	InputTokens    int    `json:"input_tokens"`
+	OutputTokens   int    `json:"output_tokens"`
+	EstimatedCalls int    `json:"estimated_calls,omitempty"`
+	LatencyMS      int64  `json:"latency_ms"`
+	// Cost は料金表による料金です（価格が未設定の場合は nil）
+	Cost *float64 `json:"cost"`
+}
+
+// costReport は料金のレポートです
+type costReport struct {
+	Currency string     `json:"currency"`
+	Lines    []costLine `json:"lines"`
+	// TotalCost は価格が設定された行の料金の合計です
+	TotalCost float64 `json:"total_cost"`
+	// Unpriced は価格が未設定のため料金に含まれていない行の数です
+	Unpriced int `json:"unpriced"`
+}
+
+// loadUsage は収集の実行ごとのLLMの使用量を読み込みます（repository が空の場合は全てのリポジトリ）
+func loadUsage(ctx context.Context, db *sql.DB, repository string, since time.Time) ([]usageRow, error) {
+	query := `
+		SELECT r.repository, r.started_at, u.driver, u.model, u.calls,
+			u.input_tokens, u.output_tokens, u.estimated_calls, u.latency_ms
+		FROM llm_usage u
+		JOIN collection_runs r ON r.id = u.run_id
+		WHERE r.started_at >= ?`
+	args := []interface{}{since.UTC()}
+	if repository != "" {
+		query += " AND r.repository = ?"
+		args = append(args, repository)
+	}
+	query += " ORDER BY r.started_at, r.id"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query LLM usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usages []usageRow
+	for rows.Next() {
+		var row usageRow
+		if err := rows.Scan(&row.Repository, &row.StartedAt, &row.Driver, &row.Model, &row.Calls,
+			&row.InputTokens, &row.OutputTokens, &row.EstimatedCalls, &row.LatencyMS); err != nil {
+			return nil, fmt.Errorf("failed to scan LLM usage: %w", err)
+		}
+		usages = append(usages, row)
+	}
+	return usages, rows.Err()
+}
+
+// buildCostReport は使用量を月（UTC）・リポジトリ・ドライバー・モデルごとに集計し、料金を計算します
+//
+// 料金はトークン数の合計に対して計算するため、料金表を変えると過去の使用量にも反映されます。
+func buildCostReport(usages []usageRow, pricing config.PricingConfig) *costReport {
+	type key struct {
+		month, repository, driver, model string
+	}
+	totals := make(map[key]*costLine)
+	for _, usage := range usages {
+		k := key{usage.StartedAt.UTC().Format("2006-01"), usage.Repository, usage.Driver, usage.Model}
+		line, ok := totals[k]
+		if !ok {
+			line = &costLine{Month: k.month, Repository: k.repository, Driver: k.driver, Model: k.model}
+			totals[k] = line
+		}
+		line.Runs++
+		line.Calls += usage.Calls
+		line.InputTokens += usage.InputTokens
+		line.OutputTokens += usage.OutputTokens
+		line.EstimatedCalls += usage.EstimatedCalls
+		line.LatencyMS += usage.LatencyMS
+	}
+
+	report := &costReport{Currency: pricing.Currency, Lines: []costLine{}}
+	for _, line := range totals {
+		if cost, ok := pricing.Cost(line.Driver, line.Model, line.InputTokens, line.OutputTokens); ok {
+			line.Cost = &cost
+			report.TotalCost += cost
+		} else {
+			report.Unpriced++
+		}
+		report.Lines = append(report.Lines, *line)
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		a, b := report.Lines[i], report.Lines[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		if a.Driver != b.Driver {
+			return a.Driver < b.Driver
+		}
+		return a.Model < b.Model
+	})
+	return report
+}
+
+// renderText は月ごとに見出しを付けたテキストでレポートを出力します
+func renderText(w io.Writer, report *costReport) error {
+	if len(report.Lines) == 0 {
+		fmt.Fprintln(w, "No LLM usage recorded yet. Run kcollector to record usage.")
+		return nil
+	}
+
+	month := ""
+	var monthCost float64
+	flush := func() {
+		if month != "" {
+			fmt.Fprintf(w, "   Subtotal: %.4f %s\n", monthCost, report.Currency)
+		}
+	}
+	for _, line := range report.Lines {
+		if line.Month != month {
+			flush()
+			month, monthCost = line.Month, 0
+			fmt.Fprintf(w, "\n📅 %s\n", month)
+		}
+
+		driver := line.Driver
+		if line.Model != "" {
+			driver += "/" + line.Model
+		}
+		tokens := fmt.Sprintf("%d input / %d output tokens", line.InputTokens, line.OutputTokens)
+		if line.EstimatedCalls > 0 {
+			tokens += " (estimated)"
+		}
+		cost := "no price configured"
+		if line.Cost != nil {
+			cost = fmt.Sprintf("%.4f %s", *line.Cost, report.Currency)
+			monthCost += *line.Cost
+		}
+		fmt.Fprintf(w, "   %s  %s  %d runs, %d calls, %s, %s  %s\n",
+			line.Repository, driver, line.Runs, line.Calls, tokens,
+			(time.Duration(line.LatencyMS) * time.Millisecond).Round(time.Second), cost)
+	}
+	flush()
+
+	fmt.Fprintf(w, "\n💰 Total: %.4f %s\n", report.TotalCost, report.Currency)
+	if report.Unpriced > 0 {
+		fmt.Fprintf(w, "⚠️  %d lines have no price configured (add them to pricing in the config): %s\n",
+			report.Unpriced, strings.Join(unpricedDrivers(report), ", "))
+	}
+	return nil
+}
+
+// unpricedDrivers は価格が未設定のドライバー・モデルを重複なく返します
+func unpricedDrivers(report *costReport) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, line := range report.Lines {
+		if line.Cost != nil {
+			continue
+		}
+		name := line.Driver
+		if line.Model != "" {
+			name += "/" + line.Model
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderJSON はJSONでレポートを出力します
+func renderJSON(w io.Writer, report *costReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// dirNode はディレクトリごとのナレッジのカバレッジです
+//
+// Files / Documents / ByType / Directories / CoveredDirectories は配下全体の集計です。
+type dirNode struct {
+	Path               string         `json:"path"`
+	Name               string         `json:"name"`
+	Files              int            `json:"files"`
+	Documents          int            `json:"documents"`
+	ByType             map[string]int `json:"by_type"`
+	Directories        int            `json:"directories"`
+	CoveredDirectories int            `json:"covered_directories"`
+	Coverage           float64        `json:"coverage"`
+	Children           []*dirNode     `json:"children,omitempty"`
+
+	ownFiles     int
+	ownDocuments int
+}
+
+// coverageReport はカバレッジレポート全体です
+type coverageReport struct {
+	Repository string   `json:"repository"`
+	Checkout   string   `json:"checkout"`
+	Unmatched  int      `json:"unmatched_documents"`
+	Tree       *dirNode `json:"tree"`
+}
+
+// walkCheckout はローカルチェックアウトを走査し、ディレクトリごとのファイル数を返します
+//
+// 隠しディレクトリ（.git など）とベンダリング用ディレクトリは対象外です。
+func walkCheckout(root string) (map[string]int, error) {
+	files := make(map[string]int)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && (strings.HasPrefix(d.Name(), ".") || collector.IsVendoredDir(d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files[path.Dir(filepath.ToSlash(rel))]++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// loadDirectoryCounts はディレクトリ・コメント種別ごとのドキュメント数を読み込みます
+//
+// noise はナレッジではないため集計しません。
+func loadDirectoryCounts(ctx context.Context, db *sql.DB, repository string) (map[string]map[string]int, error) {
+	query := `SELECT directory_path, comment_type, COUNT(*) FROM documents
+	WHERE repository = ? AND comment_type != ? GROUP BY directory_path, comment_type`
+	rows, err := db.QueryContext(ctx, query, repository, string(models.CommentTypeNoise))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]map[string]int)
+	for rows.Next() {
+		var directory, commentType string
+		var count int
+		if err := rows.Scan(&directory, &commentType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan counts: %w", err)
+		}
+		directory = normalizeDirectory(directory)
+		if counts[directory] == nil {
+			counts[directory] = make(map[string]int)
+		}
+		counts[directory][commentType] += count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating counts: %w", err)
+	}
+	return counts, nil
+}
+
+// normalizeDirectory はDBのディレクトリパスを走査結果と同じ形式にします
+func normalizeDirectory(directory string) string {
+	directory = strings.Trim(strings.TrimPrefix(directory, "./"), "/")
+	if directory == "" {
+		return "."
+	}
+	return path.Clean(directory)
+}
+
+// buildCoverageTree はファイル数とドキュメント数からディレクトリツリーを作成します
+//
+// 作業ツリーに存在しないディレクトリのドキュメント数を unmatched として返します。
+func buildCoverageTree(files map[string]int, counts map[string]map[string]int) (*dirNode, int) {
+	root := newDirNode(".")
+	nodes := map[string]*dirNode{".": root}
+
+	var ensure func(dir string) *dirNode
+	ensure = func(dir string) *dirNode {
+		if node, ok := nodes[dir]; ok {
+			return node
+		}
+		node := newDirNode(dir)
+		nodes[dir] = node
+		parent := ensure(path.Dir(dir))
+		parent.Children = append(parent.Children, node)
+		return node
+	}
+
+	for dir, count := range files {
+		ensure(dir).ownFiles += count
+	}
+
+	unmatched := 0
+	for dir, byType := range counts {
+		node, ok := nodes[dir]
+		if !ok {
+			for _, count := range byType {
+				unmatched += count
+			}
+			continue
+		}
+		for commentType, count := range byType {
+			node.ByType[commentType] += count
+			node.ownDocuments += count
+		}
+	}
+
+	aggregate(root)
+	return root, unmatched
+}
+
+// newDirNode は空のdirNodeを作成します
+func newDirNode(dir string) *dirNode {
+	return &dirNode{Path: dir, Name: path.Base(dir), ByType: make(map[string]int)}
+}
+
+// aggregate は子ディレクトリの集計を親に積み上げ、カバレッジを計算します
+func aggregate(node *dirNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		return node.Children[i].Name < node.Children[j].Name
+	})
+
+	node.Files = node.ownFiles
+	node.Documents = node.ownDocuments
+	if node.ownFiles > 0 {
+		node.Directories = 1
+		if node.ownDocuments > 0 {
+			node.CoveredDirectories = 1
+		}
+	}
+
+	for _, child := range node.Children {
+		aggregate(child)
+		node.Files += child.Files
+		node.Documents += child.Documents
+		node.Directories += child.Directories
+		node.CoveredDirectories += child.CoveredDirectories
+		for commentType, count := range child.ByType {
+			node.ByType[commentType] += count
+		}
+	}
+
+	if node.Directories > 0 {
+		node.Coverage = float64(node.CoveredDirectories) / float64(node.Directories)
+	}
+}
+
+// pruneTree は指定した深さより深いディレクトリを除きます（0 は無制限）
+func pruneTree(node *dirNode, depth int) {
+	if depth <= 0 {
+		return
+	}
+	var walk func(n *dirNode, level int)
+	walk = func(n *dirNode, level int) {
+		if level >= depth {
+			n.Children = nil
+			return
+		}
+		for _, child := range n.Children {
+			walk(child, level+1)
+		}
+	}
+	walk(node, 0)
+}
+
+// typeCounts はドキュメント数のあるコメント種別を分類体系の順に返します
+func typeCounts(byType map[string]int) []typeCount {
+	var result []typeCount
+	for _, commentType := range models.CommentTypes {
+		if count := byType[string(commentType)]; count > 0 {
+			result = append(result, typeCount{Type: string(commentType), Count: count})
+		}
+	}
+	// 分類体系にない種別（古いデータなど）は名前順で末尾に並べる
+	var others []string
+	for commentType := range byType {
+		if !models.IsValidCommentType(commentType) && byType[commentType] > 0 {
+			others = append(others, commentType)
+		}
+	}
+	sort.Strings(others)
+	for _, commentType := range others {
+		result = append(result, typeCount{Type: commentType, Count: byType[commentType]})
+	}
+	return result
+}
+
+// typeCount はコメント種別ごとのドキュメント数です
+type typeCount struct {
+	Type  string
+	Count int
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+func main() {
+	var (
+		configPath = flag.String("config", "config.yaml", "Path to config file")
+		repo       = flag.String("repo", "", "Repository the documents belong to (overrides config)")
+		checkout   = flag.String("checkout", "", "Path to a local checkout of the repository")
+		format     = flag.String("format", "text", "Output format: text, json or html")
+		output     = flag.String("output", "", "Write the report to a file instead of stdout")
+		depth      = flag.Int("depth", 3, "Maximum directory depth to show (0 for unlimited)")
+	)
+	flag.Parse()
+
+	if *format != "text" && *format != "json" && *format != "html" {
+		log.Fatalf("Invalid -format: %q (expected text, json or html)", *format)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	targetRepo := *repo
+	if targetRepo == "" && len(cfg.GitHub.Repositories) > 0 {
+		targetRepo = cfg.GitHub.Repositories[0]
+	}
+	if targetRepo == "" || *checkout == "" {
+		fmt.Println("Usage: kcoverage -repo owner/repo -checkout /path/to/checkout [-format text|json|html] [-output file] [-depth 3]")
+		os.Exit(1)
+	}
+
+	db, err := database.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx := context.Background()
+
+	files, err := walkCheckout(*checkout)
+	if err != nil {
+		log.Fatalf("Failed to walk checkout: %v", err)
+	}
+
+	counts, err := loadDirectoryCounts(ctx, db, targetRepo)
+	if err != nil {
+		log.Fatalf("Failed to load document counts: %v", err)
+	}
+
+	tree, unmatched := buildCoverageTree(files, counts)
+	pruneTree(tree, *depth)
+	report := &coverageReport{
+		Repository: targetRepo,
+		Checkout:   *checkout,
+		Unmatched:  unmatched,
+		Tree:       tree,
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch *format {
+	case "json":
+		err = renderJSON(w, report)
+	case "html":
+		err = renderHTML(w, report)
+	default:
+		err = renderText(w, report)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	if *output != "" {
+		fmt.Printf("✅ Coverage report written to %s\n", *output)
+	}
+}
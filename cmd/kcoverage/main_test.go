@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database/dbtest"
+)
+
+func TestWalkCheckout(t *testing.T) {
+	// Arrange
+	root := t.TempDir()
+	for _, file := range []string{
+		"main.go",
+		"services/payment/charge.go",
+		"services/payment/refund.go",
+		"services/billing/invoice.go",
+		"vendor/github.com/lib/lib.go",
+		".git/HEAD",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	// Act
+	files, err := walkCheckout(root)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]int{".": 1, "services/payment": 2, "services/billing": 1}
+	if len(files) != len(want) {
+		t.Fatalf("expected %v, got %v", want, files)
+	}
+	for dir, count := range want {
+		if files[dir] != count {
+			t.Errorf("expected %d files in %s, got %d", count, dir, files[dir])
+		}
+	}
+}
+
+func TestBuildCoverageTree(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	documents := []struct {
+		directory   string
+		commentType string
+	}{
+		{"services/payment", "security"},
+		{"services/payment", "security"},
+		{"./services/payment", "bug"},
+		{"services/payment", "noise"},
+		{".", "design"},
+		{"lib/legacy", "bug"},
+	}
+	for i, doc := range documents {
+		_, err := db.Exec(`
+			INSERT INTO documents (summary, original_comment, file_path, directory_path, language,
+				repository, pr_number, pr_title, pr_url, comment_url, author, comment_type, commented_at)
+			VALUES ('s', 'c', 'f', ?, 'go', 'owner/repo', 1, 'PR', 'url', ?, 'user', ?, ?)`,
+			doc.directory, i, doc.commentType, time.Now())
+		if err != nil {
+			t.Fatalf("Failed to insert document: %v", err)
+		}
+	}
+
+	files := map[string]int{".": 1, "services/payment": 2, "services/billing": 1, "services/billing/internal": 3}
+
+	// Act
+	counts, err := loadDirectoryCounts(context.Background(), db, "owner/repo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tree, unmatched := buildCoverageTree(files, counts)
+
+	// Assert
+	if unmatched != 1 {
+		t.Errorf("expected 1 unmatched document, got %d", unmatched)
+	}
+	if tree.Files != 7 || tree.Documents != 4 {
+		t.Errorf("expected root to have 7 files and 4 documents, got %d and %d", tree.Files, tree.Documents)
+	}
+	if tree.Directories != 4 || tree.CoveredDirectories != 2 || tree.Coverage != 0.5 {
+		t.Errorf("expected root coverage 2/4, got %d/%d (%v)", tree.CoveredDirectories, tree.Directories, tree.Coverage)
+	}
+
+	if len(tree.Children) != 1 || tree.Children[0].Path != "services" {
+		t.Fatalf("expected a single services child, got %+v", tree.Children)
+	}
+	services := tree.Children[0]
+	if len(services.Children) != 2 || services.Children[0].Name != "billing" || services.Children[1].Name != "payment" {
+		t.Fatalf("expected billing and payment sorted by name, got %+v", services.Children)
+	}
+	payment := services.Children[1]
+	if payment.ByType["security"] != 2 || payment.ByType["bug"] != 1 || payment.ByType["noise"] != 0 {
+		t.Errorf("unexpected counts per type: %v", payment.ByType)
+	}
+	billing := services.Children[0]
+	if billing.Documents != 0 || billing.Coverage != 0 || billing.Directories != 2 {
+		t.Errorf("expected billing to be a blind spot with 2 directories, got %+v", billing)
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	// Arrange
+	files := map[string]int{"services/payment": 2, "services/billing": 1}
+	counts := map[string]map[string]int{"services/payment": {"security": 2}}
+	tree, _ := buildCoverageTree(files, counts)
+	pruneTree(tree, 1)
+	report := &coverageReport{Repository: "owner/repo", Checkout: "/src/repo", Tree: tree}
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := renderText(&buf, report); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		output := buf.String()
+		if !strings.Contains(output, "└── services/  2 docs / 3 files, coverage 50% (1/2 dirs)  [security:2]") {
+			t.Errorf("unexpected text output:\n%s", output)
+		}
+		if strings.Contains(output, "payment/") {
+			t.Errorf("expected directories deeper than -depth to be pruned:\n%s", output)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := renderJSON(&buf, report); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var decoded coverageReport
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if decoded.Tree.Documents != 2 || decoded.Tree.Children[0].ByType["security"] != 2 {
+			t.Errorf("unexpected decoded report: %+v", decoded.Tree)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := renderHTML(&buf, report); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		output := buf.String()
+		if !strings.Contains(output, "hsl(60, 70%, 80%)") || !strings.Contains(output, "services/") {
+			t.Errorf("expected a heat colored services directory:\n%s", output)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// renderText はツリー形式のテキストでレポートを出力します
+func renderText(w io.Writer, report *coverageReport) error {
+	fmt.Fprintf(w, "📦 Repository: %s\n", report.Repository)
+	fmt.Fprintf(w, "📂 Checkout: %s\n", report.Checkout)
+	if report.Unmatched > 0 {
+		fmt.Fprintf(w, "⚠️  %d documents refer to directories that are not in the checkout\n", report.Unmatched)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, formatNode(report.Tree, report.Tree.Path))
+	writeChildren(w, report.Tree, "")
+	return nil
+}
+
+// writeChildren は子ディレクトリを罫線付きで出力します
+func writeChildren(w io.Writer, node *dirNode, prefix string) {
+	for i, child := range node.Children {
+		branch, indent := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintln(w, prefix+branch+formatNode(child, child.Name+"/"))
+		writeChildren(w, child, prefix+indent)
+	}
+}
+
+// formatNode はディレクトリ1行分の集計を整形します
+func formatNode(node *dirNode, label string) string {
+	line := fmt.Sprintf("%s  %d docs / %d files, coverage %.0f%% (%d/%d dirs)",
+		label, node.Documents, node.Files, node.Coverage*100, node.CoveredDirectories, node.Directories)
+	if node.Documents == 0 {
+		return line + "  🕳️ blind spot"
+	}
+
+	var types []string
+	for _, tc := range typeCounts(node.ByType) {
+		types = append(types, fmt.Sprintf("%s:%d", tc.Type, tc.Count))
+	}
+	return line + "  [" + strings.Join(types, " ") + "]"
+}
+
+// renderJSON はJSONでレポートを出力します
+func renderJSON(w io.Writer, report *coverageReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// renderHTML はカバレッジを色で表したヒートマップのHTMLを出力します
+func renderHTML(w io.Writer, report *coverageReport) error {
+	return heatmapTemplate.Execute(w, report)
+}
+
+// heatColor はカバレッジを背景色に変換します（0%: 赤 → 100%: 緑）
+func heatColor(coverage float64) template.CSS {
+	return template.CSS(fmt.Sprintf("hsl(%.0f, 70%%, 80%%)", coverage*120))
+}
+
+var heatmapTemplate = template.Must(template.New("heatmap").Funcs(template.FuncMap{
+	"heat":       heatColor,
+	"percent":    func(v float64) string { return fmt.Sprintf("%.0f%%", v*100) },
+	"typeCounts": typeCounts,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Knowledge coverage: {{.Repository}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+ul { list-style: none; padding-left: 1.5em; margin: 0; }
+details > summary { cursor: pointer; }
+.dir { display: inline-block; padding: 0.2em 0.6em; margin: 0.1em 0; border-radius: 4px; }
+.stats { color: #333; font-size: 0.9em; }
+.types { color: #666; font-size: 0.8em; }
+</style>
+</head>
+<body>
+<h1>Knowledge coverage: {{.Repository}}</h1>
+<p>Checkout: {{.Checkout}}{{if .Unmatched}} &mdash; {{.Unmatched}} documents refer to directories that are not in the checkout{{end}}</p>
+<ul>{{template "node" .Tree}}</ul>
+</body>
+</html>
+{{define "node"}}<li>{{if .Children}}<details open><summary>{{template "label" .}}</summary><ul>{{range .Children}}{{template "node" .}}{{end}}</ul></details>{{else}}{{template "label" .}}{{end}}</li>
+{{end}}
+{{define "label"}}<span class="dir" style="background: {{heat .Coverage}}" title="{{.Path}}">{{.Name}}/</span>
+<span class="stats">{{.Documents}} docs / {{.Files}} files, coverage {{percent .Coverage}} ({{.CoveredDirectories}}/{{.Directories}} dirs)</span>
+<span class="types">{{range typeCounts .ByType}}{{.Type}}:{{.Count}} {{end}}</span>{{end}}
+`))
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// evalCase は評価データセットの1件（コメントと期待する分類）です
+//
+// JSONLの1行に1件を記述します。id を省略した場合は行番号を使います。
+type evalCase struct {
+	ID         string `json:"id"`
+	Comment    string `json:"comment"`
+	Repository string `json:"repository"`
+	PRTitle    string `json:"pr_title"`
+	FilePath   string `json:"file_path"`
+	Language   string `json:"language"`
+	Symbol     string `json:"symbol"`
+	Role       string `json:"role"`
+
+	// 期待する分析結果（tags と severity は省略した場合は評価しない）
+	Type     string   `json:"type"`
+	Tags     []string `json:"tags"`
+	Severity string   `json:"severity"`
+}
+
+// dataset は読み込んだ評価データセットです
+type dataset struct {
+	cases []evalCase
+	// hash はデータセットの内容のハッシュです（前回の評価と同じデータか判定するために使用）
+	hash string
+}
+
+// parseDataset はJSONLの評価データセットを読み込み、各行を検証します
+func parseDataset(data []byte) (*dataset, error) {
+	sum := sha256.Sum256(data)
+	ds := &dataset{hash: hex.EncodeToString(sum[:])[:12]}
+
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var c evalCase
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("line-%d", line)
+		}
+		if previous, ok := seen[c.ID]; ok {
+			return nil, fmt.Errorf("line %d: duplicate id %q (first on line %d)", line, c.ID, previous)
+		}
+		seen[c.ID] = line
+		if strings.TrimSpace(c.Comment) == "" {
+			return nil, fmt.Errorf("line %d: comment is required", line)
+		}
+		if !models.IsValidCommentType(c.Type) {
+			return nil, fmt.Errorf("line %d: invalid type %q", line, c.Type)
+		}
+		if c.Severity != "" && !models.IsValidSeverity(c.Severity) {
+			return nil, fmt.Errorf("line %d: invalid severity %q", line, c.Severity)
+		}
+		ds.cases = append(ds.cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	if len(ds.cases) == 0 {
+		return nil, fmt.Errorf("dataset has no cases")
+	}
+	return ds, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// evaluator はデータセットの各ケースを収集時と同じプロンプトで分析します
+//
+// 同じ指示のケースを batchSize 件までまとめ、parallel 個まで並行に分析します（llm.BatchRunner）。
+type evaluator struct {
+	// template はケースに使うテンプレートを返します
+	template    func(c evalCase) *prompt.Template
+	parallel    int
+	batchSize   int
+	newAnalyzer func() (llm.BatchAnalyzer, error)
+}
+
+// evalJob は1件のケースの分析に使うプロンプトと結果です
+type evalJob struct {
+	outcome  *evalOutcome
+	template *prompt.Template
+	context  string
+	prompt   string
+	severity string
+}
+
+// evaluation は評価の実行結果です
+type evaluation struct {
+	outcomes []evalOutcome
+	// promptVersions は使用したテンプレートのバージョンです
+	promptVersions []string
+	usage          llm.Usage
+}
+
+// run はデータセットの全てのケースを分析し、ケースの順に結果を返します
+func (e *evaluator) run(ctx context.Context, cases []evalCase) (*evaluation, error) {
+	outcomes := make([]evalOutcome, len(cases))
+	jobs := make([]*evalJob, 0, len(cases))
+	requests := make([]llm.BatchRequest, 0, len(cases))
+	versions := make(map[string]bool)
+	for i, c := range cases {
+		outcomes[i] = evalOutcome{
+			CaseID:           c.ID,
+			ExpectedType:     c.Type,
+			ExpectedTags:     c.Tags,
+			ExpectedSeverity: c.Severity,
+		}
+		job, err := newEvalJob(&outcomes[i], c, e.template(c))
+		if err != nil {
+			outcomes[i].Error = err.Error()
+			continue
+		}
+		jobs = append(jobs, job)
+		requests = append(requests, llm.BatchRequest{Instructions: job.template.Instructions(), Context: job.context, Prompt: job.prompt})
+		versions[job.template.Version()] = true
+	}
+
+	runner := &llm.BatchRunner{
+		Parallel:    e.parallel,
+		BatchSize:   e.batchSize,
+		NewAnalyzer: e.newAnalyzer,
+	}
+	analyzed, err := runner.Run(ctx, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &evaluation{outcomes: outcomes}
+	for i, job := range jobs {
+		job.apply(analyzed[i])
+		if analyzed[i].Err == nil {
+			result.usage.Add(analyzed[i].Result.Usage)
+		}
+	}
+	result.promptVersions = sortedKeys(versions)
+	return result, nil
+}
+
+// newEvalJob はケースの情報からプロンプトを組み立てます
+func newEvalJob(outcome *evalOutcome, c evalCase, template *prompt.Template) (*evalJob, error) {
+	job := &evalJob{
+		outcome:  outcome,
+		template: template,
+		severity: collector.DetectSeverity(c.Comment),
+	}
+	data := prompt.Comment{
+		Repository: c.Repository,
+		PRTitle:    c.PRTitle,
+		FilePath:   c.FilePath,
+		Symbol:     c.Symbol,
+		Language:   c.Language,
+		Role:       c.Role,
+		Severity:   job.severity,
+		Body:       c.Comment,
+	}.Data()
+	var err error
+	if job.context, err = template.Context(data); err != nil {
+		return nil, err
+	}
+	if job.prompt, err = template.Single(data); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// apply は分析の結果をケースの予測に設定します
+func (job *evalJob) apply(result llm.BatchResult) {
+	if result.Err != nil {
+		job.outcome.Error = result.Err.Error()
+		return
+	}
+	analyzed := result.Result
+	job.outcome.PredictedType = analyzed.Type
+	job.outcome.PredictedTags = analyzed.Tags
+	// 収集時と同じく、明示的なマーカーをLLMの判定より優先する
+	job.outcome.PredictedSeverity = job.severity
+	if job.severity == "" && models.IsValidSeverity(analyzed.Severity) {
+		job.outcome.PredictedSeverity = analyzed.Severity
+	}
+}
+
+// sortedKeys はマップのキーを名前順に返します
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+func main() {
+	var (
+		configPath  = flag.String("config", "config.yaml", "Path to config file")
+		datasetPath = flag.String("dataset", "", "Labeled dataset (JSONL of comments and expected type/tags/severity)")
+		driverName  = flag.String("driver", "", "LLM driver to evaluate (default: llm.primary)")
+		promptPath  = flag.String("prompt", "", "Prompt template file to evaluate (default: the templates selected by the prompts config)")
+		compare     = flag.Int64("compare", 0, "Eval run id to compare with (default: the previous run of the same dataset)")
+		noSave      = flag.Bool("no-save", false, "Do not store the eval run in the database")
+		noCache     = flag.Bool("no-cache", false, "Do not use cached LLM analyses")
+		format      = flag.String("format", "text", "Output format: text or json")
+	)
+	flag.Parse()
+
+	if *datasetPath == "" {
+		fmt.Println("Usage: keval -dataset golden.jsonl [-driver name] [-prompt file.tmpl] [-compare run-id] [-no-save] [-format text|json]")
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("Invalid -format: %q (expected text or json)", *format)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	data, err := os.ReadFile(*datasetPath)
+	if err != nil {
+		log.Fatalf("Failed to read dataset: %v", err)
+	}
+	ds, err := parseDataset(data)
+	if err != nil {
+		log.Fatalf("Invalid dataset %s: %v", *datasetPath, err)
+	}
+
+	// 評価するドライバーだけのチェーン（フォールバックしない）
+	llmConfig := cfg.LLM
+	if *driverName != "" {
+		llmConfig.Primary = *driverName
+	}
+	llmConfig.Fallback = nil
+	if _, ok := llmConfig.Drivers[llmConfig.Primary]; !ok {
+		log.Fatalf("LLM driver %q is not defined in llm.drivers", llmConfig.Primary)
+	}
+	chain, err := llm.NewChainFromConfig(llmConfig)
+	if err != nil {
+		log.Fatalf("Invalid llm config: %v", err)
+	}
+	driver := chain.Drivers()[0]
+
+	selectTemplate, err := templateSelector(cfg.Prompts, *promptPath)
+	if err != nil {
+		log.Fatalf("Invalid prompt: %v", err)
+	}
+
+	db, err := database.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	// レポートをJSONで出力する場合も進捗が混ざらないよう、進捗は標準エラー出力に出す
+	var progress io.Writer = os.Stdout
+	if *format == "json" {
+		progress = os.Stderr
+	}
+	fmt.Fprintf(progress, "🧪 Evaluating %d cases with %s\n", len(ds.cases), describeDriver(driver.Name(), driver.Model()))
+
+	var cache *llm.SQLCache
+	if !cfg.LLM.Cache.Disabled && !*noCache {
+		cache = llm.NewSQLCache(db, cfg.LLM.Cache.TTL)
+		// SQLiteへの書き込みはワーカーではなく、分析が終わった後にまとめて行う
+		cache.SetDeferWrites(true)
+	}
+	e := &evaluator{
+		template:  selectTemplate,
+		parallel:  cfg.LLM.Parallel,
+		batchSize: cfg.Collection.BatchSize,
+		newAnalyzer: func() (llm.BatchAnalyzer, error) {
+			chain, err := llm.NewChainFromConfig(llmConfig)
+			if err != nil {
+				return nil, err
+			}
+			chain.SetRetryHandler(func(driver *llm.Driver, attempt, maxAttempts int, delay time.Duration, err error) {
+				fmt.Fprintf(progress, "⏳ LLM driver %s attempt %d/%d failed: %v (retrying in %s)\n", driver.Name(), attempt, maxAttempts, err, delay.Round(time.Millisecond))
+			})
+			if cache != nil {
+				chain.SetCache(cache)
+			}
+			return chain, nil
+		},
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := e.run(ctx, ds.cases)
+	if cache != nil {
+		// 失敗は SQLCache の Stats に記録される
+		_ = cache.Flush(context.WithoutCancel(ctx))
+	}
+	if err != nil {
+		log.Fatalf("Evaluation interrupted: %v", err)
+	}
+	tokens := fmt.Sprintf("%d input / %d output tokens", result.usage.InputTokens, result.usage.OutputTokens)
+	if result.usage.Estimated() {
+		tokens += " (estimated)"
+	}
+	fmt.Fprintf(progress, "💰 LLM usage: %d calls, %s\n\n", result.usage.Calls, tokens)
+
+	run := &evalRun{
+		Dataset:       filepath.Base(*datasetPath),
+		DatasetHash:   ds.hash,
+		Driver:        driver.Name(),
+		Model:         driver.Model(),
+		PromptVersion: strings.Join(result.promptVersions, ","),
+		CreatedAt:     time.Now(),
+		Outcomes:      result.outcomes,
+		Metrics:       computeMetrics(result.outcomes),
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	previousID := *compare
+	if previousID == 0 {
+		if previousID, err = previousRunID(ctx, db, run.Dataset, 0); err != nil {
+			log.Fatalf("Failed to find the previous eval run: %v", err)
+		}
+	}
+	report := &evalReport{Run: run}
+	if previousID != 0 {
+		previous, err := loadEvalRun(ctx, db, previousID)
+		if err != nil {
+			log.Fatalf("Failed to load eval run to compare with: %v", err)
+		}
+		report.Comparison = compareRuns(run, previous)
+	}
+
+	if !*noSave {
+		if _, err := saveEvalRun(ctx, db, run); err != nil {
+			log.Fatalf("Failed to save eval run: %v", err)
+		}
+	}
+
+	switch *format {
+	case "json":
+		err = renderJSON(os.Stdout, report)
+	default:
+		err = renderText(os.Stdout, report)
+	}
+	if err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+	if !*noSave {
+		fmt.Fprintf(progress, "\n✅ Saved as eval run #%d\n", run.ID)
+	}
+}
+
+// templateSelector はケースに使うプロンプトテンプレートを選ぶ関数を返します
+//
+// path を指定した場合は全てのケースにそのテンプレートを使い、指定しない場合は
+// 収集時と同じく設定ファイルの prompts からリポジトリ・言語で選びます。
+func templateSelector(cfg config.PromptsConfig, path string) (func(c evalCase) *prompt.Template, error) {
+	if path != "" {
+		template, err := prompt.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		return func(evalCase) *prompt.Template { return template }, nil
+	}
+
+	prompts, err := prompt.NewSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return func(c evalCase) *prompt.Template { return prompts.Select(c.Repository, c.Language) }, nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database/dbtest"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
+)
+
+// fakeAnalyzer はコメント本文ごとに決めた分析結果を返すテスト用のLLMです
+type fakeAnalyzer struct {
+	driver  *llm.Driver
+	results map[string]*llm.AnalysisResult
+}
+
+func (a *fakeAnalyzer) AnalyzeBatch(ctx context.Context, instructions string, items []llm.BatchItem) []llm.BatchResult {
+	results := make([]llm.BatchResult, len(items))
+	for i, item := range items {
+		body := item.Context[strings.Index(item.Context, "Comment:\n")+len("Comment:\n"):]
+		body = body[:strings.Index(body, "\n")]
+		results[i].ID = item.ID
+		if result, ok := a.results[body]; ok {
+			copied := *result
+			results[i].Result, results[i].Driver = &copied, a.driver
+		} else {
+			results[i].Err = errors.New("analysis failed")
+		}
+	}
+	return results
+}
+
+func loadGolden(t *testing.T) *dataset {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "golden.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to read dataset: %v", err)
+	}
+	ds, err := parseDataset(data)
+	if err != nil {
+		t.Fatalf("failed to parse dataset: %v", err)
+	}
+	return ds
+}
+
+func TestParseDataset_Golden(t *testing.T) {
+	// Act
+	ds := loadGolden(t)
+
+	// Assert
+	var ids []string
+	for _, c := range ds.cases {
+		ids = append(ids, c.ID)
+	}
+	if strings.Join(ids, ",") != "wrap-errors,sql-injection,missing-test,nil-deref,thanks" {
+		t.Errorf("unexpected cases: %v", ids)
+	}
+	if first := ds.cases[0]; first.Type != "maintenance" || len(first.Tags) != 2 || first.Severity != "major" || first.Language != "go" {
+		t.Errorf("unexpected first case: %+v", first)
+	}
+	if len(ds.hash) != 12 {
+		t.Errorf("expected a 12 character dataset hash, got %q", ds.hash)
+	}
+}
+
+func TestParseDataset_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"invalid type", `{"comment": "c", "type": "style"}`, `line 1: invalid type "style"`},
+		{"invalid severity", `{"comment": "c", "type": "bug", "severity": "critical"}`, `line 1: invalid severity "critical"`},
+		{"missing comment", `{"type": "bug"}`, "line 1: comment is required"},
+		{"duplicate id", "{\"id\": \"a\", \"comment\": \"c\", \"type\": \"bug\"}\n\n{\"id\": \"a\", \"comment\": \"d\", \"type\": \"bug\"}", `line 3: duplicate id "a" (first on line 1)`},
+		{"unknown field", `{"comment": "c", "type": "bug", "expected_type": "bug"}`, "line 1: json: unknown field"},
+		{"empty", "# only a comment\n", "dataset has no cases"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseDataset([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestComputeMetrics(t *testing.T) {
+	// Arrange
+	outcomes := []evalOutcome{
+		{CaseID: "1", ExpectedType: "bug", PredictedType: "bug", ExpectedTags: []string{"nil", "panic"}, PredictedTags: []string{"NIL"}, ExpectedSeverity: "major", PredictedSeverity: "major"},
+		{CaseID: "2", ExpectedType: "bug", PredictedType: "design", ExpectedSeverity: "minor", PredictedSeverity: "major"},
+		{CaseID: "3", ExpectedType: "bug", Error: "timeout", ExpectedSeverity: "nit"},
+		{CaseID: "4", ExpectedType: "design", PredictedType: "design", ExpectedTags: []string{"api"}, PredictedTags: []string{"api"}},
+		{CaseID: "5", ExpectedType: "testing", PredictedType: "bug"},
+	}
+
+	// Act
+	m := computeMetrics(outcomes)
+
+	// Assert
+	if m.Cases != 5 || m.Correct != 2 || m.Failed != 1 || m.Accuracy != 0.4 {
+		t.Errorf("unexpected totals: %+v", m)
+	}
+	want := map[string][2]float64{
+		"bug":     {0.5, 1.0 / 3},
+		"design":  {0.5, 1},
+		"testing": {0, 0},
+	}
+	for commentType, pr := range want {
+		class, ok := m.class(commentType)
+		if !ok {
+			t.Fatalf("expected metrics for %s", commentType)
+		}
+		if math.Abs(class.Precision-pr[0]) > 1e-9 || math.Abs(class.Recall-pr[1]) > 1e-9 {
+			t.Errorf("expected %s precision %.3f recall %.3f, got %+v", commentType, pr[0], pr[1], class)
+		}
+	}
+	if strings.Join(m.Labels, ",") != "testing,design,bug,(failed)" {
+		t.Errorf("expected labels in taxonomy order with failures last, got %v", m.Labels)
+	}
+	if row := m.Confusion["bug"]; len(row) != 4 || row[1] != 1 || row[2] != 1 || row[3] != 1 {
+		t.Errorf("unexpected confusion row for bug: %v", row)
+	}
+	if m.TagCases != 2 || math.Abs(m.TagOverlap-0.75) > 1e-9 {
+		t.Errorf("expected tag overlap 0.75 over 2 cases, got %.3f over %d", m.TagOverlap, m.TagCases)
+	}
+	if m.SeverityCases != 3 || math.Abs(m.SeverityAccuracy-1.0/3) > 1e-9 {
+		t.Errorf("expected severity accuracy 1/3 over 3 cases, got %.3f over %d", m.SeverityAccuracy, m.SeverityCases)
+	}
+}
+
+func TestEvaluate_ComparesWithPreviousRun(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	ds := loadGolden(t)
+	evaluate := func(results map[string]*llm.AnalysisResult, createdAt time.Time) *evalRun {
+		t.Helper()
+		e := &evaluator{
+			template:  func(evalCase) *prompt.Template { return prompt.Default() },
+			parallel:  2,
+			batchSize: 2,
+			newAnalyzer: func() (llm.BatchAnalyzer, error) {
+				return &fakeAnalyzer{driver: llm.NewDriver("fake", nil), results: results}, nil
+			},
+		}
+		result, err := e.run(context.Background(), ds.cases)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return &evalRun{
+			Dataset:       "golden.jsonl",
+			DatasetHash:   ds.hash,
+			Driver:        "fake",
+			PromptVersion: strings.Join(result.promptVersions, ","),
+			CreatedAt:     createdAt,
+			Outcomes:      result.outcomes,
+			Metrics:       computeMetrics(result.outcomes),
+		}
+	}
+	first := evaluate(map[string]*llm.AnalysisResult{
+		ds.cases[0].Comment: {Type: "implementation", Tags: []string{"errors"}, Severity: "major"},
+		ds.cases[1].Comment: {Type: "security", Tags: []string{"sql", "injection"}, Severity: "blocker"},
+		ds.cases[2].Comment: {Type: "testing"},
+		ds.cases[3].Comment: {Type: "bug", Severity: "major"},
+	}, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC))
+	if _, err := saveEvalRun(context.Background(), db, first); err != nil {
+		t.Fatalf("failed to save eval run: %v", err)
+	}
+
+	// Act
+	second := evaluate(map[string]*llm.AnalysisResult{
+		ds.cases[0].Comment: {Type: "maintenance", Tags: []string{"errors", "wrapping"}, Severity: "major"},
+		ds.cases[1].Comment: {Type: "security", Tags: []string{"sql"}, Severity: "blocker"},
+		ds.cases[2].Comment: {Type: "implementation"},
+		ds.cases[3].Comment: {Type: "bug", Severity: "major"},
+		ds.cases[4].Comment: {Type: "noise"},
+	}, time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC))
+	previousID, err := previousRunID(context.Background(), db, "golden.jsonl", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	previous, err := loadEvalRun(context.Background(), db, previousID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	report := &evalReport{Run: second, Comparison: compareRuns(second, previous)}
+	var text, jsonOut bytes.Buffer
+	if err := renderText(&text, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := renderJSON(&jsonOut, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	if previous.ID != first.ID || previous.Metrics.Accuracy != first.Metrics.Accuracy || previous.Metrics.Failed != 1 {
+		t.Errorf("expected the stored run to be loaded with the same metrics, got %+v", previous.Metrics)
+	}
+	if len(previous.Outcomes) != 5 || strings.Join(previous.Outcomes[0].PredictedTags, ",") != "errors" {
+		t.Errorf("expected the stored outcomes to keep their tags, got %+v", previous.Outcomes)
+	}
+	c := report.Comparison
+	if len(c.Fixed) != 2 || c.Fixed[0].CaseID != "wrap-errors" || c.Fixed[1].CaseID != "thanks" || c.Fixed[1].Before != failedLabel {
+		t.Errorf("unexpected fixed cases: %+v", c.Fixed)
+	}
+	if len(c.Regressed) != 1 || c.Regressed[0].CaseID != "missing-test" || c.Regressed[0].After != "implementation" {
+		t.Errorf("unexpected regressed cases: %+v", c.Regressed)
+	}
+	for _, want := range []string{
+		"✅ Accuracy: 0.800 (4/5)",
+		"🏷️  Tag overlap: 0.500 (3 cases)",
+		// nil-deref は明示的なマーカー（nit:）がLLMの判定より優先される
+		"🚦 Severity accuracy: 1.000 (3 cases)",
+		"   testing             0.000   0.000   0.000        1",
+		"Confusion matrix (rows: expected, columns: predicted)",
+		"🔁 Compared with run #1",
+		"Accuracy:          0.600 → 0.800 (+0.200)",
+		"✅ Fixed: wrap-errors (expected maintenance, was implementation)",
+		"❌ Regressed: missing-test (expected testing, now implementation)",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("expected %q in\n%s", want, text.String())
+		}
+	}
+	if strings.Contains(text.String(), "The dataset changed") {
+		t.Errorf("expected the same dataset not to be reported as changed")
+	}
+
+	var decoded evalReport
+	if err := json.Unmarshal(jsonOut.Bytes(), &decoded); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	if decoded.Run.Metrics.Accuracy != 0.8 || decoded.Comparison == nil || len(decoded.Comparison.Fixed) != 2 {
+		t.Errorf("unexpected JSON report: %s", jsonOut.String())
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// failedLabel は分析に失敗したケースの予測の表示名です（混同行列の列）
+const failedLabel = "(failed)"
+
+// evalOutcome は1件のケースの期待値と予測です
+type evalOutcome struct {
+	CaseID            string   `json:"case_id"`
+	ExpectedType      string   `json:"expected_type"`
+	PredictedType     string   `json:"predicted_type"`
+	ExpectedTags      []string `json:"expected_tags,omitempty"`
+	PredictedTags     []string `json:"predicted_tags,omitempty"`
+	ExpectedSeverity  string   `json:"expected_severity,omitempty"`
+	PredictedSeverity string   `json:"predicted_severity,omitempty"`
+	// Error は分析に失敗した場合のエラーです（失敗したケースは不正解として数える）
+	Error string `json:"error,omitempty"`
+}
+
+// correct は分類が期待どおりかどうかを返します
+func (o evalOutcome) correct() bool {
+	return o.Error == "" && o.PredictedType == o.ExpectedType
+}
+
+// predictedLabel は混同行列に使う予測の表示名を返します
+func (o evalOutcome) predictedLabel() string {
+	if o.Error != "" {
+		return failedLabel
+	}
+	return o.PredictedType
+}
+
+// classMetrics はコメント種別ごとの適合率と再現率です
+type classMetrics struct {
+	Type      string  `json:"type"`
+	Support   int     `json:"support"`
+	Predicted int     `json:"predicted"`
+	Correct   int     `json:"correct"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+}
+
+// evalMetrics は評価の集計です
+type evalMetrics struct {
+	Cases    int     `json:"cases"`
+	Correct  int     `json:"correct"`
+	Failed   int     `json:"failed"`
+	Accuracy float64 `json:"accuracy"`
+
+	Classes []classMetrics `json:"classes"`
+	// Labels は混同行列の行と列の順です（行は期待値、列は予測）
+	Labels    []string         `json:"labels"`
+	Confusion map[string][]int `json:"confusion"`
+
+	// TagOverlap は期待するタグがあるケースでの、期待と予測のタグのJaccard係数の平均です
+	TagOverlap float64 `json:"tag_overlap"`
+	TagCases   int     `json:"tag_cases"`
+	// SeverityAccuracy は期待する重要度があるケースでの正解率です
+	SeverityAccuracy float64 `json:"severity_accuracy"`
+	SeverityCases    int     `json:"severity_cases"`
+}
+
+// computeMetrics は正解率・種別ごとの適合率と再現率・混同行列・タグの一致度を計算します
+func computeMetrics(outcomes []evalOutcome) *evalMetrics {
+	m := &evalMetrics{Cases: len(outcomes), Confusion: make(map[string][]int)}
+
+	present := make(map[string]bool)
+	for _, o := range outcomes {
+		present[o.ExpectedType] = true
+		present[o.predictedLabel()] = true
+	}
+	// 分類体系の順に並べ、体系外の予測と失敗を最後に置く
+	for _, t := range models.CommentTypes {
+		if present[string(t)] {
+			m.Labels = append(m.Labels, string(t))
+			delete(present, string(t))
+		}
+	}
+	failed := present[failedLabel]
+	delete(present, failedLabel)
+	m.Labels = append(m.Labels, sortedKeys(present)...)
+	if failed {
+		m.Labels = append(m.Labels, failedLabel)
+	}
+
+	index := make(map[string]int)
+	for i, label := range m.Labels {
+		index[label] = i
+	}
+	classes := make(map[string]*classMetrics)
+	for _, label := range m.Labels {
+		if label != failedLabel {
+			classes[label] = &classMetrics{Type: label}
+		}
+	}
+
+	var overlap, severityCorrect float64
+	for _, o := range outcomes {
+		if m.Confusion[o.ExpectedType] == nil {
+			m.Confusion[o.ExpectedType] = make([]int, len(m.Labels))
+		}
+		m.Confusion[o.ExpectedType][index[o.predictedLabel()]]++
+
+		classes[o.ExpectedType].Support++
+		if o.Error != "" {
+			m.Failed++
+		} else {
+			classes[o.PredictedType].Predicted++
+		}
+		if o.correct() {
+			m.Correct++
+			classes[o.ExpectedType].Correct++
+		}
+
+		if len(o.ExpectedTags) > 0 {
+			m.TagCases++
+			overlap += jaccard(o.ExpectedTags, o.PredictedTags)
+		}
+		if o.ExpectedSeverity != "" {
+			m.SeverityCases++
+			if o.Error == "" && o.PredictedSeverity == o.ExpectedSeverity {
+				severityCorrect++
+			}
+		}
+	}
+
+	m.Accuracy = ratio(float64(m.Correct), m.Cases)
+	m.TagOverlap = ratio(overlap, m.TagCases)
+	m.SeverityAccuracy = ratio(severityCorrect, m.SeverityCases)
+	for _, label := range m.Labels {
+		class, ok := classes[label]
+		if !ok {
+			continue
+		}
+		class.Precision = ratio(float64(class.Correct), class.Predicted)
+		class.Recall = ratio(float64(class.Correct), class.Support)
+		if class.Precision+class.Recall > 0 {
+			class.F1 = 2 * class.Precision * class.Recall / (class.Precision + class.Recall)
+		}
+		m.Classes = append(m.Classes, *class)
+	}
+	return m
+}
+
+// class は種別の集計を返します（存在しない場合は false）
+func (m *evalMetrics) class(commentType string) (classMetrics, bool) {
+	for _, class := range m.Classes {
+		if class.Type == commentType {
+			return class, true
+		}
+	}
+	return classMetrics{}, false
+}
+
+// jaccard はタグの集合のJaccard係数を返します（大文字・小文字は区別しない）
+func jaccard(expected, predicted []string) float64 {
+	a := tagSet(expected)
+	b := tagSet(predicted)
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	intersection := 0
+	for tag := range a {
+		if b[tag] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// tagSet はタグを正規化した集合を返します
+func tagSet(tags []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, tag := range tags {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			set[tag] = true
+		}
+	}
+	return set
+}
+
+// ratio は n が0の場合に0を返す割り算です
+func ratio(value float64, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return value / float64(n)
+}
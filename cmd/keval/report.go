@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// caseChange は前回の評価から正誤が変わったケースです
+type caseChange struct {
+	CaseID   string `json:"case_id"`
+	Expected string `json:"expected"`
+	Before   string `json:"before"`
+	After    string `json:"after"`
+}
+
+// comparison は前回の評価との比較です
+type comparison struct {
+	Previous *evalRun `json:"previous"`
+	// SameDataset は前回と同じ内容のデータセットかどうかです（異なる場合はIDが同じケースだけを比較）
+	SameDataset bool         `json:"same_dataset"`
+	Fixed       []caseChange `json:"fixed"`
+	Regressed   []caseChange `json:"regressed"`
+}
+
+// evalReport は評価のレポートです
+type evalReport struct {
+	Run        *evalRun    `json:"run"`
+	Comparison *comparison `json:"comparison,omitempty"`
+}
+
+// compareRuns は前回の評価から正誤が変わったケースを求めます
+func compareRuns(current, previous *evalRun) *comparison {
+	before := make(map[string]evalOutcome)
+	for _, o := range previous.Outcomes {
+		before[o.CaseID] = o
+	}
+
+	c := &comparison{
+		Previous:    previous,
+		SameDataset: current.DatasetHash == previous.DatasetHash,
+		Fixed:       []caseChange{},
+		Regressed:   []caseChange{},
+	}
+	for _, o := range current.Outcomes {
+		p, ok := before[o.CaseID]
+		if !ok || p.ExpectedType != o.ExpectedType || p.correct() == o.correct() {
+			continue
+		}
+		change := caseChange{CaseID: o.CaseID, Expected: o.ExpectedType, Before: p.predictedLabel(), After: o.predictedLabel()}
+		if o.correct() {
+			c.Fixed = append(c.Fixed, change)
+		} else {
+			c.Regressed = append(c.Regressed, change)
+		}
+	}
+	return c
+}
+
+// renderText はテキストでレポートを出力します
+func renderText(w io.Writer, report *evalReport) error {
+	run, m := report.Run, report.Run.Metrics
+
+	fmt.Fprintf(w, "📊 Evaluation of %s (%d cases, dataset %s)\n", run.Dataset, m.Cases, run.DatasetHash)
+	fmt.Fprintf(w, "🤖 Driver: %s, prompt %s\n", describeDriver(run.Driver, run.Model), run.PromptVersion)
+	fmt.Fprintf(w, "✅ Accuracy: %.3f (%d/%d)\n", m.Accuracy, m.Correct, m.Cases)
+	if m.Failed > 0 {
+		fmt.Fprintf(w, "⚠️  Failed: %d cases (counted as incorrect)\n", m.Failed)
+	}
+	if m.TagCases > 0 {
+		fmt.Fprintf(w, "🏷️  Tag overlap: %.3f (%d cases)\n", m.TagOverlap, m.TagCases)
+	}
+	if m.SeverityCases > 0 {
+		fmt.Fprintf(w, "🚦 Severity accuracy: %.3f (%d cases)\n", m.SeverityAccuracy, m.SeverityCases)
+	}
+
+	width := len("type")
+	for _, label := range m.Labels {
+		width = max(width, len(label))
+	}
+
+	fmt.Fprintln(w, "\n📋 Per-class:")
+	fmt.Fprintf(w, "   %-*s  %9s  %6s  %6s  %7s\n", width, "type", "precision", "recall", "f1", "support")
+	for _, class := range m.Classes {
+		fmt.Fprintf(w, "   %-*s  %9.3f  %6.3f  %6.3f  %7d\n", width, class.Type, class.Precision, class.Recall, class.F1, class.Support)
+	}
+
+	fmt.Fprintln(w, "\n🧮 Confusion matrix (rows: expected, columns: predicted):")
+	header := fmt.Sprintf("   %-*s", width, "")
+	for _, label := range m.Labels {
+		header += "  " + label
+	}
+	fmt.Fprintln(w, header)
+	for _, label := range m.Labels {
+		row, ok := m.Confusion[label]
+		if !ok {
+			continue
+		}
+		line := fmt.Sprintf("   %-*s", width, label)
+		for i, count := range row {
+			cell := "."
+			if count > 0 {
+				cell = fmt.Sprint(count)
+			}
+			line += fmt.Sprintf("  %*s", len(m.Labels[i]), cell)
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	if report.Comparison != nil {
+		renderComparison(w, m, report.Comparison)
+	}
+	return nil
+}
+
+// renderComparison は前回の評価との差分を出力します
+func renderComparison(w io.Writer, m *evalMetrics, c *comparison) {
+	previous := c.Previous
+	pm := previous.Metrics
+	fmt.Fprintf(w, "\n🔁 Compared with run #%d (%s, %s, prompt %s):\n", previous.ID,
+		previous.CreatedAt.Local().Format("2006-01-02 15:04"), describeDriver(previous.Driver, previous.Model), previous.PromptVersion)
+	if !c.SameDataset {
+		fmt.Fprintf(w, "⚠️  The dataset changed since run #%d (%s), only cases with the same id are compared\n", previous.ID, previous.DatasetHash)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "   Accuracy:\t%s\n", formatDelta(pm.Accuracy, m.Accuracy))
+	if m.TagCases > 0 || pm.TagCases > 0 {
+		fmt.Fprintf(tw, "   Tag overlap:\t%s\n", formatDelta(pm.TagOverlap, m.TagOverlap))
+	}
+	if m.SeverityCases > 0 || pm.SeverityCases > 0 {
+		fmt.Fprintf(tw, "   Severity accuracy:\t%s\n", formatDelta(pm.SeverityAccuracy, m.SeverityAccuracy))
+	}
+	for _, label := range mergeLabels(m.Labels, pm.Labels) {
+		after, okAfter := m.class(label)
+		before, okBefore := pm.class(label)
+		if !okAfter && !okBefore {
+			continue
+		}
+		fmt.Fprintf(tw, "   F1 %s:\t%s\n", label, formatDelta(before.F1, after.F1))
+	}
+	tw.Flush()
+
+	for _, change := range c.Fixed {
+		fmt.Fprintf(w, "   ✅ Fixed: %s (expected %s, was %s)\n", change.CaseID, change.Expected, change.Before)
+	}
+	for _, change := range c.Regressed {
+		fmt.Fprintf(w, "   ❌ Regressed: %s (expected %s, now %s)\n", change.CaseID, change.Expected, change.After)
+	}
+	if len(c.Fixed) == 0 && len(c.Regressed) == 0 {
+		fmt.Fprintln(w, "   No cases changed between correct and incorrect")
+	}
+}
+
+// formatDelta は前回と今回の値と差を整形します
+func formatDelta(before, after float64) string {
+	return fmt.Sprintf("%.3f → %.3f (%+.3f)", before, after, after-before)
+}
+
+// mergeLabels は2つのラベルの列を重複なく順に並べます（失敗のラベルを除く）
+func mergeLabels(a, b []string) []string {
+	seen := map[string]bool{failedLabel: true}
+	var labels []string
+	for _, label := range append(append([]string{}, a...), b...) {
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// describeDriver はドライバー名とモデル名を表示用に整形します
+func describeDriver(driver, model string) string {
+	if model == "" {
+		return driver
+	}
+	return driver + "/" + model
+}
+
+// renderJSON はJSONでレポートを出力します
+func renderJSON(w io.Writer, report *evalReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// evalRun は1回の評価の実行です
+type evalRun struct {
+	ID            int64     `json:"id,omitempty"`
+	Dataset       string    `json:"dataset"`
+	DatasetHash   string    `json:"dataset_hash"`
+	Driver        string    `json:"driver"`
+	Model         string    `json:"model,omitempty"`
+	PromptVersion string    `json:"prompt_version"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	Outcomes []evalOutcome `json:"outcomes"`
+	Metrics  *evalMetrics  `json:"metrics"`
+}
+
+// saveEvalRun は評価の集計とケースごとの結果を保存し、実行のIDを返します
+func saveEvalRun(ctx context.Context, db *sql.DB, run *evalRun) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	m := run.Metrics
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO eval_runs (dataset, dataset_hash, driver, model, prompt_version, cases, failed,
+			accuracy, tag_overlap, severity_accuracy, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.Dataset, run.DatasetHash, run.Driver, run.Model, run.PromptVersion, m.Cases, m.Failed,
+		m.Accuracy, m.TagOverlap, m.SeverityAccuracy, run.CreatedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to save eval run: %w", err)
+	}
+	runID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get eval run id: %w", err)
+	}
+
+	for _, o := range run.Outcomes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO eval_results (run_id, case_id, expected_type, predicted_type, expected_tags, predicted_tags,
+				expected_severity, predicted_severity, error)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, o.CaseID, o.ExpectedType, o.PredictedType, encodeTags(o.ExpectedTags), encodeTags(o.PredictedTags),
+			o.ExpectedSeverity, o.PredictedSeverity, o.Error)
+		if err != nil {
+			return 0, fmt.Errorf("failed to save eval result %s: %w", o.CaseID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit eval run: %w", err)
+	}
+	run.ID = runID
+	return runID, nil
+}
+
+// previousRunID は同じデータセットの、before より前の最新の評価のIDを返します（ない場合は0）
+func previousRunID(ctx context.Context, db *sql.DB, dataset string, before int64) (int64, error) {
+	query := `SELECT id FROM eval_runs WHERE dataset = ?`
+	args := []interface{}{dataset}
+	if before > 0 {
+		query += " AND id < ?"
+		args = append(args, before)
+	}
+	query += " ORDER BY id DESC LIMIT 1"
+
+	var id int64
+	err := db.QueryRowContext(ctx, query, args...).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query previous eval run: %w", err)
+	}
+	return id, nil
+}
+
+// loadEvalRun は保存済みの評価を読み込み、ケースごとの結果から集計し直します
+func loadEvalRun(ctx context.Context, db *sql.DB, id int64) (*evalRun, error) {
+	run := &evalRun{ID: id}
+	err := db.QueryRowContext(ctx, `
+		SELECT dataset, dataset_hash, driver, model, prompt_version, created_at FROM eval_runs WHERE id = ?`, id).
+		Scan(&run.Dataset, &run.DatasetHash, &run.Driver, &run.Model, &run.PromptVersion, &run.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("eval run #%d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query eval run: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT case_id, expected_type, predicted_type, expected_tags, predicted_tags, expected_severity, predicted_severity, error
+		FROM eval_results WHERE run_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query eval results: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o evalOutcome
+		var expectedTags, predictedTags string
+		if err := rows.Scan(&o.CaseID, &o.ExpectedType, &o.PredictedType, &expectedTags, &predictedTags,
+			&o.ExpectedSeverity, &o.PredictedSeverity, &o.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan eval result: %w", err)
+		}
+		if o.ExpectedTags, err = decodeTags(expectedTags); err != nil {
+			return nil, err
+		}
+		if o.PredictedTags, err = decodeTags(predictedTags); err != nil {
+			return nil, err
+		}
+		run.Outcomes = append(run.Outcomes, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating eval results: %w", err)
+	}
+
+	run.Metrics = computeMetrics(run.Outcomes)
+	return run, nil
+}
+
+// encodeTags はタグをJSONの配列として保存します（空の場合は空文字列）
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(tags)
+	return string(data)
+}
+
+// decodeTags は encodeTags で保存したタグを読み込みます
+func decodeTags(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var tags []string
+	if err := json.Unmarshal([]byte(value), &tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/pkg/config"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+func main() {
+	var (
+		configPath   = flag.String("config", "config.yaml", "Path to config file")
+		maxScore     = flag.Float64("max-score", 0.3, "Only mine noise documents with relevance score at or below this value")
+		minNGram     = flag.Int("min-ngram", 2, "Minimum number of words in a mined phrase")
+		maxNGram     = flag.Int("max-ngram", 3, "Maximum number of words in a mined phrase")
+		minSupport   = flag.Int("min-support", 3, "Minimum number of noise documents containing a phrase")
+		minPrecision = flag.Float64("min-precision", 0.9, "Minimum estimated precision against non-noise documents")
+		maxRules     = flag.Int("max-rules", 20, "Maximum number of rules to propose")
+		apply        = flag.Bool("apply", false, "Write accepted rules into filter.learned_patterns of the config file")
+		yes          = flag.Bool("yes", false, "Accept all proposed rules without prompting (with -apply)")
+	)
+	flag.Parse()
+
+	fmt.Println("🧹 Knowledge Base Noise Pattern Miner")
+	fmt.Println("=====================================")
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx := context.Background()
+
+	noiseComments, otherComments, err := loadComments(ctx, db, *maxScore)
+	if err != nil {
+		log.Fatalf("Failed to load documents: %v", err)
+	}
+	fmt.Printf("📥 Loaded %d noise documents (score <= %.2f) and %d other documents\n",
+		len(noiseComments), *maxScore, len(otherComments))
+
+	if len(noiseComments) == 0 {
+		fmt.Println("ℹ️  No noise documents to mine")
+		return
+	}
+
+	rules := collector.MineNoisePatterns(noiseComments, otherComments, collector.NoiseMinerOptions{
+		MinNGram:     *minNGram,
+		MaxNGram:     *maxNGram,
+		MinSupport:   *minSupport,
+		MinPrecision: *minPrecision,
+		MaxRules:     *maxRules,
+	})
+
+	// 既に設定済みのフレーズは提案しない
+	rules = excludeKnownPatterns(rules, cfg.Filter.LearnedPatterns)
+
+	if len(rules) == 0 {
+		fmt.Println("ℹ️  No new rules meet the support and precision thresholds")
+		return
+	}
+
+	fmt.Printf("\n📝 Proposed filter rules (%d):\n", len(rules))
+	fmt.Println("-------------")
+	for i, rule := range rules {
+		printRule(os.Stdout, i+1, rule)
+	}
+
+	if !*apply {
+		fmt.Println("\nTip: Use -apply to write accepted rules into the config file")
+		return
+	}
+
+	var accepted []string
+	if *yes {
+		for _, rule := range rules {
+			accepted = append(accepted, rule.Phrase)
+		}
+	} else {
+		accepted = promptRules(os.Stdin, os.Stdout, rules)
+	}
+
+	if len(accepted) == 0 {
+		fmt.Println("\nℹ️  No rules accepted")
+		return
+	}
+
+	if err := config.AddLearnedPatterns(*configPath, accepted); err != nil {
+		log.Fatalf("Failed to update config: %v", err)
+	}
+	fmt.Printf("\n✅ Added %d rules to filter.learned_patterns in %s\n", len(accepted), *configPath)
+}
+
+// loadComments はnoiseドキュメントとそれ以外のドキュメントの元コメントを読み込みます
+func loadComments(ctx context.Context, db *sql.DB, maxScore float64) (noise, other []string, err error) {
+	query := `SELECT original_comment, comment_type, relevance_score FROM documents`
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment, commentType string
+		var score float64
+		if err := rows.Scan(&comment, &commentType, &score); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+
+		switch {
+		case commentType == string(models.CommentTypeNoise) && score <= maxScore:
+			noise = append(noise, comment)
+		case commentType != string(models.CommentTypeNoise):
+			other = append(other, comment)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+
+	return noise, other, nil
+}
+
+// excludeKnownPatterns は設定済みのフレーズと一致するルールを取り除きます
+func excludeKnownPatterns(rules []collector.NoiseRule, known []string) []collector.NoiseRule {
+	knownSet := make(map[string]bool)
+	for _, pattern := range known {
+		knownSet[collector.NormalizeComment(pattern)] = true
+	}
+
+	var filtered []collector.NoiseRule
+	for _, rule := range rules {
+		if !knownSet[rule.Phrase] {
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered
+}
+
+// printRule はルール候補を表示します
+func printRule(w io.Writer, index int, rule collector.NoiseRule) {
+	fmt.Fprintf(w, "%2d. %q\n", index, rule.Phrase)
+	fmt.Fprintf(w, "    precision: %.2f  noise hits: %d  other hits: %d  coverage: %.1f%%\n",
+		rule.Precision, rule.NoiseHits, rule.OtherHits, rule.Coverage*100)
+}
+
+// promptRules は各ルールを採用するかを対話的に確認します
+func promptRules(r io.Reader, w io.Writer, rules []collector.NoiseRule) []string {
+	scanner := bufio.NewScanner(r)
+
+	var accepted []string
+	for _, rule := range rules {
+		fmt.Fprintf(w, "Accept rule %q? [y/N]: ", rule.Phrase)
+		if !scanner.Scan() {
+			break
+		}
+		answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if answer == "y" || answer == "yes" {
+			accepted = append(accepted, rule.Phrase)
+		}
+	}
+	return accepted
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/database/dbtest"
+)
+
+func TestLoadComments_SplitsNoiseByScore(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	docs := []struct {
+		comment     string
+		commentType string
+		score       float64
+	}{
+		{"Nice catch, will follow up.", "noise", 0.1},
+		{"Nice catch, but the fix belongs elsewhere.", "noise", 0.6},
+		{"Please wrap errors with %w.", "maintenance", 0.8},
+	}
+	for i, doc := range docs {
+		_, err := db.Exec(`INSERT INTO documents (
+			summary, original_comment, file_path, directory_path, language,
+			repository, pr_number, pr_title, pr_url, comment_url,
+			author, comment_type, relevance_score, commented_at
+		) VALUES ('s', ?, 'main.go', '.', 'go', 'owner/repo', 1, 'PR', 'url', ?, 'user', ?, ?, ?)`,
+			doc.comment, i, doc.commentType, doc.score, time.Now())
+		if err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+
+	// Act
+	noise, other, err := loadComments(context.Background(), db, 0.3)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(noise) != 1 || noise[0] != "Nice catch, will follow up." {
+		t.Errorf("expected only the low-score noise document, got %v", noise)
+	}
+	if len(other) != 1 || other[0] != "Please wrap errors with %w." {
+		t.Errorf("expected only the non-noise document, got %v", other)
+	}
+}
+
+func TestPromptRules(t *testing.T) {
+	// Arrange
+	rules := []collector.NoiseRule{
+		{Phrase: "nice catch"},
+		{Phrase: "will follow up"},
+		{Phrase: "thanks for the quick"},
+	}
+	input := strings.NewReader("y\nn\nyes\n")
+	var output bytes.Buffer
+
+	// Act
+	accepted := promptRules(input, &output, rules)
+
+	// Assert
+	want := []string{"nice catch", "thanks for the quick"}
+	if len(accepted) != len(want) {
+		t.Fatalf("expected %v, got %v", want, accepted)
+	}
+	for i := range want {
+		if accepted[i] != want[i] {
+			t.Errorf("expected %q at %d, got %q", want[i], i, accepted[i])
+		}
+	}
+	if strings.Count(output.String(), "Accept rule") != 3 {
+		t.Errorf("expected 3 prompts, got output %q", output.String())
+	}
+}
+
+func TestExcludeKnownPatterns(t *testing.T) {
+	rules := []collector.NoiseRule{{Phrase: "nice catch"}, {Phrase: "will follow up"}}
+
+	filtered := excludeKnownPatterns(rules, []string{"Nice catch"})
+
+	if len(filtered) != 1 || filtered[0].Phrase != "will follow up" {
+		t.Errorf("expected only 'will follow up' to remain, got %v", filtered)
+	}
+}
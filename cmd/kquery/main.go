@@ -2,22 +2,37 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 
+	"github.com/pankona/knowledges/internal/collector"
 	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/pkg/models"
 )
 
 func main() {
 	var (
-		dbPath    = flag.String("db", "knowledge.db", "Path to database file")
-		directory = flag.String("dir", "", "Filter by directory (e.g., 'payment-service')")
-		filePath  = flag.String("file", "", "Filter by file path pattern (e.g., '*.rb', 'Orders.ts')")
-		author    = flag.String("author", "", "Filter by comment author")
-		commentType = flag.String("type", "", "Filter by comment type (e.g., 'security', 'performance')")
-		keyword   = flag.String("keyword", "", "Search in summary and original comment text")
-		verbose   = flag.Bool("v", false, "Show detailed output including original comment")
+		dbPath          = flag.String("db", "knowledge.db", "Path to database file")
+		directory       = flag.String("dir", "", "Filter by directory (e.g., 'payment-service')")
+		filePath        = flag.String("file", "", "Filter by file path pattern (e.g., '*.rb', 'Orders.ts')")
+		author          = flag.String("author", "", "Filter by comment author")
+		commentType     = flag.String("type", "", "Filter by comment type (e.g., 'security', 'performance')")
+		keyword         = flag.String("keyword", "", "Search in summary and original comment text")
+		symbol          = flag.String("symbol", "", "Filter by enclosing symbol (e.g., 'Refund', 'OrderService', 'OrderService.Refund')")
+		project         = flag.String("project", "", "Filter by monorepo project (e.g., 'services/payment')")
+		byProject       = flag.Bool("by-project", false, "Aggregate matching documents per project instead of listing them")
+		owner           = flag.String("owner", "", "Filter by CODEOWNERS owner (e.g., '@org/payments')")
+		role            = flag.String("role", "", "Filter by commenter role (reviewer, pr_author, third_party)")
+		severity        = flag.String("severity", "", "Filter by severity, comma separated (e.g., 'blocker,major')")
+		fileRole        = flag.String("file-role", "", "Filter by file role, comma separated (e.g., 'source,test')")
+		excludeFileRole = flag.String("exclude-file-role", "", "Exclude file roles, comma separated (e.g., 'generated,vendored')")
+		sortBy          = flag.String("sort", "relevance", "Sort order: relevance, severity or date")
+		obsolete        = flag.String("obsolete", "demote", "How to treat obsolete documents: demote (rank last), exclude or include")
+		collapse        = flag.Bool("collapse", false, "Collapse near-duplicate comments into one result per duplicate group")
+		verbose         = flag.Bool("v", false, "Show detailed output including original comment")
 	)
 	flag.Parse()
 
@@ -30,87 +45,67 @@ func main() {
 	}
 	defer db.Close()
 
-	ctx := context.Background()
-
-	// Build query with filters
-	baseQuery := `
-	SELECT id, summary, original_comment, file_path, directory_path, repository, 
-	       pr_number, pr_title, author, comment_type, relevance_score, commented_at
-	FROM documents WHERE 1=1`
-	
-	var conditions []string
-	var args []interface{}
-	argIndex := 1
-
-	if *directory != "" {
-		conditions = append(conditions, fmt.Sprintf(" AND (directory_path LIKE $%d OR file_path LIKE $%d)", argIndex, argIndex+1))
-		args = append(args, "%"+*directory+"%", "%"+*directory+"/%")
-		argIndex += 2
+	// 古いDBでも新しいカラムを参照できるようにマイグレーションを適用
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
-	if *filePath != "" {
-		conditions = append(conditions, fmt.Sprintf(" AND file_path LIKE $%d", argIndex))
-		args = append(args, "%"+*filePath+"%")
-		argIndex++
-	}
+	ctx := context.Background()
 
-	if *author != "" {
-		conditions = append(conditions, fmt.Sprintf(" AND author LIKE $%d", argIndex))
-		args = append(args, "%"+*author+"%")
-		argIndex++
+	severities, err := parseSeverities(*severity)
+	if err != nil {
+		log.Fatalf("Invalid -severity: %v", err)
 	}
-
-	if *commentType != "" {
-		conditions = append(conditions, fmt.Sprintf(" AND comment_type = $%d", argIndex))
-		args = append(args, *commentType)
-		argIndex++
+	fileRoles, err := parseFileRoles(*fileRole)
+	if err != nil {
+		log.Fatalf("Invalid -file-role: %v", err)
 	}
-
-	if *keyword != "" {
-		conditions = append(conditions, fmt.Sprintf(" AND (summary LIKE $%d OR original_comment LIKE $%d)", argIndex, argIndex+1))
-		args = append(args, "%"+*keyword+"%", "%"+*keyword+"%")
-		argIndex += 2
+	excludeFileRoles, err := parseFileRoles(*excludeFileRole)
+	if err != nil {
+		log.Fatalf("Invalid -exclude-file-role: %v", err)
 	}
-
-	for _, condition := range conditions {
-		baseQuery += condition
+	if *sortBy != "relevance" && *sortBy != "severity" && *sortBy != "date" {
+		log.Fatalf("Invalid -sort: %q (expected relevance, severity or date)", *sortBy)
 	}
-
-	baseQuery += " ORDER BY relevance_score DESC, commented_at DESC"
-
-	// Execute query
-	rows, err := db.QueryContext(ctx, baseQuery, args...)
-	if err != nil {
-		log.Fatalf("Failed to query documents: %v", err)
+	if *obsolete != "demote" && *obsolete != "exclude" && *obsolete != "include" {
+		log.Fatalf("Invalid -obsolete: %q (expected demote, exclude or include)", *obsolete)
 	}
-	defer rows.Close()
 
-	// Count results
-	var results []map[string]interface{}
-	for rows.Next() {
-		var id int64
-		var summary, originalComment, filePath, directoryPath, repository, prTitle, author, commentType string
-		var prNumber int
-		var relevanceScore float64
-		var commentedAt string
+	// Build query with filters
+	baseQuery, args := buildQuery(queryFilters{
+		directory:        *directory,
+		filePath:         *filePath,
+		author:           *author,
+		commentType:      *commentType,
+		keyword:          *keyword,
+		project:          normalizeProject(*project),
+		symbol:           strings.TrimSpace(*symbol),
+		owner:            collector.NormalizeOwnerQuery(*owner),
+		role:             *role,
+		severities:       severities,
+		fileRoles:        fileRoles,
+		excludeFileRoles: excludeFileRoles,
+		sortBy:           *sortBy,
+		obsolete:         *obsolete,
+	})
 
-		err := rows.Scan(&id, &summary, &originalComment, &filePath, &directoryPath, 
-			&repository, &prNumber, &prTitle, &author, &commentType, &relevanceScore, &commentedAt)
+	if *byProject {
+		summaries, err := runProjectSummary(ctx, db, baseQuery, args)
 		if err != nil {
-			log.Printf("Failed to scan row: %v", err)
-			continue
+			log.Fatalf("Failed to aggregate documents: %v", err)
 		}
+		printProjectSummaries(summaries)
+		return
+	}
 
-		results = append(results, map[string]interface{}{
-			"id": id, "summary": summary, "originalComment": originalComment,
-			"filePath": filePath, "directoryPath": directoryPath, "repository": repository,
-			"prNumber": prNumber, "prTitle": prTitle, "author": author,
-			"commentType": commentType, "relevanceScore": relevanceScore, "commentedAt": commentedAt,
-		})
+	// Execute query
+	results, err := runQuery(ctx, db, baseQuery, args)
+	if err != nil {
+		log.Fatalf("Failed to query documents: %v", err)
 	}
 
-	if err = rows.Err(); err != nil {
-		log.Fatalf("Error iterating rows: %v", err)
+	if *collapse {
+		results = collapseDuplicates(results)
 	}
 
 	// Show results
@@ -131,10 +126,24 @@ func main() {
 		fmt.Println("  -author username              # Search by reviewer")
 		fmt.Println("  -type implementation          # Search by comment type")
 		fmt.Println("  -keyword security             # Search by keyword")
+		fmt.Println("  -symbol Refund                # Search by enclosing function/type")
+		fmt.Println("  -project services/payment     # Search by monorepo project")
+		fmt.Println("  -by-project                   # Aggregate per project")
+		fmt.Println("  -owner @org/payments          # Search by CODEOWNERS owner")
+		fmt.Println("  -role reviewer                # Search by commenter role")
+		fmt.Println("  -collapse                     # Collapse near-duplicate comments")
+		fmt.Println("  -severity blocker,major       # Search by severity")
+		fmt.Println("  -file-role source,test        # Search by file role")
+		fmt.Println("  -exclude-file-role generated  # Exclude file roles")
+		fmt.Println("  -obsolete include             # Rank obsolete documents normally")
 		fmt.Println("  -v                            # Show full comment text")
 		fmt.Println("\nAvailable types:")
 		fmt.Println("  implementation, security, testing, business, design,")
 		fmt.Println("  maintenance, explanation, bug, noise")
+		fmt.Println("\nAvailable severities:")
+		fmt.Println("  blocker, major, minor, nit")
+		fmt.Println("\nAvailable file roles:")
+		fmt.Println("  source, test, config, generated, vendored, migration, docs")
 		return
 	}
 
@@ -143,15 +152,65 @@ func main() {
 
 	for _, result := range results {
 		fmt.Printf("ID: %d\n", result["id"])
-		fmt.Printf("📁 File: %s\n", result["filePath"])
-		fmt.Printf("📦 Repository: %s\n", result["repository"])
+		fmt.Printf("📁 File: %s", result["filePath"])
+		if result["currentPath"] != "" {
+			fmt.Printf(" → %s", result["currentPath"])
+		}
+		if result["fileRole"] != "" {
+			fmt.Printf(" (%s)", result["fileRole"])
+		}
+		if result["staleness"] == models.StalenessObsolete {
+			fmt.Printf(" [obsolete - no longer in the codebase]")
+		}
+		fmt.Println()
+		if result["symbol"] != "" {
+			fmt.Printf("🔣 Symbol: %s\n", result["symbol"])
+		}
+		if result["owners"] != "" {
+			fmt.Printf("👥 Owners: %s\n", result["owners"])
+		}
+		fmt.Printf("📦 Repository: %s", result["repository"])
+		if result["project"] != "" {
+			fmt.Printf(" (project: %s)", result["project"])
+		}
+		fmt.Println()
 		fmt.Printf("🔗 PR: #%d - %s\n", result["prNumber"], result["prTitle"])
-		fmt.Printf("👤 Author: %s\n", result["author"])
-		fmt.Printf("🏷️  Type: %s (Score: %.2f)\n", result["commentType"], result["relevanceScore"])
+		fmt.Printf("👤 Author: %s", result["author"])
+		if result["commentRole"] != "" {
+			fmt.Printf(" (%s)", result["commentRole"])
+		}
+		fmt.Println()
+		fmt.Printf("🏷️  Type: %s (Score: %.2f)", result["commentType"], result["relevanceScore"])
+		if result["severity"] != "" {
+			fmt.Printf(" [%s]", result["severity"])
+		}
+		if result["analysisMethod"] == models.AnalysisMethodHeuristic {
+			fmt.Printf(" [heuristic - pending re-analysis]")
+		}
+		fmt.Println()
 		fmt.Printf("📅 Date: %s\n", result["commentedAt"])
 		fmt.Printf("💭 Summary: %s\n", result["summary"])
-		
+		if count, ok := result["duplicateCount"].(int); ok && count > 0 {
+			fmt.Printf("🔁 Near-duplicates: %d more (group %s)\n", count, result["duplicateGroup"])
+		}
+
 		if *verbose {
+			if result["llmDriver"] != "" {
+				fmt.Printf("🤖 Analyzed by: %s", result["llmDriver"])
+				if result["llmModel"] != "" {
+					fmt.Printf(" (%s)", result["llmModel"])
+				}
+				if result["promptVersion"] != "" {
+					fmt.Printf(", prompt %s", result["promptVersion"])
+				}
+				if count, ok := result["repairCount"].(int); ok && count > 0 {
+					fmt.Printf(", repaired %d times", count)
+				}
+				if input, ok := result["inputTokens"].(int); ok && input > 0 {
+					fmt.Printf(", %d/%d tokens", input, result["outputTokens"])
+				}
+				fmt.Println()
+			}
 			fmt.Printf("📝 Original Comment:\n%s\n", result["originalComment"])
 		}
 		fmt.Println("---")
@@ -160,4 +219,350 @@ func main() {
 	if !*verbose && len(results) > 0 {
 		fmt.Println("\nTip: Use -v flag to see full comment text")
 	}
-}
\ No newline at end of file
+}
+
+// runQuery はクエリを実行して結果を読み込みます
+func runQuery(ctx context.Context, db *sql.DB, query string, args []interface{}) ([]map[string]interface{}, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []map[string]interface{}
+	for rows.Next() {
+		var id int64
+		var summary, originalComment, filePath, currentPath, directoryPath, project, symbol, fileRole, owners, staleness, repository, prTitle, author, commentRole, commentType, severity, analysisMethod, llmDriver, llmModel, promptVersion, duplicateGroup string
+		var prNumber, repairCount, inputTokens, outputTokens int
+		var relevanceScore float64
+		var commentedAt string
+
+		err := rows.Scan(&id, &summary, &originalComment, &filePath, &currentPath, &directoryPath, &project, &symbol, &fileRole, &owners, &staleness,
+			&repository, &prNumber, &prTitle, &author, &commentRole, &commentType, &relevanceScore, &severity, &analysisMethod, &llmDriver, &llmModel, &promptVersion, &repairCount, &inputTokens, &outputTokens, &duplicateGroup, &commentedAt)
+		if err != nil {
+			log.Printf("Failed to scan row: %v", err)
+			continue
+		}
+
+		results = append(results, map[string]interface{}{
+			"id": id, "summary": summary, "originalComment": originalComment,
+			"filePath": filePath, "currentPath": currentPath, "directoryPath": directoryPath, "project": project, "symbol": symbol, "fileRole": fileRole, "owners": owners, "staleness": staleness, "repository": repository,
+			"prNumber": prNumber, "prTitle": prTitle, "author": author, "commentRole": commentRole,
+			"commentType": commentType, "relevanceScore": relevanceScore, "commentedAt": commentedAt,
+			"severity": severity, "analysisMethod": analysisMethod, "llmDriver": llmDriver, "llmModel": llmModel, "promptVersion": promptVersion, "repairCount": repairCount, "inputTokens": inputTokens, "outputTokens": outputTokens, "duplicateGroup": duplicateGroup,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return results, nil
+}
+
+// queryFilters は検索条件を表現します
+type queryFilters struct {
+	directory        string
+	filePath         string
+	author           string
+	commentType      string
+	keyword          string
+	project          string
+	symbol           string
+	owner            string
+	role             string
+	severities       []string
+	fileRoles        []string
+	excludeFileRoles []string
+	sortBy           string
+	obsolete         string
+}
+
+// buildQuery は検索条件からSQLクエリと引数を組み立てます
+func buildQuery(filters queryFilters) (string, []interface{}) {
+	baseQuery := `
+	SELECT id, summary, original_comment, file_path, current_path, directory_path, project, symbol, file_role, owners, staleness, repository, 
+	       pr_number, pr_title, author, comment_role, comment_type, relevance_score, severity, analysis_method, llm_driver, llm_model, prompt_version, repair_count, llm_input_tokens, llm_output_tokens, duplicate_group, commented_at
+	FROM documents WHERE 1=1`
+
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	if filters.directory != "" {
+		conditions = append(conditions, fmt.Sprintf(" AND (directory_path LIKE $%d OR %s)", argIndex, pathMatchCondition(fmt.Sprintf("$%d", argIndex+1))))
+		args = append(args, "%"+filters.directory+"%", "%"+filters.directory+"/%")
+		argIndex += 2
+	}
+
+	if filters.filePath != "" {
+		conditions = append(conditions, " AND "+pathMatchCondition(fmt.Sprintf("$%d", argIndex)))
+		args = append(args, "%"+filters.filePath+"%")
+		argIndex++
+	}
+
+	if filters.author != "" {
+		conditions = append(conditions, fmt.Sprintf(" AND author LIKE $%d", argIndex))
+		args = append(args, "%"+filters.author+"%")
+		argIndex++
+	}
+
+	if filters.commentType != "" {
+		conditions = append(conditions, fmt.Sprintf(" AND comment_type = $%d", argIndex))
+		args = append(args, filters.commentType)
+		argIndex++
+	}
+
+	if filters.keyword != "" {
+		conditions = append(conditions, fmt.Sprintf(" AND (summary LIKE $%d OR original_comment LIKE $%d)", argIndex, argIndex+1))
+		args = append(args, "%"+filters.keyword+"%", "%"+filters.keyword+"%")
+		argIndex += 2
+	}
+
+	if filters.project != "" {
+		conditions = append(conditions, fmt.Sprintf(" AND project = $%d", argIndex))
+		args = append(args, filters.project)
+		argIndex++
+	}
+
+	if filters.symbol != "" {
+		// "Refund" は "OrderService.Refund" に、"OrderService" はそのメソッドにもマッチする
+		conditions = append(conditions, fmt.Sprintf(" AND (symbol = $%d OR symbol LIKE $%d ESCAPE '\\' OR symbol LIKE $%d ESCAPE '\\')", argIndex, argIndex+1, argIndex+2))
+		escaped := escapeLike(filters.symbol)
+		args = append(args, filters.symbol, "%."+escaped, escaped+".%")
+		argIndex += 3
+	}
+
+	if filters.owner != "" {
+		// ownersは空白区切りのため、前後に空白を付けて完全一致で検索する
+		conditions = append(conditions, fmt.Sprintf(" AND instr(' ' || owners || ' ', $%d) > 0", argIndex))
+		args = append(args, " "+filters.owner+" ")
+		argIndex++
+	}
+
+	if filters.role != "" {
+		conditions = append(conditions, fmt.Sprintf(" AND comment_role = $%d", argIndex))
+		args = append(args, filters.role)
+		argIndex++
+	}
+
+	if len(filters.severities) > 0 {
+		placeholders := make([]string, len(filters.severities))
+		for i, severity := range filters.severities {
+			placeholders[i] = fmt.Sprintf("$%d", argIndex)
+			args = append(args, severity)
+			argIndex++
+		}
+		conditions = append(conditions, fmt.Sprintf(" AND severity IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if len(filters.fileRoles) > 0 {
+		placeholders := make([]string, len(filters.fileRoles))
+		for i, fileRole := range filters.fileRoles {
+			placeholders[i] = fmt.Sprintf("$%d", argIndex)
+			args = append(args, fileRole)
+			argIndex++
+		}
+		conditions = append(conditions, fmt.Sprintf(" AND file_role IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if len(filters.excludeFileRoles) > 0 {
+		placeholders := make([]string, len(filters.excludeFileRoles))
+		for i, fileRole := range filters.excludeFileRoles {
+			placeholders[i] = fmt.Sprintf("$%d", argIndex)
+			args = append(args, fileRole)
+			argIndex++
+		}
+		conditions = append(conditions, fmt.Sprintf(" AND file_role NOT IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if filters.obsolete == "exclude" {
+		conditions = append(conditions, " AND staleness != 'obsolete'")
+	}
+
+	for _, condition := range conditions {
+		baseQuery += condition
+	}
+
+	// 参照先が存在しないドキュメントは既定で末尾に並べる
+	orderBy := " ORDER BY "
+	if filters.obsolete == "demote" {
+		orderBy += "staleness = 'obsolete', "
+	}
+
+	switch filters.sortBy {
+	case "severity":
+		baseQuery += orderBy + `CASE severity
+			WHEN 'blocker' THEN 0 WHEN 'major' THEN 1 WHEN 'minor' THEN 2 WHEN 'nit' THEN 3 ELSE 4
+		END, relevance_score DESC, commented_at DESC`
+	case "date":
+		baseQuery += orderBy + "commented_at DESC, relevance_score DESC"
+	default:
+		baseQuery += orderBy + "relevance_score DESC, commented_at DESC"
+	}
+
+	return baseQuery, args
+}
+
+// projectSummary はプロジェクト単位の集計結果です
+type projectSummary struct {
+	project        string
+	documents      int
+	highSeverity   int
+	avgRelevance   float64
+	topCommentType string
+}
+
+// runProjectSummary は検索条件にマッチするドキュメントをプロジェクト単位で集計します
+func runProjectSummary(ctx context.Context, db *sql.DB, query string, args []interface{}) ([]projectSummary, error) {
+	summaryQuery := `
+	WITH matched AS (` + query + `),
+	type_counts AS (
+		SELECT project, comment_type, COUNT(*) AS cnt,
+		       ROW_NUMBER() OVER (PARTITION BY project ORDER BY COUNT(*) DESC, comment_type) AS rn
+		FROM matched GROUP BY project, comment_type
+	)
+	SELECT m.project, COUNT(*), SUM(CASE WHEN m.severity IN ('blocker', 'major') THEN 1 ELSE 0 END),
+	       AVG(m.relevance_score), COALESCE(t.comment_type, '')
+	FROM matched m
+	LEFT JOIN type_counts t ON t.project = m.project AND t.rn = 1
+	GROUP BY m.project
+	ORDER BY COUNT(*) DESC, m.project`
+
+	rows, err := db.QueryContext(ctx, summaryQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []projectSummary
+	for rows.Next() {
+		var s projectSummary
+		if err := rows.Scan(&s.project, &s.documents, &s.highSeverity, &s.avgRelevance, &s.topCommentType); err != nil {
+			return nil, fmt.Errorf("failed to scan project summary: %w", err)
+		}
+		summaries = append(summaries, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// printProjectSummaries はプロジェクト単位の集計結果を表示します
+func printProjectSummaries(summaries []projectSummary) {
+	fmt.Printf("📈 Found documents in %d projects\n", len(summaries))
+	if len(summaries) == 0 {
+		return
+	}
+
+	fmt.Println("🗂️  Projects:")
+	fmt.Println("-------------")
+	fmt.Printf("%-40s %6s %6s %9s  %s\n", "PROJECT", "DOCS", "HIGH", "AVG SCORE", "TOP TYPE")
+	for _, s := range summaries {
+		project := s.project
+		if project == "" {
+			project = "(unassigned)"
+		}
+		fmt.Printf("%-40s %6d %6d %9.2f  %s\n", project, s.documents, s.highSeverity, s.avgRelevance, s.topCommentType)
+	}
+	fmt.Println("\nTip: Use -project <name> to list the documents of a project")
+}
+
+// pathMatchCondition は記録時のパス・リネーム後の現在のパス・過去のパスのいずれかが
+// パターンにマッチする条件を返します（エイリアスは krename で記録）
+func pathMatchCondition(placeholder string) string {
+	return fmt.Sprintf(`(file_path LIKE %[1]s OR current_path LIKE %[1]s OR EXISTS (
+		SELECT 1 FROM path_aliases pa
+		WHERE pa.repository = documents.repository
+		  AND pa.current_path = CASE WHEN documents.current_path != '' THEN documents.current_path ELSE documents.file_path END
+		  AND pa.alias_path LIKE %[1]s))`, placeholder)
+}
+
+// escapeLike はLIKE検索のワイルドカード文字をエスケープします
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	return replacer.Replace(value)
+}
+
+// normalizeProject は検索用のプロジェクト指定を正規化します
+func normalizeProject(project string) string {
+	project = strings.TrimSpace(project)
+	if project == "" {
+		return ""
+	}
+	project = strings.Trim(strings.TrimPrefix(project, "./"), "/")
+	if project == "" || project == "." {
+		return collector.RootProject
+	}
+	return project
+}
+
+// parseSeverities はカンマ区切りの重要度リストを検証して分解します
+func parseSeverities(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var severities []string
+	for _, part := range strings.Split(value, ",") {
+		severity := strings.ToLower(strings.TrimSpace(part))
+		if severity == "" {
+			continue
+		}
+		if !models.IsValidSeverity(severity) {
+			return nil, fmt.Errorf("unknown severity %q (expected one of %s)", severity, strings.Join(models.Severities, ", "))
+		}
+		severities = append(severities, severity)
+	}
+	return severities, nil
+}
+
+// parseFileRoles はカンマ区切りのファイル役割リストを検証して分解します
+func parseFileRoles(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var fileRoles []string
+	for _, part := range strings.Split(value, ",") {
+		fileRole := strings.ToLower(strings.TrimSpace(part))
+		if fileRole == "" {
+			continue
+		}
+		if !models.IsValidFileRole(fileRole) {
+			return nil, fmt.Errorf("unknown file role %q (expected one of %s)", fileRole, strings.Join(models.FileRoles, ", "))
+		}
+		fileRoles = append(fileRoles, fileRole)
+	}
+	return fileRoles, nil
+}
+
+// collapseDuplicates は同じ重複グループの結果を先頭の1件にまとめます
+//
+// 結果はスコア順に並んでいるため、各グループで最も関連度の高いドキュメントが残ります。
+func collapseDuplicates(results []map[string]interface{}) []map[string]interface{} {
+	var collapsed []map[string]interface{}
+	representatives := make(map[string]map[string]interface{})
+
+	for _, result := range results {
+		group, _ := result["duplicateGroup"].(string)
+		if group == "" {
+			collapsed = append(collapsed, result)
+			continue
+		}
+
+		if representative, ok := representatives[group]; ok {
+			representative["duplicateCount"] = representative["duplicateCount"].(int) + 1
+			continue
+		}
+
+		result["duplicateCount"] = 0
+		representatives[group] = result
+		collapsed = append(collapsed, result)
+	}
+
+	return collapsed
+}
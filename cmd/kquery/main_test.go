@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/internal/database/dbtest"
 	"github.com/pankona/knowledges/pkg/models"
 )
 
@@ -113,19 +117,572 @@ func TestQueryWithNoResults_ShowsHelpMessage(t *testing.T) {
 	t.Skip("Test implementation pending - TDD Red phase")
 }
 
+func TestBuildQuery_RoleFilter(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	now := time.Now()
+	for i, role := range []string{"reviewer", "pr_author", "third_party", "reviewer"} {
+		doc := &models.Document{
+			Summary:         "Summary",
+			OriginalComment: "Comment",
+			FilePath:        "main.go",
+			DirectoryPath:   ".",
+			Language:        "go",
+			Repository:      "owner/repo",
+			PRNumber:        1,
+			PRTitle:         "PR",
+			PRURL:           "https://github.com/owner/repo/pull/1",
+			CommentURL:      fmt.Sprintf("https://github.com/owner/repo/pull/1#discussion_r%d", i),
+			Author:          "user",
+			CommentRole:     role,
+			CommentType:     "implementation",
+			RelevanceScore:  0.8,
+			CommentedAt:     now,
+			CollectedAt:     now,
+			UpdatedAt:       now,
+		}
+		if err := insertTestDocument(db, doc); err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+
+	// Act
+	query, args := buildQuery(queryFilters{role: "reviewer"})
+	results, err := runQuery(context.Background(), db, query, args)
+	if err != nil {
+		t.Fatalf("Failed to query documents: %v", err)
+	}
+	count := len(results)
+
+	// Assert
+	if count != 2 {
+		t.Errorf("Expected 2 reviewer documents, got %d", count)
+	}
+}
+
+func TestBuildQuery_SeverityFilterAndSort(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	now := time.Now()
+	docs := []struct {
+		severity string
+		score    float64
+	}{
+		{"nit", 0.9},
+		{"major", 0.5},
+		{"blocker", 0.4},
+		{"minor", 0.8},
+		{"major", 0.7},
+	}
+	for i, d := range docs {
+		doc := &models.Document{
+			Summary:         "Summary",
+			OriginalComment: "Comment",
+			FilePath:        "main.go",
+			DirectoryPath:   ".",
+			Language:        "go",
+			Repository:      "owner/repo",
+			PRNumber:        1,
+			PRTitle:         "PR",
+			PRURL:           "https://github.com/owner/repo/pull/1",
+			CommentURL:      fmt.Sprintf("https://github.com/owner/repo/pull/1#discussion_r%d", i),
+			Author:          "user",
+			CommentType:     "implementation",
+			RelevanceScore:  d.score,
+			Severity:        d.severity,
+			CommentedAt:     now,
+			CollectedAt:     now,
+			UpdatedAt:       now,
+		}
+		if err := insertTestDocument(db, doc); err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+
+	// Act
+	query, args := buildQuery(queryFilters{severities: []string{"blocker", "major"}, sortBy: "severity"})
+	results, err := runQuery(context.Background(), db, query, args)
+	if err != nil {
+		t.Fatalf("Failed to query documents: %v", err)
+	}
+
+	var got []string
+	for _, result := range results {
+		got = append(got, fmt.Sprintf("%s/%.1f", result["severity"], result["relevanceScore"]))
+	}
+
+	// Assert
+	want := []string{"blocker/0.4", "major/0.7", "major/0.5"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestBuildQuery_FileRoleFilters(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	now := time.Now()
+	for i, fileRole := range []string{"source", "test", "generated", "vendored", "source"} {
+		doc := &models.Document{
+			Summary:         "Summary",
+			OriginalComment: "Comment",
+			FilePath:        fmt.Sprintf("file%d.go", i),
+			DirectoryPath:   ".",
+			Language:        "go",
+			FileRole:        fileRole,
+			Repository:      "owner/repo",
+			PRNumber:        1,
+			PRTitle:         "PR",
+			PRURL:           "https://github.com/owner/repo/pull/1",
+			CommentURL:      fmt.Sprintf("https://github.com/owner/repo/pull/1#discussion_r%d", i),
+			Author:          "user",
+			CommentType:     "implementation",
+			RelevanceScore:  0.8,
+			CommentedAt:     now,
+			CollectedAt:     now,
+			UpdatedAt:       now,
+		}
+		if err := insertTestDocument(db, doc); err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filters queryFilters
+		want    int
+	}{
+		{"include source", queryFilters{fileRoles: []string{"source"}}, 2},
+		{"include source and test", queryFilters{fileRoles: []string{"source", "test"}}, 3},
+		{"exclude generated and vendored", queryFilters{excludeFileRoles: []string{"generated", "vendored"}}, 3},
+		{"include and exclude", queryFilters{fileRoles: []string{"source", "test"}, excludeFileRoles: []string{"test"}}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			query, args := buildQuery(tt.filters)
+			results, err := runQuery(context.Background(), db, query, args)
+			if err != nil {
+				t.Fatalf("Failed to query documents: %v", err)
+			}
+
+			// Assert
+			if len(results) != tt.want {
+				t.Errorf("Expected %d documents, got %d", tt.want, len(results))
+			}
+		})
+	}
+}
+
+func TestBuildQuery_OwnerFilter(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	now := time.Now()
+	ownerSets := [][]string{
+		{"@org/payments"},
+		{"@org/payments-core"},
+		{"@org/billing", "@org/payments"},
+		nil,
+	}
+	for i, owners := range ownerSets {
+		doc := &models.Document{
+			Summary:         "Summary",
+			OriginalComment: "Comment",
+			FilePath:        fmt.Sprintf("file%d.go", i),
+			DirectoryPath:   ".",
+			Language:        "go",
+			Owners:          owners,
+			Repository:      "owner/repo",
+			PRNumber:        1,
+			PRTitle:         "PR",
+			PRURL:           "https://github.com/owner/repo/pull/1",
+			CommentURL:      fmt.Sprintf("https://github.com/owner/repo/pull/1#discussion_r%d", i),
+			Author:          "user",
+			CommentType:     "implementation",
+			RelevanceScore:  0.8,
+			CommentedAt:     now,
+			CollectedAt:     now,
+			UpdatedAt:       now,
+		}
+		if err := insertTestDocument(db, doc); err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+
+	// Act
+	query, args := buildQuery(queryFilters{owner: "@org/payments"})
+	results, err := runQuery(context.Background(), db, query, args)
+	if err != nil {
+		t.Fatalf("Failed to query documents: %v", err)
+	}
+
+	// Assert: "@org/payments-core" には部分一致しない
+	if len(results) != 2 {
+		t.Errorf("Expected 2 documents owned by @org/payments, got %d", len(results))
+	}
+}
+
+func TestBuildQuery_ProjectFilterAndSummary(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	now := time.Now()
+	docs := []struct {
+		project     string
+		commentType string
+		severity    string
+		score       float64
+	}{
+		{"services/payment", "security", "blocker", 0.9},
+		{"services/payment", "security", "minor", 0.7},
+		{"services/payment", "testing", "", 0.5},
+		{"services/search", "implementation", "major", 0.6},
+		{".", "maintenance", "nit", 0.4},
+	}
+	for i, d := range docs {
+		doc := &models.Document{
+			Summary:         "Summary",
+			OriginalComment: "Comment",
+			FilePath:        fmt.Sprintf("%s/file%d.go", d.project, i),
+			DirectoryPath:   d.project,
+			Project:         d.project,
+			Language:        "go",
+			Repository:      "owner/repo",
+			PRNumber:        1,
+			PRTitle:         "PR",
+			PRURL:           "https://github.com/owner/repo/pull/1",
+			CommentURL:      fmt.Sprintf("https://github.com/owner/repo/pull/1#discussion_r%d", i),
+			Author:          "user",
+			CommentType:     d.commentType,
+			RelevanceScore:  d.score,
+			Severity:        d.severity,
+			CommentedAt:     now,
+			CollectedAt:     now,
+			UpdatedAt:       now,
+		}
+		if err := insertTestDocument(db, doc); err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+
+	// Act: プロジェクトで絞り込み
+	query, args := buildQuery(queryFilters{project: normalizeProject("./services/payment/")})
+	results, err := runQuery(context.Background(), db, query, args)
+	if err != nil {
+		t.Fatalf("Failed to query documents: %v", err)
+	}
+
+	// Assert
+	if len(results) != 3 {
+		t.Errorf("Expected 3 documents in services/payment, got %d", len(results))
+	}
+
+	// Act: プロジェクト単位で集計
+	query, args = buildQuery(queryFilters{})
+	summaries, err := runProjectSummary(context.Background(), db, query, args)
+	if err != nil {
+		t.Fatalf("Failed to aggregate documents: %v", err)
+	}
+
+	// Assert
+	if len(summaries) != 3 {
+		t.Fatalf("Expected 3 projects, got %d", len(summaries))
+	}
+	first := summaries[0]
+	if first.project != "services/payment" || first.documents != 3 || first.highSeverity != 1 || first.topCommentType != "security" {
+		t.Errorf("unexpected summary for services/payment: %+v", first)
+	}
+	if first.avgRelevance < 0.69 || first.avgRelevance > 0.71 {
+		t.Errorf("expected average relevance 0.70, got %.2f", first.avgRelevance)
+	}
+}
+
+func TestBuildQuery_SymbolFilter(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	now := time.Now()
+	symbols := []string{"OrderService.Refund", "Refund", "OrderService.Cancel", "PartialRefund", "Order_Service.Refund", ""}
+	for i, symbol := range symbols {
+		doc := &models.Document{
+			Summary:         "Summary",
+			OriginalComment: "Comment",
+			FilePath:        "orders.go",
+			DirectoryPath:   ".",
+			Symbol:          symbol,
+			Language:        "go",
+			Repository:      "owner/repo",
+			PRNumber:        1,
+			PRTitle:         "PR",
+			PRURL:           "https://github.com/owner/repo/pull/1",
+			CommentURL:      fmt.Sprintf("https://github.com/owner/repo/pull/1#discussion_r%d", i),
+			Author:          "user",
+			CommentType:     "implementation",
+			RelevanceScore:  0.8,
+			CommentedAt:     now,
+			CollectedAt:     now,
+			UpdatedAt:       now,
+		}
+		if err := insertTestDocument(db, doc); err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+
+	tests := []struct {
+		symbol string
+		want   int
+	}{
+		{"Refund", 3},              // Refund, OrderService.Refund, Order_Service.Refund
+		{"OrderService", 2},        // OrderService.Refund, OrderService.Cancel
+		{"OrderService.Refund", 1}, // 完全一致
+		{"Order_Service", 1},       // "_" はワイルドカードとして扱わない
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			// Act
+			query, args := buildQuery(queryFilters{symbol: tt.symbol})
+			results, err := runQuery(context.Background(), db, query, args)
+			if err != nil {
+				t.Fatalf("Failed to query documents: %v", err)
+			}
+
+			// Assert
+			if len(results) != tt.want {
+				t.Errorf("Expected %d documents for symbol %q, got %d", tt.want, tt.symbol, len(results))
+			}
+		})
+	}
+}
+
+func TestBuildQuery_PathFiltersFollowRenames(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	now := time.Now()
+	docs := []*models.Document{
+		// リネーム前にコメントされたドキュメント（krenameで現在のパスが記録済み）
+		{FilePath: "pkg/payment/charge.go", DirectoryPath: "pkg/payment"},
+		// リネーム後にコメントされたドキュメント
+		{FilePath: "services/payment/charge.go", DirectoryPath: "services/payment"},
+		{FilePath: "services/search/index.go", DirectoryPath: "services/search"},
+	}
+	for i, doc := range docs {
+		doc.Summary = "Summary"
+		doc.OriginalComment = "Comment"
+		doc.Language = "go"
+		doc.Repository = "owner/repo"
+		doc.PRNumber = 1
+		doc.PRTitle = "PR"
+		doc.PRURL = "https://github.com/owner/repo/pull/1"
+		doc.CommentURL = fmt.Sprintf("https://github.com/owner/repo/pull/1#discussion_r%d", i)
+		doc.Author = "user"
+		doc.CommentType = "implementation"
+		doc.RelevanceScore = 0.8
+		doc.CommentedAt = now
+		doc.CollectedAt = now
+		doc.UpdatedAt = now
+		if err := insertTestDocument(db, doc); err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+	if _, err := db.Exec(`UPDATE documents SET current_path = 'services/payment/charge.go' WHERE file_path = 'pkg/payment/charge.go'`); err != nil {
+		t.Fatalf("Failed to set current path: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO path_aliases (repository, alias_path, current_path) VALUES ('owner/repo', 'pkg/payment/charge.go', 'services/payment/charge.go')`); err != nil {
+		t.Fatalf("Failed to insert path alias: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		filters queryFilters
+		want    int
+	}{
+		{"current directory", queryFilters{directory: "services/payment"}, 2},
+		{"historical directory", queryFilters{directory: "pkg/payment"}, 2},
+		{"current file", queryFilters{filePath: "services/payment/charge.go"}, 2},
+		{"historical file", queryFilters{filePath: "pkg/payment/charge.go"}, 2},
+		{"unrelated directory", queryFilters{directory: "services/search"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			query, args := buildQuery(tt.filters)
+			results, err := runQuery(context.Background(), db, query, args)
+			if err != nil {
+				t.Fatalf("Failed to query documents: %v", err)
+			}
+
+			// Assert
+			if len(results) != tt.want {
+				t.Errorf("Expected %d documents, got %d", tt.want, len(results))
+			}
+		})
+	}
+}
+
+func TestBuildQuery_ObsoleteDocuments(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	now := time.Now()
+	docs := []struct {
+		staleness string
+		score     float64
+	}{
+		{models.StalenessObsolete, 0.9},
+		{models.StalenessCurrent, 0.5},
+		{"", 0.7},
+		{models.StalenessMoved, 0.6},
+	}
+	for i, d := range docs {
+		doc := &models.Document{
+			Summary:         "Summary",
+			OriginalComment: "Comment",
+			FilePath:        "main.go",
+			DirectoryPath:   ".",
+			Language:        "go",
+			Staleness:       d.staleness,
+			Repository:      "owner/repo",
+			PRNumber:        1,
+			PRTitle:         "PR",
+			PRURL:           "https://github.com/owner/repo/pull/1",
+			CommentURL:      fmt.Sprintf("https://github.com/owner/repo/pull/1#discussion_r%d", i),
+			Author:          "user",
+			CommentType:     "implementation",
+			RelevanceScore:  d.score,
+			CommentedAt:     now,
+			CollectedAt:     now,
+			UpdatedAt:       now,
+		}
+		if err := insertTestDocument(db, doc); err != nil {
+			t.Fatalf("Failed to insert test document: %v", err)
+		}
+	}
+
+	tests := []struct {
+		obsolete string
+		want     []string
+	}{
+		{"demote", []string{"/0.7", "moved/0.6", "current/0.5", "obsolete/0.9"}},
+		{"exclude", []string{"/0.7", "moved/0.6", "current/0.5"}},
+		{"include", []string{"obsolete/0.9", "/0.7", "moved/0.6", "current/0.5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.obsolete, func(t *testing.T) {
+			// Act
+			query, args := buildQuery(queryFilters{obsolete: tt.obsolete})
+			results, err := runQuery(context.Background(), db, query, args)
+			if err != nil {
+				t.Fatalf("Failed to query documents: %v", err)
+			}
+
+			var got []string
+			for _, result := range results {
+				got = append(got, fmt.Sprintf("%s/%.1f", result["staleness"], result["relevanceScore"]))
+			}
+
+			// Assert
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeProject(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		".":                  ".",
+		"./":                 ".",
+		"services/payment/":  "services/payment",
+		"./services/payment": "services/payment",
+		" services/payment ": "services/payment",
+	}
+
+	for input, want := range tests {
+		if got := normalizeProject(input); got != want {
+			t.Errorf("normalizeProject(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseFileRoles(t *testing.T) {
+	got, err := parseFileRoles("Source, generated,,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "source" || got[1] != "generated" {
+		t.Errorf("unexpected file roles: %v", got)
+	}
+
+	if _, err := parseFileRoles("binary"); err == nil {
+		t.Error("expected error for unknown file role")
+	}
+}
+
+func TestParseSeverities(t *testing.T) {
+	got, err := parseSeverities("Blocker, major,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(got) != "[blocker major]" {
+		t.Errorf("Expected [blocker major], got %v", got)
+	}
+
+	if _, err := parseSeverities("critical"); err == nil {
+		t.Error("Expected error for unknown severity")
+	}
+}
+
+func TestCollapseDuplicates(t *testing.T) {
+	// Arrange - results are already sorted by relevance
+	results := []map[string]interface{}{
+		{"id": int64(1), "duplicateGroup": "dg-a"},
+		{"id": int64(2), "duplicateGroup": ""},
+		{"id": int64(3), "duplicateGroup": "dg-a"},
+		{"id": int64(4), "duplicateGroup": "dg-b"},
+		{"id": int64(5), "duplicateGroup": "dg-a"},
+	}
+
+	// Act
+	collapsed := collapseDuplicates(results)
+
+	// Assert
+	wantIDs := []int64{1, 2, 4}
+	if len(collapsed) != len(wantIDs) {
+		t.Fatalf("expected %d results, got %d", len(wantIDs), len(collapsed))
+	}
+	for i, want := range wantIDs {
+		if collapsed[i]["id"] != want {
+			t.Errorf("result %d: expected id %d, got %v", i, want, collapsed[i]["id"])
+		}
+	}
+	if collapsed[0]["duplicateCount"] != 2 {
+		t.Errorf("expected 2 collapsed duplicates for dg-a, got %v", collapsed[0]["duplicateCount"])
+	}
+}
+
 // Helper function to insert test documents
 func insertTestDocument(db *sql.DB, doc *models.Document) error {
 	query := `
 	INSERT INTO documents (
-		summary, original_comment, file_path, directory_path, language,
+		summary, original_comment, file_path, directory_path, project, symbol, language, file_role, owners, staleness,
 		repository, pr_number, pr_title, pr_url, comment_url,
-		author, comment_type, relevance_score, commented_at, collected_at, updated_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		author, comment_role, comment_type, relevance_score, severity, commented_at, collected_at, updated_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := db.Exec(query,
-		doc.Summary, doc.OriginalComment, doc.FilePath, doc.DirectoryPath, doc.Language,
+		doc.Summary, doc.OriginalComment, doc.FilePath, doc.DirectoryPath, doc.Project, doc.Symbol, doc.Language, doc.FileRole, strings.Join(doc.Owners, " "), doc.Staleness,
 		doc.Repository, doc.PRNumber, doc.PRTitle, doc.PRURL, doc.CommentURL,
-		doc.Author, doc.CommentType, doc.RelevanceScore, doc.CommentedAt, doc.CollectedAt, doc.UpdatedAt)
+		doc.Author, doc.CommentRole, doc.CommentType, doc.RelevanceScore, doc.Severity, doc.CommentedAt, doc.CollectedAt, doc.UpdatedAt)
 	
 	return err
 }
\ No newline at end of file
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
+	"github.com/pankona/knowledges/pkg/config"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+func main() {
+	var (
+		configPath    = flag.String("config", "config.yaml", "Path to config file")
+		repo          = flag.String("repo", "", "Only re-analyze documents of this repository")
+		commentType   = flag.String("type", "", "Only re-analyze documents of this comment type")
+		promptVersion = flag.String("prompt-version", "", "Only re-analyze documents analyzed with this prompt version")
+		outdated      = flag.Bool("outdated", false, "Only re-analyze documents not analyzed with the current prompt template")
+		heuristic     = flag.Bool("heuristic", false, "Only re-analyze documents classified by the heuristic fallback")
+		since         = flag.String("since", "", "Only re-analyze comments made on or after this date (YYYY-MM-DD)")
+		until         = flag.String("until", "", "Only re-analyze comments made before this date (YYYY-MM-DD)")
+		limit         = flag.Int("limit", 0, "Maximum number of documents to re-analyze (0 for no limit)")
+		dryRun        = flag.Bool("dry-run", false, "Show the changes without saving them")
+		yes           = flag.Bool("yes", false, "Save the new analyses without prompting")
+		noCache       = flag.Bool("no-cache", false, "Do not use or store cached LLM analyses")
+		refreshCache  = flag.Bool("refresh-cache", false, "Ignore cached LLM analyses and overwrite them with fresh results")
+	)
+	flag.Parse()
+
+	if *commentType != "" && !models.IsValidCommentType(*commentType) {
+		log.Fatalf("Invalid -type: %q", *commentType)
+	}
+	filters := documentFilters{
+		repository:    *repo,
+		commentType:   *commentType,
+		promptVersion: *promptVersion,
+		heuristic:     *heuristic,
+		limit:         *limit,
+	}
+	var err error
+	if filters.since, err = parseDate(*since); err != nil {
+		log.Fatalf("Invalid -since: %v", err)
+	}
+	if filters.until, err = parseDate(*until); err != nil {
+		log.Fatalf("Invalid -until: %v", err)
+	}
+
+	fmt.Println("🔁 Knowledge Base Re-analysis")
+	fmt.Println("=============================")
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	prompts, err := prompt.NewSet(cfg.Prompts)
+	if err != nil {
+		log.Fatalf("Invalid prompts config: %v", err)
+	}
+	if _, err := llm.NewChainFromConfig(cfg.LLM); err != nil {
+		log.Fatalf("Invalid llm config: %v", err)
+	}
+	fmt.Printf("🤖 LLM drivers: %s\n", strings.Join(cfg.LLM.DriverChain(), " → "))
+	for _, t := range prompts.Templates() {
+		fmt.Printf("📝 Prompt template: %s (version %s)\n", t.Name(), t.Version())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	documents, err := selectDocuments(ctx, db, filters)
+	if err != nil {
+		log.Fatalf("Failed to load documents: %v", err)
+	}
+	if *outdated {
+		documents = excludeCurrentPrompt(documents, prompts)
+	}
+	if len(documents) == 0 {
+		fmt.Println("ℹ️  No documents match the filters")
+		return
+	}
+	fmt.Printf("📥 Re-analyzing %d documents with %d parallel workers, up to %d comments per LLM call\n",
+		len(documents), cfg.LLM.Parallel, cfg.Collection.BatchSize)
+
+	commentFilter := collector.NewCommentFilter()
+	commentFilter.SetAuthorReplyPolicy(cfg.Collection.AuthorReplies, *cfg.Collection.AuthorReplyWeight)
+	var cache *llm.SQLCache
+	if !cfg.LLM.Cache.Disabled && !*noCache {
+		cache = llm.NewSQLCache(db, cfg.LLM.Cache.TTL)
+		cache.SetRefresh(*refreshCache)
+		// SQLiteへの書き込みはワーカーではなく、分析が終わった後にまとめて行う
+		cache.SetDeferWrites(true)
+	}
+
+	r := &reanalyzer{
+		prompts:       prompts,
+		commentFilter: commentFilter,
+		parallel:      cfg.LLM.Parallel,
+		batchSize:     cfg.Collection.BatchSize,
+		newAnalyzer: func(usage llm.UsageHandler) (llm.BatchAnalyzer, error) {
+			chain, err := llm.NewChainFromConfig(cfg.LLM)
+			if err != nil {
+				return nil, err
+			}
+			chain.SetFallbackHandler(func(failed *llm.Driver, err error) {
+				log.Printf("⚠️  LLM driver %s failed: %v", failed.Name(), err)
+			})
+			if cache != nil {
+				chain.SetCache(cache)
+			}
+			chain.SetUsageHandler(usage)
+			return chain, nil
+		},
+		progress: func(done, total int) {
+			fmt.Printf("⏳ Re-analyzed %d/%d documents\n", done, total)
+		},
+		cache: cache,
+	}
+	results, err := r.runByRepository(ctx, db, documents)
+	if err != nil {
+		log.Fatalf("Re-analysis interrupted, only the LLM usage was saved: %v", err)
+	}
+
+	fmt.Println()
+	printDiff(os.Stdout, results)
+
+	if *dryRun {
+		fmt.Println("\nℹ️  Dry run, nothing was saved")
+		return
+	}
+	if !*yes && !confirm(os.Stdin, os.Stdout, "Save the new analyses?") {
+		fmt.Println("\nℹ️  Nothing was saved")
+		return
+	}
+
+	applied, err := applyReanalyses(context.WithoutCancel(ctx), db, results, time.Now())
+	if err != nil {
+		log.Fatalf("Failed to save analyses: %v", err)
+	}
+	fmt.Printf("\n✅ Saved new analysis versions for %d documents\n", applied)
+}
+
+// parseDate は YYYY-MM-DD の日付を解析します（空文字列はゼロ値）
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// confirm は質問に y で答えたかどうかを返します
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	fmt.Fprintf(w, "\n%s [y/N]: ", question)
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/database/dbtest"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
+	"github.com/pankona/knowledges/pkg/config"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// fakeAnalyzer はコメント本文ごとに決めた分析結果を返すテスト用のLLMです
+type fakeAnalyzer struct {
+	driver  *llm.Driver
+	results map[string]*llm.AnalysisResult
+	// usage は呼び出しごとに1件10トークンとして通知されます
+	usage llm.UsageHandler
+}
+
+func (a *fakeAnalyzer) AnalyzeBatch(ctx context.Context, instructions string, items []llm.BatchItem) []llm.BatchResult {
+	if a.usage != nil {
+		a.usage(a.driver, llm.Usage{Calls: 1, InputTokens: 10 * len(items), OutputTokens: len(items)})
+	}
+	results := make([]llm.BatchResult, len(items))
+	for i, item := range items {
+		body := item.Context[strings.Index(item.Context, "Comment:\n")+len("Comment:\n"):]
+		body = body[:strings.Index(body, "\n")]
+		results[i].ID = item.ID
+		if result, ok := a.results[body]; ok {
+			copied := *result
+			results[i].Result, results[i].Driver = &copied, a.driver
+		} else {
+			results[i].Err = errors.New("analysis failed")
+		}
+	}
+	return results
+}
+
+// testDocument は保存するテスト用のドキュメントです
+type testDocument struct {
+	repository  string
+	comment     string
+	role        string
+	commentType string
+	score       float64
+	method      string
+	version     string
+	commentedAt time.Time
+}
+
+func insertDocuments(t *testing.T, db *sql.DB, documents ...testDocument) {
+	t.Helper()
+	for i, doc := range documents {
+		_, err := db.Exec(`
+			INSERT INTO documents (summary, original_comment, file_path, directory_path, language,
+				repository, pr_number, pr_title, pr_url, comment_url, author, comment_role, comment_type, tags, relevance_score,
+				analysis_method, llm_driver, prompt_version, commented_at, updated_at)
+			VALUES (?, ?, 'main.go', '.', 'go', ?, ?, 'PR', 'url', ?, 'reviewer', ?, ?, '[old]', ?, ?, 'claude', ?, ?, ?)`,
+			"Old summary "+doc.comment, doc.comment, doc.repository, i+1, "comment-"+doc.comment, doc.role,
+			doc.commentType, doc.score, doc.method, doc.version, doc.commentedAt, doc.commentedAt)
+		if err != nil {
+			t.Fatalf("Failed to insert document: %v", err)
+		}
+	}
+}
+
+func TestSelectDocuments_Filters(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	march := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	insertDocuments(t, db,
+		testDocument{repository: "owner/api", comment: "a", commentType: "bug", score: 0.8, method: models.AnalysisMethodLLM, version: "v1", commentedAt: march},
+		testDocument{repository: "owner/api", comment: "b", commentType: "bug", score: 0.6, method: models.AnalysisMethodHeuristic, commentedAt: march},
+		testDocument{repository: "owner/api", comment: "c", commentType: "design", score: 0.7, method: models.AnalysisMethodLLM, version: "v2", commentedAt: march.AddDate(0, 1, 0)},
+		testDocument{repository: "owner/web", comment: "d", commentType: "bug", score: 0.9, method: models.AnalysisMethodLLM, version: "v1", commentedAt: march},
+	)
+
+	tests := []struct {
+		name    string
+		filters documentFilters
+		want    string
+	}{
+		{"all", documentFilters{}, "abcd"},
+		{"repository", documentFilters{repository: "owner/api"}, "abc"},
+		{"type", documentFilters{commentType: "bug"}, "abd"},
+		{"prompt version", documentFilters{promptVersion: "v1"}, "ad"},
+		{"heuristic", documentFilters{heuristic: true}, "b"},
+		{"date range", documentFilters{since: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)}, "c"},
+		{"until", documentFilters{until: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), repository: "owner/api"}, "ab"},
+		{"limit", documentFilters{limit: 2}, "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			documents, err := selectDocuments(context.Background(), db, tt.filters)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := ""
+			for _, document := range documents {
+				got += document.comment
+			}
+			if got != tt.want {
+				t.Errorf("expected documents %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReanalyze_ShowsDiffAndSavesNewVersion(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	commentedAt := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	insertDocuments(t, db,
+		testDocument{repository: "owner/api", comment: "Wrap this error.", commentType: "bug", score: 0.8, method: models.AnalysisMethodLLM, version: "v1", commentedAt: commentedAt},
+		testDocument{repository: "owner/api", comment: "nit: I would rename this.", role: models.CommentRolePRAuthor, commentType: "implementation", score: 0.6, method: models.AnalysisMethodHeuristic, commentedAt: commentedAt},
+		testDocument{repository: "owner/api", comment: "Unchanged comment.", commentType: "design", score: 0.7, method: models.AnalysisMethodLLM, version: "v1", commentedAt: commentedAt},
+		testDocument{repository: "owner/api", comment: "The LLM fails on this.", commentType: "bug", score: 0.5, method: models.AnalysisMethodLLM, version: "v1", commentedAt: commentedAt},
+	)
+	prompts, err := prompt.NewSet(config.PromptsConfig{})
+	if err != nil {
+		t.Fatalf("failed to create prompts: %v", err)
+	}
+	commentFilter := collector.NewCommentFilter()
+	commentFilter.SetAuthorReplyPolicy("downweight", 0.5)
+	analyzer := &fakeAnalyzer{
+		driver: llm.NewDriver("fake", nil),
+		results: map[string]*llm.AnalysisResult{
+			"Wrap this error.":          {Summary: "Wrap errors", Type: "maintenance", Tags: []string{"errors"}, RelevanceScore: 0.9, Severity: "major"},
+			"nit: I would rename this.": {Summary: "Rename", Type: "maintenance", RelevanceScore: 0.8, Severity: "minor"},
+			"Unchanged comment.":        {Summary: "Same", Type: "design", RelevanceScore: 0.7},
+		},
+	}
+	r := &reanalyzer{
+		prompts:       prompts,
+		commentFilter: commentFilter,
+		parallel:      2,
+		batchSize:     3,
+		newAnalyzer:   func(llm.UsageHandler) (llm.BatchAnalyzer, error) { return analyzer, nil },
+	}
+	documents, err := selectDocuments(context.Background(), db, documentFilters{})
+	if err != nil {
+		t.Fatalf("failed to select documents: %v", err)
+	}
+
+	// Act
+	results, err := r.run(context.Background(), documents, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out bytes.Buffer
+	printDiff(&out, results)
+	applied, err := applyReanalyses(context.Background(), db, results, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied != 3 {
+		t.Errorf("expected 3 documents to be saved, got %d", applied)
+	}
+	for _, want := range []string{
+		"📄 #1 owner/api#1 main.go\n   type:  bug → maintenance\n   score: 0.80 → 0.90 (+0.10)\n",
+		"📄 #2 owner/api#2 main.go\n   type:  implementation → maintenance\n   score: 0.60 → 0.40 (-0.20)\n",
+		"📊 2 of 3 re-analyzed documents changed",
+		"   bug → maintenance: 1\n   implementation → maintenance: 1\n",
+		"⚠️  1 documents failed to re-analyze and keep their current analysis",
+		"   #4: analysis failed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "#3 owner/api") {
+		t.Errorf("expected the unchanged document not to be listed, got\n%s", out.String())
+	}
+
+	// 明示的なマーカー（nit）と作成者の返信の重み付けは収集時と同じく適用する
+	var commentType, severity, method, driver, version, tags string
+	var score float64
+	err = db.QueryRow("SELECT comment_type, relevance_score, severity, analysis_method, llm_driver, prompt_version, tags FROM documents WHERE id = 2").
+		Scan(&commentType, &score, &severity, &method, &driver, &version, &tags)
+	if err != nil {
+		t.Fatalf("failed to query document: %v", err)
+	}
+	if commentType != "maintenance" || score != 0.4 || severity != models.SeverityNit || method != models.AnalysisMethodLLM ||
+		driver != "fake" || version != prompt.Default().Version() || tags != "" {
+		t.Errorf("unexpected document: %s %.2f %s %s %s %s %q", commentType, score, severity, method, driver, version, tags)
+	}
+
+	rows, err := db.Query(`
+		SELECT d.id, v.version, v.comment_type, v.tags FROM analysis_versions v
+		JOIN documents d ON d.repository = v.repository AND d.pr_number = v.pr_number AND d.comment_url = v.comment_url
+		ORDER BY d.id, v.version`)
+	if err != nil {
+		t.Fatalf("failed to query versions: %v", err)
+	}
+	defer rows.Close()
+	var versions []string
+	for rows.Next() {
+		var documentID, version int
+		var versionType, versionTags string
+		if err := rows.Scan(&documentID, &version, &versionType, &versionTags); err != nil {
+			t.Fatalf("failed to scan version: %v", err)
+		}
+		versions = append(versions, strings.TrimSpace(fmt.Sprintf("%d %d %s %s", documentID, version, versionType, versionTags)))
+	}
+	want := []string{"1 1 bug [old]", "1 2 maintenance [errors]", "2 1 implementation [old]", "2 2 maintenance", "3 1 design [old]", "3 2 design"}
+	if strings.Join(versions, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected versions %v, got %v", want, versions)
+	}
+
+	// もう一度再分析すると次のバージョンとして追加する
+	if _, err := applyReanalyses(context.Background(), db, results[:1], time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var latest int
+	if err := db.QueryRow("SELECT MAX(version) FROM analysis_versions WHERE comment_url = 'comment-Wrap this error.'").Scan(&latest); err != nil {
+		t.Fatalf("failed to query versions: %v", err)
+	}
+	if latest != 3 {
+		t.Errorf("expected version 3, got %d", latest)
+	}
+}
+
+func TestApplyReanalyses_KeepsHistoryAcrossRecollection(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	commentedAt := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	document := testDocument{repository: "owner/api", comment: "a", commentType: "bug", score: 0.8, method: models.AnalysisMethodLLM, commentedAt: commentedAt}
+	insertDocuments(t, db, document)
+	reanalyze := func(commentType string) {
+		t.Helper()
+		documents, err := selectDocuments(context.Background(), db, documentFilters{})
+		if err != nil {
+			t.Fatalf("failed to select documents: %v", err)
+		}
+		results := []*reanalysis{{document: documents[0], next: analysis{Summary: "s", CommentType: commentType, AnalysisMethod: models.AnalysisMethodLLM}}}
+		if _, err := applyReanalyses(context.Background(), db, results, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	reanalyze("design")
+
+	// Act
+	// kcollector -pr-url と同じく、PRのドキュメントを削除して収集し直す
+	if _, err := db.Exec("DELETE FROM documents WHERE repository = 'owner/api' AND pr_number = 1"); err != nil {
+		t.Fatalf("failed to delete documents: %v", err)
+	}
+	insertDocuments(t, db, document)
+	reanalyze("testing")
+
+	// Assert
+	rows, err := db.Query("SELECT version, comment_type FROM analysis_versions WHERE comment_url = 'comment-a' ORDER BY version")
+	if err != nil {
+		t.Fatalf("failed to query versions: %v", err)
+	}
+	defer rows.Close()
+	var versions []string
+	for rows.Next() {
+		var version int
+		var commentType string
+		if err := rows.Scan(&version, &commentType); err != nil {
+			t.Fatalf("failed to scan version: %v", err)
+		}
+		versions = append(versions, fmt.Sprintf("%d %s", version, commentType))
+	}
+	want := []string{"1 bug", "2 design", "3 testing"}
+	if strings.Join(versions, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected the history to survive re-collection as %v, got %v", want, versions)
+	}
+}
+
+func TestReanalyze_SavesUsagePerRepository(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	commentedAt := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	insertDocuments(t, db,
+		testDocument{repository: "owner/api", comment: "a", commentType: "bug", score: 0.8, method: models.AnalysisMethodLLM, commentedAt: commentedAt},
+		testDocument{repository: "owner/web", comment: "b", commentType: "bug", score: 0.8, method: models.AnalysisMethodLLM, commentedAt: commentedAt},
+		testDocument{repository: "owner/api", comment: "c", commentType: "bug", score: 0.8, method: models.AnalysisMethodLLM, commentedAt: commentedAt},
+	)
+	prompts, err := prompt.NewSet(config.PromptsConfig{})
+	if err != nil {
+		t.Fatalf("failed to create prompts: %v", err)
+	}
+	driver := llm.NewDriver("fake", nil)
+	analysis := &llm.AnalysisResult{Summary: "Same", Type: "bug", RelevanceScore: 0.8}
+	r := &reanalyzer{
+		prompts:       prompts,
+		commentFilter: collector.NewCommentFilter(),
+		parallel:      1,
+		batchSize:     5,
+		newAnalyzer: func(usage llm.UsageHandler) (llm.BatchAnalyzer, error) {
+			results := map[string]*llm.AnalysisResult{"a": analysis, "b": analysis, "c": analysis}
+			return &fakeAnalyzer{driver: driver, results: results, usage: usage}, nil
+		},
+	}
+	documents, err := selectDocuments(context.Background(), db, documentFilters{})
+	if err != nil {
+		t.Fatalf("failed to select documents: %v", err)
+	}
+
+	// Act
+	results, err := r.runByRepository(context.Background(), db, documents)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	rows, err := db.Query(`
+		SELECT r.repository, r.documents, u.driver, u.calls, u.input_tokens
+		FROM collection_runs r JOIN llm_usage u ON u.run_id = r.id ORDER BY r.id`)
+	if err != nil {
+		t.Fatalf("failed to query runs: %v", err)
+	}
+	defer rows.Close()
+	var runs []string
+	for rows.Next() {
+		var repository, driver string
+		var documents, calls, input int
+		if err := rows.Scan(&repository, &documents, &driver, &calls, &input); err != nil {
+			t.Fatalf("failed to scan run: %v", err)
+		}
+		runs = append(runs, fmt.Sprintf("%s %d %s %d %d", repository, documents, driver, calls, input))
+	}
+	want := []string{"owner/api 2 fake 1 20", "owner/web 1 fake 1 10"}
+	if strings.Join(runs, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected runs %v, got %v", want, runs)
+	}
+}
+
+func TestNewJob_RebuildsCollectedContext(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	insertDocuments(t, db, testDocument{repository: "owner/api", comment: "Wrap this error.", commentType: "bug", method: models.AnalysisMethodLLM})
+	if _, err := db.Exec(`UPDATE documents SET line_number = 42, symbol = 'Store.Save'`); err != nil {
+		t.Fatalf("failed to update document: %v", err)
+	}
+	prompts, err := prompt.NewSet(config.PromptsConfig{})
+	if err != nil {
+		t.Fatalf("failed to create prompts: %v", err)
+	}
+	documents, err := selectDocuments(context.Background(), db, documentFilters{})
+	if err != nil {
+		t.Fatalf("failed to select documents: %v", err)
+	}
+
+	// Act
+	job, err := (&reanalyzer{prompts: prompts}).newJob(&reanalysis{document: documents[0]})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"- PR #1: PR", "- File: main.go (line 42)", "- Symbol: Store.Save", "- Author: reviewer"} {
+		if !strings.Contains(job.context, want) {
+			t.Errorf("expected the prompt to contain %q:\n%s", want, job.context)
+		}
+	}
+}
+
+func TestExcludeCurrentPrompt(t *testing.T) {
+	// Arrange
+	prompts, err := prompt.NewSet(config.PromptsConfig{})
+	if err != nil {
+		t.Fatalf("failed to create prompts: %v", err)
+	}
+	current := &storedDocument{id: 1, current: analysis{PromptVersion: prompt.Default().Version()}}
+	outdated := &storedDocument{id: 2, current: analysis{PromptVersion: "old"}}
+	heuristic := &storedDocument{id: 3}
+
+	// Act
+	documents := excludeCurrentPrompt([]*storedDocument{current, outdated, heuristic}, prompts)
+
+	// Assert
+	if len(documents) != 2 || documents[0] != outdated || documents[1] != heuristic {
+		t.Errorf("expected only the documents analyzed with another prompt, got %+v", documents)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if got := confirm(strings.NewReader(tt.input), &out, "Save?"); got != tt.want {
+			t.Errorf("confirm(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/internal/llm"
+	"github.com/pankona/knowledges/internal/prompt"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// documentFilters は再分析するドキュメントの条件です
+type documentFilters struct {
+	repository    string
+	commentType   string
+	promptVersion string
+	// since と until はコメント日時の範囲です（ゼロ値は制限なし、until は含まない）
+	since time.Time
+	until time.Time
+	// heuristic はルールベース分類にフォールバックしたドキュメントだけを対象にするかどうかです
+	heuristic bool
+	limit     int
+}
+
+// analysis はドキュメントの分析結果です
+type analysis struct {
+	Summary        string
+	CommentType    string
+	Tags           []string
+	RelevanceScore float64
+	Severity       string
+	AnalysisMethod string
+	LLMDriver      string
+	LLMModel       string
+	PromptVersion  string
+}
+
+// storedDocument は再分析に使う保存済みのドキュメントです
+type storedDocument struct {
+	id         int64
+	repository string
+	prNumber   int
+	commentURL string
+	prTitle    string
+	filePath   string
+	lineNumber int
+	symbol     string
+	language   string
+	author     string
+	role       string
+	comment    string
+	updatedAt  time.Time
+	current    analysis
+}
+
+// reanalysis は1件のドキュメントの再分析の結果です
+type reanalysis struct {
+	document *storedDocument
+	next     analysis
+	repairs  int
+	usage    llm.Usage
+	err      error
+}
+
+// changed は分類か関連度（小数点以下2桁）が変わったかどうかを返します
+func (r *reanalysis) changed() bool {
+	return r.next.CommentType != r.document.current.CommentType || scoreChanged(r.document.current.RelevanceScore, r.next.RelevanceScore)
+}
+
+// scoreChanged は表示する精度（小数点以下2桁）で関連度が変わったかどうかを返します
+func scoreChanged(before, after float64) bool {
+	return fmt.Sprintf("%.2f", before) != fmt.Sprintf("%.2f", after)
+}
+
+// selectDocuments は条件に合うドキュメントをID順に読み込みます
+func selectDocuments(ctx context.Context, db *sql.DB, filters documentFilters) ([]*storedDocument, error) {
+	query := `
+	SELECT id, repository, pr_number, comment_url, pr_title, file_path, COALESCE(line_number, 0), symbol, language, author, comment_role, original_comment, updated_at,
+	       summary, comment_type, tags, relevance_score, severity, analysis_method, llm_driver, llm_model, prompt_version
+	FROM documents WHERE 1=1`
+
+	var args []interface{}
+	if filters.repository != "" {
+		query += " AND repository = ?"
+		args = append(args, filters.repository)
+	}
+	if filters.commentType != "" {
+		query += " AND comment_type = ?"
+		args = append(args, filters.commentType)
+	}
+	if filters.promptVersion != "" {
+		query += " AND prompt_version = ?"
+		args = append(args, filters.promptVersion)
+	}
+	if !filters.since.IsZero() {
+		query += " AND commented_at >= ?"
+		args = append(args, filters.since)
+	}
+	if !filters.until.IsZero() {
+		query += " AND commented_at < ?"
+		args = append(args, filters.until)
+	}
+	if filters.heuristic {
+		query += " AND analysis_method = ?"
+		args = append(args, models.AnalysisMethodHeuristic)
+	}
+	query += " ORDER BY id"
+	if filters.limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filters.limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var documents []*storedDocument
+	for rows.Next() {
+		document := &storedDocument{}
+		current := &document.current
+		var tags sql.NullString
+		var updatedAt sql.NullTime
+		err := rows.Scan(&document.id, &document.repository, &document.prNumber, &document.commentURL, &document.prTitle, &document.filePath, &document.lineNumber,
+			&document.symbol, &document.language, &document.author, &document.role, &document.comment, &updatedAt,
+			&current.Summary, &current.CommentType, &tags, &current.RelevanceScore, &current.Severity,
+			&current.AnalysisMethod, &current.LLMDriver, &current.LLMModel, &current.PromptVersion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		current.Tags = parseTags(tags.String)
+		document.updatedAt = updatedAt.Time
+		documents = append(documents, document)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+	return documents, nil
+}
+
+// excludeCurrentPrompt は現在のテンプレートで分析済みのドキュメントを取り除きます
+func excludeCurrentPrompt(documents []*storedDocument, prompts *prompt.Set) []*storedDocument {
+	var outdated []*storedDocument
+	for _, document := range documents {
+		if prompts.Select(document.repository, document.language).Version() != document.current.PromptVersion {
+			outdated = append(outdated, document)
+		}
+	}
+	return outdated
+}
+
+// reanalyzer は保存済みのコメントと情報からプロンプトを組み立て直し、LLMで分析し直します
+//
+// 収集時と同じく、同じ指示のコメントを batchSize 件までまとめ、parallel 個まで並行に分析します（llm.BatchRunner）。
+type reanalyzer struct {
+	prompts       *prompt.Set
+	commentFilter *collector.CommentFilter
+	parallel      int
+	batchSize     int
+	// newAnalyzer はワーカーごとのLLMを作成します（usage は呼び出しごとの使用量を受け取る）
+	newAnalyzer func(usage llm.UsageHandler) (llm.BatchAnalyzer, error)
+	// progress は各バッチの分析が終わるたびに呼ばれます
+	progress func(done, total int)
+	// cache は書き込みを保留するLLMのキャッシュです（分析が終わるたびに書き込む）
+	cache *llm.SQLCache
+}
+
+// reanalysisJob は1件のドキュメントの分析に使うプロンプトです
+type reanalysisJob struct {
+	result   *reanalysis
+	template *prompt.Template
+	context  string
+	prompt   string
+	severity string
+}
+
+// runByRepository はリポジトリごとにドキュメントを分析し直し、リポジトリごとの実行としてLLMの使用量を保存します
+//
+// kcost の集計に含めるため、使用量は結果を保存するかどうかに関わらず、中断した場合も保存します。
+func (r *reanalyzer) runByRepository(ctx context.Context, db *sql.DB, documents []*storedDocument) ([]*reanalysis, error) {
+	var repositories []string
+	groups := make(map[string][]*storedDocument)
+	for _, document := range documents {
+		if _, ok := groups[document.repository]; !ok {
+			repositories = append(repositories, document.repository)
+		}
+		groups[document.repository] = append(groups[document.repository], document)
+	}
+
+	var results []*reanalysis
+	for _, repository := range repositories {
+		recorder := llm.NewUsageRecorder()
+		startedAt := time.Now()
+		repositoryResults, err := r.run(ctx, groups[repository], recorder.Record)
+		if r.cache != nil {
+			// 失敗は SQLCache の Stats に記録される
+			_ = r.cache.Flush(context.WithoutCancel(ctx))
+		}
+
+		run := &llm.Run{
+			Repository: repository,
+			StartedAt:  startedAt,
+			FinishedAt: time.Now(),
+			Cancelled:  err != nil,
+			Usages:     recorder.Totals(),
+		}
+		for _, result := range repositoryResults {
+			if result.err == nil {
+				run.Documents++
+			}
+		}
+		if _, saveErr := llm.SaveRun(context.WithoutCancel(ctx), db, run); saveErr != nil {
+			log.Printf("⚠️  Failed to save LLM usage of %s: %v", repository, saveErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, repositoryResults...)
+	}
+	return results, nil
+}
+
+// run はドキュメントを分析し直し、ドキュメントの順に結果を返します
+//
+// 分析に失敗したドキュメントは err を設定して返します（現在の分析結果は変更しない）。
+func (r *reanalyzer) run(ctx context.Context, documents []*storedDocument, usage llm.UsageHandler) ([]*reanalysis, error) {
+	results := make([]*reanalysis, len(documents))
+	var jobs []*reanalysisJob
+	var requests []llm.BatchRequest
+	for i, document := range documents {
+		results[i] = &reanalysis{document: document}
+		job, err := r.newJob(results[i])
+		if err != nil {
+			results[i].err = err
+			continue
+		}
+		jobs = append(jobs, job)
+		requests = append(requests, llm.BatchRequest{Instructions: job.template.Instructions(), Context: job.context, Prompt: job.prompt})
+	}
+
+	runner := &llm.BatchRunner{
+		Parallel:  r.parallel,
+		BatchSize: r.batchSize,
+		NewAnalyzer: func() (llm.BatchAnalyzer, error) {
+			return r.newAnalyzer(usage)
+		},
+		Progress: r.progress,
+	}
+	analyzed, err := runner.Run(ctx, requests)
+	if analyzed == nil {
+		// LLMの作成に失敗した
+		return nil, err
+	}
+	for i, job := range jobs {
+		r.apply(job, analyzed[i])
+	}
+	return results, err
+}
+
+// newJob はドキュメントの保存済みの情報からプロンプトを組み立てます
+func (r *reanalyzer) newJob(result *reanalysis) (*reanalysisJob, error) {
+	document := result.document
+	job := &reanalysisJob{
+		result:   result,
+		template: r.prompts.Select(document.repository, document.language),
+		severity: collector.DetectSeverity(document.comment),
+	}
+
+	// 収集時と同じプロンプトになるよう、保存済みの情報を全て渡す
+	data := prompt.Comment{
+		Repository: document.repository,
+		PRNumber:   document.prNumber,
+		PRTitle:    document.prTitle,
+		FilePath:   document.filePath,
+		LineNumber: document.lineNumber,
+		Symbol:     document.symbol,
+		Language:   document.language,
+		Author:     document.author,
+		Role:       document.role,
+		Severity:   job.severity,
+		Body:       document.comment,
+	}.Data()
+	var err error
+	if job.context, err = job.template.Context(data); err != nil {
+		return nil, err
+	}
+	if job.prompt, err = job.template.Single(data); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// apply は分析の結果をジョブの再分析の結果に設定します
+func (r *reanalyzer) apply(job *reanalysisJob, batchResult llm.BatchResult) {
+	result := job.result
+	if batchResult.Err != nil {
+		result.err = batchResult.Err
+		return
+	}
+
+	analyzed := batchResult.Result
+	// 明示的なマーカーがあればLLMの判定より優先する（収集時と同じ）
+	severity := job.severity
+	if severity == "" && models.IsValidSeverity(analyzed.Severity) {
+		severity = analyzed.Severity
+	}
+	weight := r.commentFilter.RelevanceWeight(github.Comment{Role: result.document.role})
+
+	result.next = analysis{
+		Summary:        analyzed.Summary,
+		CommentType:    analyzed.Type,
+		Tags:           append([]string(nil), analyzed.Tags...),
+		RelevanceScore: analyzed.RelevanceScore * weight,
+		Severity:       severity,
+		AnalysisMethod: models.AnalysisMethodLLM,
+		LLMDriver:      batchResult.Driver.Name(),
+		LLMModel:       batchResult.Driver.Model(),
+		PromptVersion:  job.template.Version(),
+	}
+	result.repairs = analyzed.Repairs
+	result.usage = analyzed.Usage
+}
+
+// typeChange は分類の変化ごとの件数です
+type typeChange struct {
+	from, to string
+	count    int
+}
+
+// printDiff は分類と関連度が変わったドキュメントと、変化の集計を出力します
+func printDiff(w io.Writer, results []*reanalysis) {
+	var changed, failed int
+	var scoreDelta float64
+	var analyzed int
+	var usage llm.Usage
+	changes := make(map[[2]string]int)
+
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+			continue
+		}
+		analyzed++
+		usage.Add(result.usage)
+		before, after := result.document.current, result.next
+		scoreDelta += after.RelevanceScore - before.RelevanceScore
+		if !result.changed() {
+			continue
+		}
+		changed++
+
+		fmt.Fprintf(w, "📄 #%d %s#%d %s\n", result.document.id, result.document.repository, result.document.prNumber, result.document.filePath)
+		if before.CommentType != after.CommentType {
+			changes[[2]string{before.CommentType, after.CommentType}]++
+			fmt.Fprintf(w, "   type:  %s → %s\n", before.CommentType, after.CommentType)
+		}
+		if scoreChanged(before.RelevanceScore, after.RelevanceScore) {
+			fmt.Fprintf(w, "   score: %.2f → %.2f (%+.2f)\n", before.RelevanceScore, after.RelevanceScore, after.RelevanceScore-before.RelevanceScore)
+		}
+	}
+
+	fmt.Fprintf(w, "\n📊 %d of %d re-analyzed documents changed\n", changed, analyzed)
+	var sorted []typeChange
+	for change, count := range changes {
+		sorted = append(sorted, typeChange{from: change[0], to: change[1], count: count})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		if sorted[i].from != sorted[j].from {
+			return sorted[i].from < sorted[j].from
+		}
+		return sorted[i].to < sorted[j].to
+	})
+	for _, change := range sorted {
+		fmt.Fprintf(w, "   %s → %s: %d\n", change.from, change.to, change.count)
+	}
+	if analyzed > 0 {
+		fmt.Fprintf(w, "📈 Mean score change: %+.3f\n", scoreDelta/float64(analyzed))
+		tokens := fmt.Sprintf("%d input / %d output tokens", usage.InputTokens, usage.OutputTokens)
+		if usage.Estimated() {
+			tokens += " (estimated)"
+		}
+		fmt.Fprintf(w, "💰 LLM usage: %d calls, %s\n", usage.Calls, tokens)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "⚠️  %d documents failed to re-analyze and keep their current analysis\n", failed)
+		for _, result := range results {
+			if result.err != nil {
+				fmt.Fprintf(w, "   #%d: %v\n", result.document.id, result.err)
+			}
+		}
+	}
+}
+
+// applyReanalyses は再分析の結果を新しい分析バージョンとして保存し、ドキュメントを更新します
+//
+// 初めて再分析するドキュメントは、現在の分析結果をバージョン1として履歴に残します。
+// 全ての結果を1つのトランザクションで保存し、保存したドキュメント数を返します。
+func applyReanalyses(ctx context.Context, db *sql.DB, results []*reanalysis, now time.Time) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	applied := 0
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		document := result.document
+
+		var latest int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(MAX(version), 0) FROM analysis_versions
+			WHERE repository = ? AND pr_number = ? AND comment_url = ?`,
+			document.repository, document.prNumber, document.commentURL).Scan(&latest)
+		if err != nil {
+			return 0, fmt.Errorf("failed to query analysis versions: %w", err)
+		}
+		if latest == 0 {
+			latest = 1
+			analyzedAt := document.updatedAt
+			if analyzedAt.IsZero() {
+				analyzedAt = now
+			}
+			if err := insertVersion(ctx, tx, document, latest, document.current, analyzedAt); err != nil {
+				return 0, err
+			}
+		}
+		if err := insertVersion(ctx, tx, document, latest+1, result.next, now); err != nil {
+			return 0, err
+		}
+
+		next := result.next
+		_, err = tx.ExecContext(ctx, `
+			UPDATE documents SET
+				summary = ?, comment_type = ?, tags = ?, relevance_score = ?, severity = ?,
+				analysis_method = ?, llm_driver = ?, llm_model = ?, prompt_version = ?, repair_count = ?,
+				llm_input_tokens = ?, llm_output_tokens = ?, llm_tokens_estimated = ?, llm_latency_ms = ?,
+				updated_at = ?
+			WHERE id = ?`,
+			next.Summary, next.CommentType, formatTags(next.Tags), next.RelevanceScore, next.Severity,
+			next.AnalysisMethod, next.LLMDriver, next.LLMModel, next.PromptVersion, result.repairs,
+			result.usage.InputTokens, result.usage.OutputTokens, result.usage.Estimated(), result.usage.Latency.Milliseconds(),
+			now, document.id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update document #%d: %w", document.id, err)
+		}
+		applied++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit analyses: %w", err)
+	}
+	return applied, nil
+}
+
+// insertVersion は分析結果をドキュメントのコメントの履歴に保存します
+func insertVersion(ctx context.Context, tx *sql.Tx, document *storedDocument, version int, a analysis, createdAt time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO analysis_versions (repository, pr_number, comment_url, version, summary, comment_type, tags, relevance_score, severity,
+			analysis_method, llm_driver, llm_model, prompt_version, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		document.repository, document.prNumber, document.commentURL, version, a.Summary, a.CommentType, formatTags(a.Tags), a.RelevanceScore, a.Severity,
+		a.AnalysisMethod, a.LLMDriver, a.LLMModel, a.PromptVersion, createdAt)
+	if err != nil {
+		return fmt.Errorf("failed to save analysis version %d of document #%d: %w", version, document.id, err)
+	}
+	return nil
+}
+
+// formatTags はタグを kcollector の saveDocument と同じ形式にシリアライズします
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v", tags)
+}
+
+// parseTags はシリアライズしたタグ文字列を分解します
+func parseTags(tagsStr string) []string {
+	return strings.Fields(strings.Trim(tagsStr, "[]"))
+}
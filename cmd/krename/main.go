@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/internal/git"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+func main() {
+	var (
+		configPath = flag.String("config", "config.yaml", "Path to config file")
+		repo       = flag.String("repo", "", "Repository the documents belong to (overrides config)")
+		checkout   = flag.String("checkout", "", "Path to a local git checkout of the repository")
+		dryRun     = flag.Bool("dry-run", false, "Show the path changes without writing them")
+	)
+	flag.Parse()
+
+	fmt.Println("🔀 Knowledge Base Rename Tracker")
+	fmt.Println("================================")
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	targetRepo := *repo
+	if targetRepo == "" && len(cfg.GitHub.Repositories) > 0 {
+		targetRepo = cfg.GitHub.Repositories[0]
+	}
+	if targetRepo == "" || *checkout == "" {
+		fmt.Println("Usage: krename -repo owner/repo -checkout /path/to/checkout [-dry-run] [-config config.yaml]")
+		os.Exit(1)
+	}
+
+	db, err := database.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx := context.Background()
+
+	fmt.Printf("📦 Repository: %s\n", targetRepo)
+	fmt.Printf("📂 Checkout: %s\n", *checkout)
+
+	renames, err := git.NewRepository(*checkout).Renames(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read rename history: %v", err)
+	}
+	fmt.Printf("📜 Found %d renames in history\n", len(renames))
+
+	docs, err := loadDocumentPaths(ctx, db, targetRepo)
+	if err != nil {
+		log.Fatalf("Failed to load documents: %v", err)
+	}
+
+	aliases := git.PathAliases(renames)
+	updates := planPathUpdates(docs, renames)
+
+	fmt.Printf("🔗 %d path aliases, %d documents with a new current path\n", len(aliases), len(updates))
+	for i, update := range updates {
+		if i >= 10 {
+			fmt.Printf("   ... and %d more\n", len(updates)-i)
+			break
+		}
+		fmt.Printf("   %s → %s\n", update.filePath, displayPath(update))
+	}
+
+	if *dryRun {
+		fmt.Println("\nℹ️  Dry run: no changes written")
+		return
+	}
+
+	if err := saveRenames(ctx, db, targetRepo, aliases, updates); err != nil {
+		log.Fatalf("Failed to save path aliases: %v", err)
+	}
+	fmt.Println("\n✅ Path aliases saved")
+}
+
+// documentPath はドキュメントのパス情報です
+type documentPath struct {
+	id          int64
+	filePath    string
+	currentPath string
+	commentedAt time.Time
+}
+
+// pathUpdate はドキュメントの現在のパスの変更です
+type pathUpdate struct {
+	id          int64
+	filePath    string
+	currentPath string
+}
+
+// loadDocumentPaths はリポジトリのドキュメントのパスを読み込みます
+func loadDocumentPaths(ctx context.Context, db *sql.DB, repository string) ([]documentPath, error) {
+	query := `SELECT id, file_path, current_path, commented_at FROM documents WHERE repository = ? AND file_path != ''`
+	rows, err := db.QueryContext(ctx, query, repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []documentPath
+	for rows.Next() {
+		var doc documentPath
+		if err := rows.Scan(&doc.id, &doc.filePath, &doc.currentPath, &doc.commentedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+	return docs, nil
+}
+
+// planPathUpdates はコメント後のリネームをたどり、現在のパスが変わるドキュメントを返します
+//
+// リネームされていない場合の current_path は空文字列（file_path と同じ）とします。
+func planPathUpdates(docs []documentPath, renames []git.Rename) []pathUpdate {
+	var updates []pathUpdate
+	for _, doc := range docs {
+		resolved := git.ResolvePath(renames, doc.filePath, doc.commentedAt)
+		if resolved == doc.filePath {
+			resolved = ""
+		}
+		if resolved == doc.currentPath {
+			continue
+		}
+		updates = append(updates, pathUpdate{id: doc.id, filePath: doc.filePath, currentPath: resolved})
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].filePath != updates[j].filePath {
+			return updates[i].filePath < updates[j].filePath
+		}
+		return updates[i].id < updates[j].id
+	})
+	return updates
+}
+
+// saveRenames はパスエイリアスを置き換え、ドキュメントの現在のパスを更新します
+func saveRenames(ctx context.Context, db *sql.DB, repository string, aliases map[string]string, updates []pathUpdate) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM path_aliases WHERE repository = ?`, repository); err != nil {
+		return fmt.Errorf("failed to clear path aliases: %w", err)
+	}
+
+	for aliasPath, currentPath := range aliases {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO path_aliases (repository, alias_path, current_path) VALUES (?, ?, ?)`,
+			repository, aliasPath, currentPath)
+		if err != nil {
+			return fmt.Errorf("failed to insert path alias: %w", err)
+		}
+	}
+
+	for _, update := range updates {
+		if _, err := tx.ExecContext(ctx, `UPDATE documents SET current_path = ? WHERE id = ?`, update.currentPath, update.id); err != nil {
+			return fmt.Errorf("failed to update document %d: %w", update.id, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// displayPath は変更後のパスを表示用に返します
+func displayPath(update pathUpdate) string {
+	if update.currentPath == "" {
+		return update.filePath + " (restored)"
+	}
+	return update.currentPath
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database/dbtest"
+	"github.com/pankona/knowledges/internal/git"
+)
+
+func TestPlanPathUpdates(t *testing.T) {
+	// Arrange
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	renamedAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	after := time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	renames := []git.Rename{
+		{Time: renamedAt, OldPath: "pkg/payment/charge.go", NewPath: "services/payment/charge.go"},
+	}
+	docs := []documentPath{
+		{id: 1, filePath: "pkg/payment/charge.go", commentedAt: before},
+		{id: 2, filePath: "pkg/payment/charge.go", commentedAt: after},
+		{id: 3, filePath: "main.go", commentedAt: before},
+		{id: 4, filePath: "pkg/payment/charge.go", currentPath: "services/payment/charge.go", commentedAt: before},
+		{id: 5, filePath: "lib/old.go", currentPath: "lib/new.go", commentedAt: before},
+	}
+
+	// Act
+	updates := planPathUpdates(docs, renames)
+
+	// Assert
+	if len(updates) != 2 {
+		t.Fatalf("expected 2 updates, got %d: %+v", len(updates), updates)
+	}
+	if updates[0].id != 5 || updates[0].currentPath != "" {
+		t.Errorf("expected stale current path of document 5 to be cleared, got %+v", updates[0])
+	}
+	if updates[1].id != 1 || updates[1].currentPath != "services/payment/charge.go" {
+		t.Errorf("expected document 1 to follow the rename, got %+v", updates[1])
+	}
+}
+
+func TestSaveRenames(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	ctx := context.Background()
+	_, err := db.Exec(`
+		INSERT INTO documents (summary, original_comment, file_path, directory_path, language,
+			repository, pr_number, pr_title, pr_url, comment_url, author, comment_type, commented_at)
+		VALUES ('s', 'c', 'pkg/payment/charge.go', 'pkg/payment', 'go',
+			'owner/repo', 1, 'PR', 'url', 'comment-1', 'user', 'bug', ?)`, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to insert document: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO path_aliases (repository, alias_path, current_path) VALUES ('owner/repo', 'stale.go', 'gone.go')`); err != nil {
+		t.Fatalf("Failed to insert alias: %v", err)
+	}
+
+	aliases := map[string]string{"pkg/payment/charge.go": "services/payment/charge.go"}
+	docs, err := loadDocumentPaths(ctx, db, "owner/repo")
+	if err != nil {
+		t.Fatalf("Failed to load documents: %v", err)
+	}
+	updates := planPathUpdates(docs, []git.Rename{
+		{Time: time.Now().Add(time.Hour), OldPath: "pkg/payment/charge.go", NewPath: "services/payment/charge.go"},
+	})
+
+	// Act
+	err = saveRenames(ctx, db, "owner/repo", aliases, updates)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var currentPath string
+	if err := db.QueryRow(`SELECT current_path FROM documents WHERE comment_url = 'comment-1'`).Scan(&currentPath); err != nil {
+		t.Fatalf("Failed to query document: %v", err)
+	}
+	if currentPath != "services/payment/charge.go" {
+		t.Errorf("expected current path to be updated, got %q", currentPath)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM path_aliases WHERE repository = 'owner/repo'`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count aliases: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected aliases to be replaced (1 alias), got %d", count)
+	}
+}
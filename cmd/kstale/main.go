@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/database"
+	"github.com/pankona/knowledges/internal/git"
+	"github.com/pankona/knowledges/pkg/config"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+func main() {
+	var (
+		configPath = flag.String("config", "config.yaml", "Path to config file")
+		repo       = flag.String("repo", "", "Repository the documents belong to (overrides config)")
+		checkout   = flag.String("checkout", "", "Path to a local git checkout of the repository")
+		dryRun     = flag.Bool("dry-run", false, "Show the staleness of documents without writing it")
+		verbose    = flag.Bool("v", false, "List every moved and obsolete document")
+	)
+	flag.Parse()
+
+	fmt.Println("🧭 Knowledge Base Staleness Checker")
+	fmt.Println("===================================")
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	targetRepo := *repo
+	if targetRepo == "" && len(cfg.GitHub.Repositories) > 0 {
+		targetRepo = cfg.GitHub.Repositories[0]
+	}
+	if targetRepo == "" || *checkout == "" {
+		fmt.Println("Usage: kstale -repo owner/repo -checkout /path/to/checkout [-dry-run] [-v] [-config config.yaml]")
+		os.Exit(1)
+	}
+
+	db, err := database.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx := context.Background()
+
+	fmt.Printf("📦 Repository: %s\n", targetRepo)
+	fmt.Printf("📂 Checkout: %s\n", *checkout)
+
+	gitRepo := git.NewRepository(*checkout)
+	renames, err := gitRepo.Renames(ctx)
+	if err != nil {
+		log.Fatalf("Failed to read rename history: %v", err)
+	}
+
+	docs, err := loadStaleTargets(ctx, db, targetRepo)
+	if err != nil {
+		log.Fatalf("Failed to load documents: %v", err)
+	}
+	fmt.Printf("🔍 Checking %d documents\n", len(docs))
+
+	checks, err := checkDocuments(ctx, collector.NewStalenessChecker(gitRepo), docs, renames)
+	if err != nil {
+		log.Fatalf("Failed to check documents: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, check := range checks {
+		counts[check.result.Status]++
+	}
+	fmt.Printf("✅ Current: %d\n", counts[models.StalenessCurrent])
+	fmt.Printf("🚚 Moved: %d\n", counts[models.StalenessMoved])
+	fmt.Printf("🪦 Obsolete: %d\n", counts[models.StalenessObsolete])
+
+	if *verbose {
+		for _, check := range checks {
+			if check.result.Status == models.StalenessCurrent {
+				continue
+			}
+			fmt.Printf("   [%s] #%d %s: %s\n", check.result.Status, check.doc.id, check.doc.filePath, check.result.Reason)
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("\nℹ️  Dry run: no changes written")
+		return
+	}
+
+	if err := saveStaleness(ctx, db, checks); err != nil {
+		log.Fatalf("Failed to save staleness: %v", err)
+	}
+	fmt.Println("\n✅ Staleness saved")
+}
+
+// staleTarget は鮮度を確認するドキュメントです
+type staleTarget struct {
+	id          int64
+	filePath    string
+	currentPath string
+	symbol      string
+	commentedAt time.Time
+}
+
+// stalenessCheck はドキュメントの鮮度判定結果です
+type stalenessCheck struct {
+	doc    staleTarget
+	result collector.StalenessResult
+}
+
+// loadStaleTargets はファイルを参照しているドキュメントを読み込みます
+func loadStaleTargets(ctx context.Context, db *sql.DB, repository string) ([]staleTarget, error) {
+	query := `SELECT id, file_path, current_path, symbol, commented_at FROM documents
+	WHERE repository = ? AND file_path != '' ORDER BY id`
+	rows, err := db.QueryContext(ctx, query, repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []staleTarget
+	for rows.Next() {
+		var doc staleTarget
+		if err := rows.Scan(&doc.id, &doc.filePath, &doc.currentPath, &doc.symbol, &doc.commentedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+	return docs, nil
+}
+
+// checkDocuments はコメント後のリネームを反映したパスで各ドキュメントの鮮度を判定します
+func checkDocuments(ctx context.Context, checker *collector.StalenessChecker, docs []staleTarget, renames []git.Rename) ([]stalenessCheck, error) {
+	checks := make([]stalenessCheck, 0, len(docs))
+	for _, doc := range docs {
+		path := git.ResolvePath(renames, doc.filePath, doc.commentedAt)
+		result, err := checker.Check(ctx, doc.filePath, path, doc.symbol)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", doc.id, err)
+		}
+		checks = append(checks, stalenessCheck{doc: doc, result: result})
+	}
+	return checks, nil
+}
+
+// saveStaleness は鮮度と移動先のパスを保存します
+//
+// 移動したドキュメントは移動先を current_path に記録します。obsolete のドキュメントは
+// 最後に分かっている current_path をそのまま残します。
+func saveStaleness(ctx context.Context, db *sql.DB, checks []stalenessCheck) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, check := range checks {
+		currentPath := check.doc.currentPath
+		switch check.result.Status {
+		case models.StalenessCurrent:
+			currentPath = ""
+		case models.StalenessMoved:
+			currentPath = check.result.Path
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`UPDATE documents SET staleness = ?, staleness_checked_at = CURRENT_TIMESTAMP, current_path = ? WHERE id = ?`,
+			check.result.Status, currentPath, check.doc.id)
+		if err != nil {
+			return fmt.Errorf("failed to update document %d: %w", check.doc.id, err)
+		}
+	}
+
+	return tx.Commit()
+}
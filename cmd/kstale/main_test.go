@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/database/dbtest"
+	"github.com/pankona/knowledges/internal/git"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// fakeTree はメモリ上の作業ツリーです
+type fakeTree map[string]string
+
+func (t fakeTree) ReadFile(path string) ([]byte, error) {
+	content, ok := t[path]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (t fakeTree) GrepFiles(ctx context.Context, word string) ([]string, error) {
+	return nil, nil
+}
+
+func TestCheckAndSaveStaleness(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	ctx := context.Background()
+	commentedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	documents := []struct {
+		commentURL  string
+		filePath    string
+		currentPath string
+		symbol      string
+	}{
+		{"comment-1", "main.go", "", "main"},
+		{"comment-2", "pkg/payment/charge.go", "", ""},
+		{"comment-3", "lib/legacy.go", "lib/legacy_v2.go", ""},
+	}
+	for _, doc := range documents {
+		_, err := db.Exec(`
+			INSERT INTO documents (summary, original_comment, file_path, current_path, symbol, directory_path, language,
+				repository, pr_number, pr_title, pr_url, comment_url, author, comment_type, commented_at)
+			VALUES ('s', 'c', ?, ?, ?, '', 'go', 'owner/repo', 1, 'PR', 'url', ?, 'user', 'bug', ?)`,
+			doc.filePath, doc.currentPath, doc.symbol, doc.commentURL, commentedAt)
+		if err != nil {
+			t.Fatalf("Failed to insert document: %v", err)
+		}
+	}
+
+	tree := fakeTree{
+		"main.go":                    "package main\n\nfunc main() {}\n",
+		"services/payment/charge.go": "package payment\n",
+	}
+	renames := []git.Rename{
+		{Time: commentedAt.Add(time.Hour), OldPath: "pkg/payment/charge.go", NewPath: "services/payment/charge.go"},
+	}
+
+	docs, err := loadStaleTargets(ctx, db, "owner/repo")
+	if err != nil {
+		t.Fatalf("Failed to load documents: %v", err)
+	}
+
+	// Act
+	checks, err := checkDocuments(ctx, collector.NewStalenessChecker(tree), docs, renames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = saveStaleness(ctx, db, checks)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]struct {
+		staleness   string
+		currentPath string
+	}{
+		"comment-1": {models.StalenessCurrent, ""},
+		"comment-2": {models.StalenessMoved, "services/payment/charge.go"},
+		"comment-3": {models.StalenessObsolete, "lib/legacy_v2.go"},
+	}
+	for commentURL, expected := range want {
+		var staleness, currentPath string
+		var checkedAt *time.Time
+		err := db.QueryRow(`SELECT staleness, current_path, staleness_checked_at FROM documents WHERE comment_url = ?`, commentURL).
+			Scan(&staleness, &currentPath, &checkedAt)
+		if err != nil {
+			t.Fatalf("Failed to query document: %v", err)
+		}
+		if staleness != expected.staleness || currentPath != expected.currentPath {
+			t.Errorf("%s: got staleness %q current path %q, want %q %q", commentURL, staleness, currentPath, expected.staleness, expected.currentPath)
+		}
+		if checkedAt == nil {
+			t.Errorf("%s: expected staleness_checked_at to be set", commentURL)
+		}
+	}
+}
@@ -3,6 +3,6 @@ module github.com/pankona/knowledges
 go 1.24.4
 
 require (
-	github.com/mattn/go-sqlite3 v1.14.30 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/mattn/go-sqlite3 v1.14.30
+	gopkg.in/yaml.v3 v3.0.1
 )
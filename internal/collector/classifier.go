@@ -0,0 +1,177 @@
+package collector
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// Classification はルールベース分類の結果を表現します
+type Classification struct {
+	Summary    string
+	Type       string
+	Tags       []string
+	Severity   string
+	Confidence float64
+}
+
+// classificationRule はコメント種別ごとのキーワードルールです
+type classificationRule struct {
+	commentType models.CommentType
+	tag         string
+	pattern     *regexp.Regexp
+}
+
+const (
+	maxHeuristicTags       = 5
+	maxHeuristicSummaryLen = 200
+
+	// ヒューリスティック分類はLLM分析より常に低い信頼度に抑える
+	baseConfidence    = 0.3
+	confidencePerHit  = 0.1
+	maxConfidence     = 0.6
+	defaultConfidence = 0.2
+)
+
+var (
+	nitPattern      = regexp.MustCompile(`(?i)^\s*(nit|nitpick|minor)\s*[:：]`)
+	questionPattern = regexp.MustCompile(`(?i)(^\s*(q|question)\s*[:：])|(\?\s*$)|(^\s*(why|what|how|could you explain|can you explain|is there)\b)`)
+)
+
+// HeuristicClassifier はLLMを使わずにキーワードとファイル種別でコメントを分類します
+type HeuristicClassifier struct {
+	rules    []classificationRule
+	fileInfo *FileInfoExtractor
+}
+
+// NewHeuristicClassifier は新しいHeuristicClassifierを作成します
+func NewHeuristicClassifier() *HeuristicClassifier {
+	rule := func(t models.CommentType, tag, expr string) classificationRule {
+		return classificationRule{commentType: t, tag: tag, pattern: regexp.MustCompile(`(?i)\b(` + expr + `)`)}
+	}
+
+	return &HeuristicClassifier{
+		rules: []classificationRule{
+			rule(models.CommentTypeSecurity, "security", `sql injection|injection|xss|csrf|vulnerab|secret|credential|password|token|auth[a-z]*|sanitiz|escap(e|ing)|permission|privilege|encrypt`),
+			rule(models.CommentTypeBug, "bug", `bug|panic|nil pointer|null pointer|nil dereference|race condition|data race|deadlock|crash|off[- ]by[- ]one|incorrect|wrong|broken|leak|overflow|regression`),
+			rule(models.CommentTypeTesting, "testing", `tests?|testing|test case|coverage|assert[a-z]*|mock[a-z]*|fixture|stub|table[- ]driven|flaky`),
+			rule(models.CommentTypeImplementation, "performance", `performance|optimi[sz]|allocat[a-z]*|complexity|o\(n|cache|caching|benchmark|faster|slow|inefficient`),
+			rule(models.CommentTypeImplementation, "refactoring", `refactor[a-z]*|simplif[a-z]*|duplicat[a-z]*|extract|reuse|helper`),
+			rule(models.CommentTypeDesign, "design", `architecture|interface|abstraction|responsibilit[a-z]*|coupling|cohesion|layer[a-z]*|dependency injection|design pattern|separation of concerns`),
+			rule(models.CommentTypeMaintenance, "readability", `naming|rename|readab[a-z]*|typo|docstring|godoc|doc comment|format[a-z]*|style|lint[a-z]*|unused|dead code|magic number`),
+			rule(models.CommentTypeBusiness, "business-logic", `spec(ification)?|requirement|customer|business|domain|pricing|billing|invoice|policy|compliance`),
+		},
+		fileInfo: NewFileInfoExtractor(),
+	}
+}
+
+// Classify はコメントをルールベースで分類します
+func (c *HeuristicClassifier) Classify(comment github.Comment) *Classification {
+	body := strings.TrimSpace(comment.Body)
+
+	scores := make(map[models.CommentType]int)
+	var tags []string
+
+	severity := DetectSeverity(body)
+	isNit := nitPattern.MatchString(body) || severity == models.SeverityNit
+	isQuestion := questionPattern.MatchString(body)
+
+	for _, r := range c.rules {
+		hits := len(r.pattern.FindAllString(body, -1))
+		if hits == 0 {
+			continue
+		}
+		scores[r.commentType] += hits
+		tags = appendUnique(tags, r.tag)
+	}
+
+	// ファイル種別による補正
+	if c.fileInfo.IsTestFile(comment.FilePath) {
+		scores[models.CommentTypeTesting]++
+		tags = appendUnique(tags, "test-file")
+	} else if c.fileInfo.IsConfigFile(comment.FilePath) {
+		tags = appendUnique(tags, "config-file")
+	}
+
+	// 質問形式は説明・質問として扱う
+	if isQuestion {
+		scores[models.CommentTypeExplanation] += 2
+		tags = appendUnique(tags, "question")
+	}
+
+	// nit: は保守性の軽微な指摘として扱う
+	if isNit {
+		scores[models.CommentTypeMaintenance] += 2
+		tags = appendUnique(tags, "nit")
+	}
+
+	commentType, hits := bestType(scores)
+
+	confidence := defaultConfidence
+	if hits > 0 {
+		confidence = baseConfidence + confidencePerHit*float64(hits-1)
+		if confidence > maxConfidence {
+			confidence = maxConfidence
+		}
+	}
+	if isNit && confidence > baseConfidence {
+		confidence = baseConfidence
+	}
+
+	if len(tags) > maxHeuristicTags {
+		tags = tags[:maxHeuristicTags]
+	}
+
+	return &Classification{
+		Summary:    heuristicSummary(comment),
+		Type:       string(commentType),
+		Tags:       tags,
+		Severity:   severity,
+		Confidence: confidence,
+	}
+}
+
+// bestType は最もスコアの高い種別を返します（同点の場合は分類体系の順序で決定）
+func bestType(scores map[models.CommentType]int) (models.CommentType, int) {
+	best := models.CommentTypeImplementation
+	bestScore := 0
+	for _, t := range models.CommentTypes {
+		if scores[t] > bestScore {
+			best = t
+			bestScore = scores[t]
+		}
+	}
+	return best, bestScore
+}
+
+// heuristicSummary はコメント本文の冒頭からサマリーを作成します
+func heuristicSummary(comment github.Comment) string {
+	text := strings.Join(strings.Fields(comment.Body), " ")
+	text = nitPattern.ReplaceAllString(text, "")
+	text = strings.TrimSpace(text)
+
+	// 最初の文までを使用
+	if i := strings.IndexAny(text, ".!?。"); i > 0 && i < maxHeuristicSummaryLen {
+		text = text[:i+1]
+	}
+	if runes := []rune(text); len(runes) > maxHeuristicSummaryLen {
+		text = string(runes[:maxHeuristicSummaryLen]) + "..."
+	}
+
+	if comment.FilePath == "" {
+		return text
+	}
+	return "In " + comment.FilePath + ": " + text
+}
+
+// appendUnique は重複しない場合のみ要素を追加します
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
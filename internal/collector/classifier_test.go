@@ -0,0 +1,129 @@
+package collector_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+func TestHeuristicClassifier_Classify_Type(t *testing.T) {
+	classifier := collector.NewHeuristicClassifier()
+
+	tests := []struct {
+		name    string
+		comment github.Comment
+		want    string
+	}{
+		{
+			name: "security keywords",
+			comment: github.Comment{
+				Body:     "This query concatenates user input and is open to SQL injection. Please sanitize it.",
+				FilePath: "internal/db/query.go",
+			},
+			want: "security",
+		},
+		{
+			name: "bug keywords",
+			comment: github.Comment{
+				Body:     "This will panic with a nil pointer when the config is missing.",
+				FilePath: "cmd/server/main.go",
+			},
+			want: "bug",
+		},
+		{
+			name: "test file boosts testing",
+			comment: github.Comment{
+				Body:     "Please add a case for the empty input as well.",
+				FilePath: "internal/parser/parser_test.go",
+			},
+			want: "testing",
+		},
+		{
+			name: "nit prefix",
+			comment: github.Comment{
+				Body:     "nit: the variable could be shorter",
+				FilePath: "internal/parser/parser.go",
+			},
+			want: "maintenance",
+		},
+		{
+			name: "question marker",
+			comment: github.Comment{
+				Body:     "Why do we need to keep the old behaviour here?",
+				FilePath: "internal/parser/parser.go",
+			},
+			want: "explanation",
+		},
+		{
+			name: "no keywords",
+			comment: github.Comment{
+				Body:     "Let's move this block below the loop.",
+				FilePath: "main.go",
+			},
+			want: "implementation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifier.Classify(tt.comment)
+			if got.Type != tt.want {
+				t.Errorf("Classify().Type = %q, want %q for comment: %q", got.Type, tt.want, tt.comment.Body)
+			}
+			if !models.IsValidCommentType(got.Type) {
+				t.Errorf("Classify().Type = %q is not part of the taxonomy", got.Type)
+			}
+		})
+	}
+}
+
+func TestHeuristicClassifier_Classify_TagsAndConfidence(t *testing.T) {
+	classifier := collector.NewHeuristicClassifier()
+
+	// Act
+	got := classifier.Classify(github.Comment{
+		Body:     "nit: typo in the doc comment",
+		FilePath: "internal/parser/parser_test.go",
+	})
+
+	// Assert
+	for _, want := range []string{"nit", "readability", "test-file"} {
+		found := false
+		for _, tag := range got.Tags {
+			if tag == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected tag %q in %v", want, got.Tags)
+		}
+	}
+	if len(got.Tags) > 5 {
+		t.Errorf("expected at most 5 tags, got %d", len(got.Tags))
+	}
+	if got.Confidence <= 0 || got.Confidence > 0.6 {
+		t.Errorf("expected confidence in (0, 0.6], got %.2f", got.Confidence)
+	}
+}
+
+func TestHeuristicClassifier_Classify_Summary(t *testing.T) {
+	classifier := collector.NewHeuristicClassifier()
+
+	// Act
+	got := classifier.Classify(github.Comment{
+		Body:     "nit: Prefer   early return here. It keeps the happy path unindented.",
+		FilePath: "main.go",
+	})
+
+	// Assert
+	want := "In main.go: Prefer early return here."
+	if got.Summary != want {
+		t.Errorf("expected summary %q, got %q", want, got.Summary)
+	}
+	if strings.Contains(got.Summary, "Review comment about") {
+		t.Errorf("summary should not be the generic fallback: %q", got.Summary)
+	}
+}
@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CodeOwnersLocations はCODEOWNERSファイルを探索するパスです（GitHubの探索順）
+var CodeOwnersLocations = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// codeOwnersRule はCODEOWNERSの1行分のルールです
+type codeOwnersRule struct {
+	pattern string
+	re      *regexp.Regexp
+	owners  []string
+}
+
+// CodeOwners はパース済みのCODEOWNERSです
+type CodeOwners struct {
+	rules []codeOwnersRule
+	hash  string
+}
+
+// ParseCodeOwners はCODEOWNERSファイルの内容をパースします
+//
+// 不正なパターンの行はGitHubと同様に無視し、エラーとして返しません。
+func ParseCodeOwners(content []byte) *CodeOwners {
+	sum := sha256.Sum256(content)
+	codeOwners := &CodeOwners{hash: hex.EncodeToString(sum[:])}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := stripCodeOwnersComment(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		re, err := compileCodeOwnersPattern(fields[0])
+		if err != nil {
+			continue
+		}
+
+		var owners []string
+		for _, owner := range fields[1:] {
+			owners = append(owners, normalizeOwner(owner))
+		}
+
+		codeOwners.rules = append(codeOwners.rules, codeOwnersRule{
+			pattern: fields[0],
+			re:      re,
+			owners:  owners,
+		})
+	}
+
+	return codeOwners
+}
+
+// Hash はCODEOWNERSの内容のハッシュを返します（変更検知に使用）
+func (c *CodeOwners) Hash() string {
+	return c.hash
+}
+
+// Owners はファイルパスのオーナー（チーム・ユーザー）を返します
+//
+// CODEOWNERSの仕様どおり、最後にマッチしたルールが優先されます。
+// オーナーのないルールにマッチした場合はオーナーなしになります。
+func (c *CodeOwners) Owners(filePath string) []string {
+	if c == nil || filePath == "" {
+		return nil
+	}
+
+	path := strings.TrimPrefix(strings.TrimPrefix(filePath, "./"), "/")
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].re.MatchString(path) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// compileCodeOwnersPattern はCODEOWNERSのパターンを正規表現に変換します
+//
+// gitignoreと同様に、先頭または途中に "/" を含むパターンはリポジトリルートからの相対パス、
+// それ以外は任意の階層にマッチします。ディレクトリにマッチした場合は配下の全ファイルが対象ですが、
+// "docs/*" のように末尾が "/*" のパターンは直下のファイルのみにマッチします。
+func compileCodeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") || strings.Contains(pattern, "[") {
+		return nil, fmt.Errorf("unsupported CODEOWNERS pattern %q", pattern)
+	}
+
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.Trim(pattern, "/")
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty CODEOWNERS pattern %q", pattern)
+	}
+
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(trimmed, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	b.WriteString(globToRegexp(trimmed))
+	switch {
+	case dirOnly:
+		b.WriteString("/.*")
+	case strings.HasSuffix(trimmed, "/*"):
+		// 直下のファイルのみ
+	default:
+		b.WriteString("(?:/.*)?")
+	}
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// stripCodeOwnersComment は行末のコメントを取り除きます（"\#" はエスケープとして扱う）
+func stripCodeOwnersComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+			line = line[:i]
+			break
+		}
+	}
+	return strings.ReplaceAll(line, `\#`, "#")
+}
+
+// normalizeOwner はオーナー表記を比較用に正規化します（GitHubのチーム名・ユーザー名は大文字小文字を区別しない）
+func normalizeOwner(owner string) string {
+	return strings.ToLower(strings.TrimSpace(owner))
+}
+
+// NormalizeOwnerQuery は検索用のオーナー指定を正規化します
+//
+// "org/team" や "user" のように "@" が省略された場合は補完します（メールアドレスを除く）。
+func NormalizeOwnerQuery(owner string) string {
+	owner = normalizeOwner(owner)
+	if owner == "" || strings.HasPrefix(owner, "@") || strings.Contains(owner, "@") {
+		return owner
+	}
+	return "@" + owner
+}
@@ -0,0 +1,79 @@
+package collector_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+)
+
+func TestCodeOwners_Owners(t *testing.T) {
+	content := []byte(`# Default owners
+*                       @org/core
+
+*.js                    @org/frontend   # inline comment
+/docs/                  @org/docs-team
+apps/                   @octocat
+/services/payments/     @Org/Payments alice@example.com
+/services/payments/generated/
+/scripts/*              @org/tooling
+**/logs                 @org/observability
+`)
+
+	codeOwners := collector.ParseCodeOwners(content)
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"main.go", []string{"@org/core"}},
+		{"web/app.js", []string{"@org/frontend"}},
+		{"docs/guide/setup.md", []string{"@org/docs-team"}},
+		{"sub/docs/readme.md", []string{"@org/core"}},
+		{"apps/web/index.ts", []string{"@octocat"}},
+		{"nested/apps/api/main.go", []string{"@octocat"}},
+		{"services/payments/charge.go", []string{"@org/payments", "alice@example.com"}},
+		{"services/payments/generated/api.pb.go", nil},
+		{"scripts/deploy.sh", []string{"@org/tooling"}},
+		{"scripts/ci/build.sh", []string{"@org/core"}},
+		{"build/logs/output.log", []string{"@org/observability"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := codeOwners.Owners(tt.path)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Owners(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeOwners_Hash(t *testing.T) {
+	a := collector.ParseCodeOwners([]byte("* @org/core\n"))
+	b := collector.ParseCodeOwners([]byte("* @org/core\n"))
+	c := collector.ParseCodeOwners([]byte("* @org/platform\n"))
+
+	if a.Hash() != b.Hash() {
+		t.Error("expected identical content to have the same hash")
+	}
+	if a.Hash() == c.Hash() {
+		t.Error("expected different content to have different hashes")
+	}
+}
+
+func TestNormalizeOwnerQuery(t *testing.T) {
+	tests := map[string]string{
+		"@org/payments":     "@org/payments",
+		"org/Payments":      "@org/payments",
+		"octocat":           "@octocat",
+		"alice@example.com": "alice@example.com",
+		"":                  "",
+	}
+
+	for input, want := range tests {
+		if got := collector.NormalizeOwnerQuery(input); got != want {
+			t.Errorf("NormalizeOwnerQuery(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DefaultDuplicateDistance はニアデュプリケートとみなすSimHashのハミング距離の既定値です
+	DefaultDuplicateDistance = 3
+
+	// ニアデュプリケート判定に必要な最小トークン数（短すぎるコメントは誤検出が多い）
+	minDuplicateTokens = 4
+)
+
+var (
+	urlPattern     = regexp.MustCompile(`https?://\S+`)
+	mentionPattern = regexp.MustCompile(`@[\w-]+`)
+	nonWordPattern = regexp.MustCompile(`[^\p{L}\p{N}%_]+`)
+)
+
+// NormalizeComment はニアデュプリケート判定用にコメント本文を正規化します
+func NormalizeComment(body string) string {
+	text := strings.ToLower(body)
+	text = nitPattern.ReplaceAllString(text, "")
+	text = urlPattern.ReplaceAllString(text, " ")
+	text = mentionPattern.ReplaceAllString(text, " ")
+	text = nonWordPattern.ReplaceAllString(text, " ")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// SimHash は正規化済みテキストの64bit SimHashを計算します
+//
+// 特徴量には単語のユニグラムとバイグラムを使用します。
+func SimHash(normalized string) uint64 {
+	tokens := strings.Fields(normalized)
+	if len(tokens) == 0 {
+		return 0
+	}
+
+	features := make([]string, 0, len(tokens)*2)
+	features = append(features, tokens...)
+	for i := 0; i+1 < len(tokens); i++ {
+		features = append(features, tokens[i]+" "+tokens[i+1])
+	}
+
+	var weights [64]int
+	for _, feature := range features {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			hash |= 1 << uint(bit)
+		}
+	}
+	return hash
+}
+
+// HammingDistance は2つのハッシュのハミング距離を返します
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatHash はハッシュをDB保存用の16進文字列に変換します
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParseHash はDB保存用の16進文字列をハッシュに変換します
+func ParseHash(s string) (uint64, error) {
+	var hash uint64
+	if _, err := fmt.Sscanf(s, "%x", &hash); err != nil {
+		return 0, fmt.Errorf("invalid hash %q: %w", s, err)
+	}
+	return hash, nil
+}
+
+// DuplicateEntry はインデックスに登録された既存ドキュメントです
+type DuplicateEntry struct {
+	DocumentID int64
+	// Key はドキュメントのキーです（DocumentKey、再収集時に自身と一致させないために使用）
+	Key   string
+	Hash  uint64
+	Group string
+	// Reusable はLLM分析結果を再利用できるかどうかです（ヒューリスティック分類は再利用しない）
+	Reusable bool
+}
+
+// DuplicateIndex はSimHashによるニアデュプリケート検索を行います
+type DuplicateIndex struct {
+	maxDistance int
+	entries     []DuplicateEntry
+}
+
+// NewDuplicateIndex は新しいDuplicateIndexを作成します
+func NewDuplicateIndex(maxDistance int) *DuplicateIndex {
+	return &DuplicateIndex{maxDistance: maxDistance}
+}
+
+// Add はドキュメントをインデックスに登録します
+func (idx *DuplicateIndex) Add(entry DuplicateEntry) {
+	idx.entries = append(idx.entries, entry)
+}
+
+// Len は登録済みのドキュメント数を返します
+func (idx *DuplicateIndex) Len() int {
+	return len(idx.entries)
+}
+
+// Find は最も近いニアデュプリケートを探します
+//
+// 分析結果を再利用できるエントリを優先し、同条件ではハミング距離の小さいものを返します。
+// key が空でない場合、同じキーのエントリ（同じコメントの保存済みドキュメント）は除外します。
+func (idx *DuplicateIndex) Find(normalized string, hash uint64, key string) (*DuplicateEntry, bool) {
+	if idx.maxDistance < 0 || len(strings.Fields(normalized)) < minDuplicateTokens {
+		return nil, false
+	}
+
+	var best *DuplicateEntry
+	bestDistance := idx.maxDistance + 1
+	for i := range idx.entries {
+		entry := &idx.entries[i]
+		if key != "" && entry.Key == key {
+			continue
+		}
+		distance := HammingDistance(entry.Hash, hash)
+		if distance > idx.maxDistance {
+			continue
+		}
+		if best == nil || (entry.Reusable && !best.Reusable) ||
+			(entry.Reusable == best.Reusable && distance < bestDistance) {
+			best = entry
+			bestDistance = distance
+		}
+	}
+
+	if best == nil {
+		return nil, false
+	}
+	return best, true
+}
+
+// DocumentKey はドキュメントを一意に識別するキーを作成します（documents の UNIQUE 制約と同じ組み合わせ）
+func DocumentKey(repository string, prNumber int, commentURL string) string {
+	return fmt.Sprintf("%s#%d#%s", repository, prNumber, commentURL)
+}
+
+// NewDuplicateGroup はハッシュから新しい重複グループIDを作成します
+func NewDuplicateGroup(hash uint64) string {
+	return "dg-" + FormatHash(hash)
+}
@@ -0,0 +1,93 @@
+package collector_test
+
+import (
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+)
+
+func TestNormalizeComment(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"nit: Please wrap errors with %w!", "please wrap errors with %w"},
+		{"@alice see https://example.com/doc for details.", "see for details"},
+		{"  Multiple\n\nlines\tand   spaces ", "multiple lines and spaces"},
+	}
+
+	for _, tt := range tests {
+		got := collector.NormalizeComment(tt.body)
+		if got != tt.want {
+			t.Errorf("NormalizeComment(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestSimHash_NearDuplicates(t *testing.T) {
+	a := collector.SimHash(collector.NormalizeComment("Please wrap errors with %w so callers can use errors.Is on the result."))
+	b := collector.SimHash(collector.NormalizeComment("please wrap errors with %w so that callers can use errors.Is on the result"))
+	c := collector.SimHash(collector.NormalizeComment("This query is vulnerable to SQL injection because user input is concatenated."))
+
+	if d := collector.HammingDistance(a, b); d > 10 {
+		t.Errorf("expected near-duplicates to be close, distance = %d", d)
+	}
+	if d := collector.HammingDistance(a, c); d <= 10 {
+		t.Errorf("expected different comments to be far apart, distance = %d", d)
+	}
+}
+
+func TestDuplicateIndex_Find(t *testing.T) {
+	normalized := collector.NormalizeComment("Please wrap errors with %w so callers can inspect them.")
+	hash := collector.SimHash(normalized)
+
+	index := collector.NewDuplicateIndex(collector.DefaultDuplicateDistance)
+	index.Add(collector.DuplicateEntry{DocumentID: 1, Hash: hash ^ 0x1, Group: "dg-heuristic", Reusable: false})
+	index.Add(collector.DuplicateEntry{DocumentID: 2, Hash: hash ^ 0x3, Group: "dg-llm", Reusable: true})
+	index.Add(collector.DuplicateEntry{DocumentID: 3, Hash: ^hash, Group: "dg-other", Reusable: true})
+
+	// Act
+	match, found := index.Find(normalized, hash, "")
+
+	// Assert - reusable analyses are preferred over closer heuristic ones
+	if !found {
+		t.Fatal("expected to find a near-duplicate")
+	}
+	if match.DocumentID != 2 {
+		t.Errorf("expected document 2, got %d", match.DocumentID)
+	}
+
+	// Short comments are never matched
+	if _, found := index.Find("fix this", hash, ""); found {
+		t.Error("expected short comments not to be matched")
+	}
+
+	// A negative distance disables detection
+	disabled := collector.NewDuplicateIndex(-1)
+	disabled.Add(collector.DuplicateEntry{DocumentID: 1, Hash: hash, Reusable: true})
+	if _, found := disabled.Find(normalized, hash, ""); found {
+		t.Error("expected detection to be disabled")
+	}
+
+	// The document of the same comment is never matched with itself
+	self := collector.NewDuplicateIndex(collector.DefaultDuplicateDistance)
+	self.Add(collector.DuplicateEntry{DocumentID: 1, Key: "owner/repo#1#r1", Hash: hash, Reusable: true})
+	if _, found := self.Find(normalized, hash, "owner/repo#1#r1"); found {
+		t.Error("expected the same document not to be matched")
+	}
+	if match, found := self.Find(normalized, hash, "owner/repo#2#r1"); !found || match.DocumentID != 1 {
+		t.Error("expected other comments to match the document")
+	}
+}
+
+func TestParseHash_RoundTrip(t *testing.T) {
+	hash := uint64(0xdeadbeefcafef00d)
+
+	got, err := collector.ParseHash(collector.FormatHash(hash))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != hash {
+		t.Errorf("expected %x, got %x", hash, got)
+	}
+}
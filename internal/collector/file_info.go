@@ -2,52 +2,52 @@ package collector
 
 import (
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
 // FileInfoExtractor はファイルパスから情報を抽出します
 type FileInfoExtractor struct {
-	languageMap map[string]string
-	testPatterns []string
-	configPatterns []string
+	languageMap     map[string]string
+	filenameMap     map[string]string
+	pathRules       []languagePathRule
+	disambiguations map[string][]languageDisambiguation
+	roleRules       fileRoleRules
+	testPatterns    []string
+	configPatterns  []string
+}
+
+// languagePathRule はパスの慣習による言語判定ルールです
+type languagePathRule struct {
+	pattern  *regexp.Regexp
+	language string
 }
 
 // NewFileInfoExtractor は新しいFileInfoExtractorを作成します
 func NewFileInfoExtractor() *FileInfoExtractor {
+	languageMap := make(map[string]string, len(defaultLanguageExtensions))
+	for ext, language := range defaultLanguageExtensions {
+		languageMap[ext] = language
+	}
+	filenameMap := make(map[string]string, len(defaultLanguageFilenames))
+	for name, language := range defaultLanguageFilenames {
+		filenameMap[name] = language
+	}
+	var pathRules []languagePathRule
+	for _, rule := range defaultLanguagePathRules {
+		pattern, err := compilePathPattern(rule.pattern)
+		if err != nil {
+			panic(err)
+		}
+		pathRules = append(pathRules, languagePathRule{pattern: pattern, language: rule.language})
+	}
+
 	return &FileInfoExtractor{
-		languageMap: map[string]string{
-			".go":   "go",
-			".js":   "javascript",
-			".ts":   "typescript",
-			".tsx":  "typescript",
-			".jsx":  "javascript",
-			".py":   "python",
-			".java": "java",
-			".c":    "c",
-			".h":    "c",
-			".cpp":  "cpp",
-			".hpp":  "cpp",
-			".cc":   "cpp",
-			".cxx":  "cpp",
-			".cs":   "csharp",
-			".php":  "php",
-			".rb":   "ruby",
-			".rs":   "rust",
-			".vue":  "vue",
-			".css":  "css",
-			".scss": "scss",
-			".sass": "sass",
-			".less": "less",
-			".html": "html",
-			".xml":  "xml",
-			".json": "json",
-			".yaml": "yaml",
-			".yml":  "yaml",
-			".toml": "toml",
-			".md":   "markdown",
-			".sh":   "shell",
-			".sql":  "sql",
-		},
+		languageMap:     languageMap,
+		filenameMap:     filenameMap,
+		pathRules:       pathRules,
+		disambiguations: defaultLanguageDisambiguations,
+		roleRules:       compileFileRoleRules(),
 		testPatterns: []string{
 			"_test.",
 			".test.",
@@ -76,23 +76,76 @@ func NewFileInfoExtractor() *FileInfoExtractor {
 	}
 }
 
+// AddLanguageExtensions は拡張子と言語の対応を追加・上書きします（例: ".tpl" → "gotemplate"）
+func (e *FileInfoExtractor) AddLanguageExtensions(extensions map[string]string) {
+	for ext, language := range extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		e.languageMap[ext] = language
+	}
+}
+
+// AddLanguageFilenames はファイル名と言語の対応を追加・上書きします
+func (e *FileInfoExtractor) AddLanguageFilenames(filenames map[string]string) {
+	for name, language := range filenames {
+		e.filenameMap[name] = language
+	}
+}
+
+// AddLanguagePathRule はパスパターンによる言語判定ルールを既定のルールより優先して追加します
+func (e *FileInfoExtractor) AddLanguagePathRule(pattern, language string) error {
+	re, err := compilePathPattern(pattern)
+	if err != nil {
+		return err
+	}
+	e.pathRules = append([]languagePathRule{{pattern: re, language: language}}, e.pathRules...)
+	return nil
+}
+
 // ExtractLanguage はファイルパスから言語を推定します
 func (e *FileInfoExtractor) ExtractLanguage(filePath string) string {
+	return e.DetectLanguage(filePath, nil)
+}
+
+// DetectLanguage はファイルパスと（取得できていれば）内容から言語を推定します
+//
+// 判定順序はパスの慣習、ファイル名、拡張子（内容による曖昧さの解消を含む）、shebangです。
+func (e *FileInfoExtractor) DetectLanguage(filePath string, content []byte) string {
 	if filePath == "" {
 		return "unknown"
 	}
 
-	// 特殊ケース
+	normalizedPath := strings.TrimPrefix(filepath.ToSlash(filePath), "./")
+	for _, rule := range e.pathRules {
+		if rule.pattern.MatchString(normalizedPath) {
+			return rule.language
+		}
+	}
+
 	fileName := filepath.Base(filePath)
-	switch fileName {
-	case "Dockerfile":
+	if language, ok := e.filenameMap[fileName]; ok {
+		return language
+	}
+	// Dockerfile.dev や api.Dockerfile などの派生
+	if strings.HasPrefix(fileName, "Dockerfile.") || strings.HasSuffix(fileName, ".Dockerfile") {
 		return "dockerfile"
-	case "Makefile":
-		return "makefile"
 	}
 
 	ext := strings.ToLower(filepath.Ext(filePath))
 	if language, ok := e.languageMap[ext]; ok {
+		if len(content) > 0 {
+			for _, rule := range e.disambiguations[ext] {
+				if rule.pattern.Match(content) {
+					return rule.language
+				}
+			}
+		}
+		return language
+	}
+
+	if language := detectShebang(content); language != "" {
 		return language
 	}
 
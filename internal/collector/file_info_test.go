@@ -141,4 +141,103 @@ func TestFileInfoExtractor_IsConfigFile(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestFileInfoExtractor_ExtractLanguage_Extended(t *testing.T) {
+	extractor := collector.NewFileInfoExtractor()
+
+	tests := []struct {
+		filePath string
+		want     string
+	}{
+		{"app/src/main/kotlin/Main.kt", "kotlin"},
+		{"build.gradle.kts", "kotlin"},
+		{"Sources/App/View.swift", "swift"},
+		{"infra/main.tf", "terraform"},
+		{"api/v1/service.proto", "protobuf"},
+		{"schema/query.graphql", "graphql"},
+		{"db/migrations/0001_init.up.sql", "sql"},
+		{".github/workflows/ci.yml", "github-actions"},
+		{".github/workflows/release.yaml", "github-actions"},
+		{"deploy/config.yml", "yaml"},
+		{"BUILD.bazel", "starlark"},
+		{"third_party/BUILD", "starlark"},
+		{"tools/defs.bzl", "starlark"},
+		{"go.mod", "go-module"},
+		{"Dockerfile.dev", "dockerfile"},
+		{"docker/api.Dockerfile", "dockerfile"},
+		{"Jenkinsfile", "groovy"},
+		{"Gemfile", "ruby"},
+		{"charts/api/templates/deployment.yaml", "helm"},
+		{"scripts/deploy", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			got := extractor.ExtractLanguage(tt.filePath)
+			if got != tt.want {
+				t.Errorf("ExtractLanguage(%q) = %q, want %q", tt.filePath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileInfoExtractor_DetectLanguage_WithContent(t *testing.T) {
+	extractor := collector.NewFileInfoExtractor()
+
+	tests := []struct {
+		name     string
+		filePath string
+		content  string
+		want     string
+	}{
+		{"shebang env python", "scripts/deploy", "#!/usr/bin/env python3\nimport sys\n", "python"},
+		{"shebang bash", "bin/setup", "#!/bin/bash\nset -e\n", "shell"},
+		{"shebang node", "bin/cli", "#!/usr/bin/env node\nconsole.log(1)\n", "javascript"},
+		{"unknown shebang", "bin/tool", "#!/usr/bin/env whatever\n", "unknown"},
+		{"no shebang", "bin/tool", "just some text\n", "unknown"},
+		{"extension wins over shebang", "tool.py", "#!/bin/sh\n", "python"},
+		{"c header", "include/list.h", "#ifndef LIST_H\nstruct list { int x; };\n", "c"},
+		{"cpp header with class", "include/list.h", "#pragma once\nclass List {\npublic:\n  int size();\n};\n", "cpp"},
+		{"cpp header with namespace", "include/util.h", "namespace util {\nint f();\n}\n", "cpp"},
+		{"objective-c header", "include/View.h", "#import <UIKit/UIKit.h>\n@interface View : UIView\n@end\n", "objective-c"},
+		{"matlab", "analysis/run.m", "function y = run(x)\n  y = x * 2;\nend\n", "matlab"},
+		{"objective-c implementation", "App/View.m", "#import \"View.h\"\n@implementation View\n@end\n", "objective-c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractor.DetectLanguage(tt.filePath, []byte(tt.content))
+			if got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.filePath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileInfoExtractor_LanguageOverrides(t *testing.T) {
+	extractor := collector.NewFileInfoExtractor()
+	extractor.AddLanguageExtensions(map[string]string{"tmpl": "gotemplate", ".h": "cpp"})
+	extractor.AddLanguageFilenames(map[string]string{"Earthfile": "earthly"})
+	if err := extractor.AddLanguagePathRule("deploy/**/*.yaml", "kubernetes"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		filePath string
+		want     string
+	}{
+		{"web/index.tmpl", "gotemplate"},
+		{"include/list.h", "cpp"},
+		{"Earthfile", "earthly"},
+		{"deploy/prod/api.yaml", "kubernetes"},
+		{"config/app.yaml", "yaml"},
+	}
+
+	for _, tt := range tests {
+		got := extractor.ExtractLanguage(tt.filePath)
+		if got != tt.want {
+			t.Errorf("ExtractLanguage(%q) = %q, want %q", tt.filePath, got, tt.want)
+		}
+	}
+}
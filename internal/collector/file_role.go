@@ -0,0 +1,199 @@
+package collector
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// 自動生成ファイルのパスパターン
+var generatedPathPatterns = []string{
+	"*.pb.go",
+	"*.pb.gw.go",
+	"*_pb2.py",
+	"*_pb2_grpc.py",
+	"*.pb.cc",
+	"*.pb.h",
+	"*_pb.js",
+	"*_pb.d.ts",
+	"*_gen.go",
+	"*_generated.go",
+	"zz_generated*.go",
+	"*.generated.*",
+	"*.g.dart",
+	"*.freezed.dart",
+	"*.min.js",
+	"*.min.css",
+	"*.map",
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"poetry.lock",
+	"composer.lock",
+	"go.sum",
+}
+
+// 自動生成ファイルのヘッダー（Goの規約 "// Code generated ... DO NOT EDIT." など）
+var generatedContentPattern = regexp.MustCompile(`(?m)^\s*(//|#|/\*|\*|--)\s*(Code generated .* DO NOT EDIT\.?|@generated\b|This file was automatically generated|AUTO-GENERATED FILE|Autogenerated by|DO NOT EDIT)`)
+
+// ベンダリングされたコードのディレクトリ
+var vendoredDirs = []string{
+	"vendor",
+	"third_party",
+	"third-party",
+	"thirdparty",
+	"node_modules",
+	"bower_components",
+	"Godeps",
+	"Pods",
+}
+
+// DBマイグレーションのパスパターン
+var migrationPathPatterns = []string{
+	"**/migrations/**",
+	"**/migration/**",
+	"**/db/migrate/**",
+	"**/alembic/versions/**",
+	"**/flyway/**/*.sql",
+	"**/liquibase/**",
+	"**/*.up.sql",
+	"**/*.down.sql",
+	"**/V*__*.sql",
+}
+
+// ドキュメントのパスパターン
+var docsPathPatterns = []string{
+	"docs/**",
+	"doc/**",
+	"**/docs/**",
+	"*.md",
+	"*.mdx",
+	"*.rst",
+	"*.adoc",
+	"README*",
+	"CHANGELOG*",
+	"CONTRIBUTING*",
+	"LICENSE*",
+}
+
+// 設定ファイルとして扱う拡張子・言語
+var configExtensions = map[string]bool{
+	".yaml": true, ".yml": true, ".toml": true, ".ini": true,
+	".cfg": true, ".conf": true, ".properties": true, ".env": true,
+}
+
+var configLanguages = map[string]bool{
+	"github-actions": true, "gitlab-ci": true, "circleci": true,
+	"dockerfile": true, "makefile": true, "starlark": true, "terraform": true,
+	"hcl": true, "helm": true, "go-module": true,
+}
+
+// fileRoleRules はコンパイル済みのパスパターンです
+type fileRoleRules struct {
+	generated []*regexp.Regexp
+	migration []*regexp.Regexp
+	docs      []*regexp.Regexp
+}
+
+func compileFileRoleRules() fileRoleRules {
+	compile := func(patterns []string) []*regexp.Regexp {
+		var compiled []*regexp.Regexp
+		for _, pattern := range patterns {
+			re, err := compilePathPattern(pattern)
+			if err != nil {
+				panic(err)
+			}
+			compiled = append(compiled, re)
+		}
+		return compiled
+	}
+
+	return fileRoleRules{
+		generated: compile(generatedPathPatterns),
+		migration: compile(migrationPathPatterns),
+		docs:      compile(docsPathPatterns),
+	}
+}
+
+// ClassifyFileRole はファイルの役割を判定します
+//
+// 優先順位は vendored, generated, migration, test, docs, config, source です。
+// 内容が取得できている場合は自動生成ヘッダーも判定に使用します。
+func (e *FileInfoExtractor) ClassifyFileRole(filePath string, content []byte) string {
+	if filePath == "" {
+		return models.FileRoleSource
+	}
+
+	normalizedPath := strings.TrimPrefix(filepath.ToSlash(filePath), "./")
+
+	if isVendored(normalizedPath) {
+		return models.FileRoleVendored
+	}
+	if matchAny(e.roleRules.generated, normalizedPath) || isGeneratedContent(content) {
+		return models.FileRoleGenerated
+	}
+	if matchAny(e.roleRules.migration, normalizedPath) {
+		return models.FileRoleMigration
+	}
+	if e.IsTestFile(normalizedPath) {
+		return models.FileRoleTest
+	}
+	if matchAny(e.roleRules.docs, normalizedPath) {
+		return models.FileRoleDocs
+	}
+	if e.IsConfigFile(normalizedPath) ||
+		configExtensions[strings.ToLower(filepath.Ext(normalizedPath))] ||
+		configLanguages[e.DetectLanguage(normalizedPath, content)] {
+		return models.FileRoleConfig
+	}
+
+	return models.FileRoleSource
+}
+
+// isVendored はパスにベンダリング用ディレクトリが含まれるか判定します
+func isVendored(path string) bool {
+	segments := strings.Split(path, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if IsVendoredDir(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsVendoredDir はベンダリング用のディレクトリ名（vendor, node_modules など）か判定します
+func IsVendoredDir(name string) bool {
+	for _, dir := range vendoredDirs {
+		if name == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeneratedContent はファイル先頭付近に自動生成ヘッダーがあるか判定します
+func isGeneratedContent(content []byte) bool {
+	if len(content) == 0 {
+		return false
+	}
+	// ヘッダーは先頭付近にあるため、先頭の一部のみ検査する
+	const headerSize = 2048
+	if len(content) > headerSize {
+		content = content[:headerSize]
+	}
+	return generatedContentPattern.Match(content)
+}
+
+// matchAny はいずれかのパターンにマッチするか判定します
+func matchAny(patterns []*regexp.Regexp, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package collector_test
+
+import (
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+func TestFileInfoExtractor_ClassifyFileRole(t *testing.T) {
+	extractor := collector.NewFileInfoExtractor()
+
+	tests := []struct {
+		name     string
+		filePath string
+		content  string
+		want     string
+	}{
+		{"go source", "internal/parser/parser.go", "", models.FileRoleSource},
+		{"go test", "internal/parser/parser_test.go", "", models.FileRoleTest},
+		{"jest spec", "src/components/Button.spec.tsx", "", models.FileRoleTest},
+		{"vendored go", "vendor/github.com/foo/bar/bar.go", "", models.FileRoleVendored},
+		{"vendored test stays vendored", "third_party/lib/lib_test.go", "", models.FileRoleVendored},
+		{"node_modules", "web/node_modules/react/index.js", "", models.FileRoleVendored},
+		{"protobuf output", "api/v1/service.pb.go", "", models.FileRoleGenerated},
+		{"python protobuf output", "proto/service_pb2.py", "", models.FileRoleGenerated},
+		{"kubernetes deepcopy", "api/v1/zz_generated.deepcopy.go", "", models.FileRoleGenerated},
+		{"lock file", "package-lock.json", "", models.FileRoleGenerated},
+		{"generated header", "internal/mock/store.go", "// Code generated by MockGen. DO NOT EDIT.\npackage mock\n", models.FileRoleGenerated},
+		{"generated marker", "src/schema.ts", "/**\n * @generated\n */\nexport type A = string;\n", models.FileRoleGenerated},
+		{"rails migration", "db/migrate/20240101000000_create_users.rb", "", models.FileRoleMigration},
+		{"sql migration", "internal/store/migrations/0001_init.up.sql", "", models.FileRoleMigration},
+		{"flyway migration", "sql/V2__add_index.sql", "", models.FileRoleMigration},
+		{"markdown", "README.md", "", models.FileRoleDocs},
+		{"docs directory", "docs/architecture/overview.png", "", models.FileRoleDocs},
+		{"yaml config", "deploy/values.yaml", "", models.FileRoleConfig},
+		{"github actions", ".github/workflows/ci.yml", "", models.FileRoleConfig},
+		{"dockerfile", "build/Dockerfile", "", models.FileRoleConfig},
+		{"go module", "go.mod", "", models.FileRoleConfig},
+		{"empty path", "", "", models.FileRoleSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content []byte
+			if tt.content != "" {
+				content = []byte(tt.content)
+			}
+
+			got := extractor.ClassifyFileRole(tt.filePath, content)
+			if got != tt.want {
+				t.Errorf("ClassifyFileRole(%q) = %q, want %q", tt.filePath, got, tt.want)
+			}
+			if !models.IsValidFileRole(got) {
+				t.Errorf("ClassifyFileRole(%q) = %q is not a valid file role", tt.filePath, got)
+			}
+		})
+	}
+}
+
+func TestFileInfoExtractor_ClassifyFileRole_HeaderOnlyNearTop(t *testing.T) {
+	extractor := collector.NewFileInfoExtractor()
+
+	// Arrange: 先頭から離れた位置にある生成マーカーは無視する
+	content := make([]byte, 0, 4096)
+	for len(content) < 3000 {
+		content = append(content, "// regular comment line\n"...)
+	}
+	content = append(content, "// Code generated by tool. DO NOT EDIT.\n"...)
+
+	// Act
+	got := extractor.ClassifyFileRole("internal/store/store.go", content)
+
+	// Assert
+	if got != models.FileRoleSource {
+		t.Errorf("expected %q, got %q", models.FileRoleSource, got)
+	}
+}
@@ -4,6 +4,14 @@ import (
 	"strings"
 
 	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// AuthorReplyPolicy はPR作成者の返信の扱いです
+const (
+	AuthorReplyKeep       = "keep"       // 通常のコメントとして扱う
+	AuthorReplyDrop       = "drop"       // フィルタで除外する
+	AuthorReplyDownweight = "downweight" // 関連度スコアを下げる
 )
 
 // CommentFilter はレビューコメントをフィルタリングします
@@ -11,6 +19,10 @@ type CommentFilter struct {
 	minLength       int
 	excludePatterns []string
 	excludeAuthors  []string
+	excludePhrases  []string
+
+	authorReplyPolicy string
+	authorReplyWeight float64
 }
 
 // NewCommentFilter は新しいCommentFilterを作成します
@@ -50,7 +62,46 @@ func NewCommentFilter() *CommentFilter {
 			"renovate[bot]",
 			"codecov[bot]",
 		},
+		authorReplyPolicy: AuthorReplyKeep,
+		authorReplyWeight: 1.0,
+	}
+}
+
+// SetAuthorReplyPolicy はPR作成者の返信の扱いを設定します
+func (f *CommentFilter) SetAuthorReplyPolicy(policy string, weight float64) {
+	f.authorReplyPolicy = policy
+	f.authorReplyWeight = weight
+}
+
+// AddExcludePhrases は正規化済み本文に含まれていれば除外するフレーズを追加します
+//
+// knoiseで学習したフレーズのように、完全一致ではなく単語境界での部分一致で判定します。
+func (f *CommentFilter) AddExcludePhrases(phrases []string) {
+	for _, phrase := range phrases {
+		normalized := NormalizeComment(phrase)
+		if normalized != "" {
+			f.excludePhrases = append(f.excludePhrases, normalized)
+		}
+	}
+}
+
+// MatchesExcludePhrase は本文が除外フレーズを含むかチェックします
+func (f *CommentFilter) MatchesExcludePhrase(body string) bool {
+	normalized := " " + NormalizeComment(body) + " "
+	for _, phrase := range f.excludePhrases {
+		if strings.Contains(normalized, " "+phrase+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// RelevanceWeight はコメントの役割に応じた関連度スコアの重みを返します
+func (f *CommentFilter) RelevanceWeight(comment github.Comment) float64 {
+	if comment.Role == models.CommentRolePRAuthor && f.authorReplyPolicy == AuthorReplyDownweight {
+		return f.authorReplyWeight
 	}
+	return 1.0
 }
 
 // IsUseful はコメントが有用かどうかを判定します
@@ -62,6 +113,11 @@ func (f *CommentFilter) IsUseful(comment github.Comment) bool {
 		}
 	}
 
+	// PR作成者の返信を除外
+	if comment.Role == models.CommentRolePRAuthor && f.authorReplyPolicy == AuthorReplyDrop {
+		return false
+	}
+
 	// 最小文字数チェック
 	if !f.HasMinimumLength(comment.Body) {
 		return false
@@ -79,6 +135,11 @@ func (f *CommentFilter) IsUseful(comment github.Comment) bool {
 		}
 	}
 
+	// 学習済みフレーズチェック
+	if f.MatchesExcludePhrase(comment.Body) {
+		return false
+	}
+
 	return true
 }
 
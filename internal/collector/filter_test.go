@@ -225,4 +225,47 @@ func TestCommentFilter_HasMinimumLength(t *testing.T) {
 			t.Errorf("HasMinimumLength(%q) = %v, want %v", tt.body, got, tt.want)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestCommentFilter_AuthorReplyPolicy(t *testing.T) {
+	authorReply := github.Comment{
+		Body:   "I did it this way because the upstream API does not support batching.",
+		Author: github.Author{Login: "pr-author"},
+		Role:   "pr_author",
+	}
+	reviewerComment := github.Comment{
+		Body:   "Consider batching these requests to reduce latency.",
+		Author: github.Author{Login: "reviewer1"},
+		Role:   "reviewer",
+	}
+
+	tests := []struct {
+		policy           string
+		wantUseful       bool
+		wantAuthorWeight float64
+	}{
+		{collector.AuthorReplyKeep, true, 1.0},
+		{collector.AuthorReplyDrop, false, 1.0},
+		{collector.AuthorReplyDownweight, true, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			filter := collector.NewCommentFilter()
+			filter.SetAuthorReplyPolicy(tt.policy, 0.5)
+
+			if got := filter.IsUseful(authorReply); got != tt.wantUseful {
+				t.Errorf("IsUseful(author reply) = %v, want %v", got, tt.wantUseful)
+			}
+			if got := filter.RelevanceWeight(authorReply); got != tt.wantAuthorWeight {
+				t.Errorf("RelevanceWeight(author reply) = %v, want %v", got, tt.wantAuthorWeight)
+			}
+			if !filter.IsUseful(reviewerComment) {
+				t.Errorf("reviewer comment should always be useful")
+			}
+			if got := filter.RelevanceWeight(reviewerComment); got != 1.0 {
+				t.Errorf("RelevanceWeight(reviewer comment) = %v, want 1.0", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package collector
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultLanguageExtensions は拡張子と言語の対応表です
+var defaultLanguageExtensions = map[string]string{
+	// Go / C系
+	".go":  "go",
+	".c":   "c",
+	".h":   "c",
+	".cpp": "cpp",
+	".hpp": "cpp",
+	".cc":  "cpp",
+	".cxx": "cpp",
+	".hh":  "cpp",
+	".hxx": "cpp",
+	".cu":  "cuda",
+	".m":   "objective-c",
+	".mm":  "objective-cpp",
+	".cs":  "csharp",
+	".fs":  "fsharp",
+	".vb":  "vbnet",
+	".zig": "zig",
+	".rs":  "rust",
+
+	// JVM
+	".java":   "java",
+	".kt":     "kotlin",
+	".kts":    "kotlin",
+	".scala":  "scala",
+	".sc":     "scala",
+	".sbt":    "scala",
+	".groovy": "groovy",
+	".gradle": "groovy",
+	".clj":    "clojure",
+	".cljs":   "clojure",
+
+	// Apple / モバイル
+	".swift": "swift",
+	".dart":  "dart",
+
+	// Web
+	".js":     "javascript",
+	".jsx":    "javascript",
+	".mjs":    "javascript",
+	".cjs":    "javascript",
+	".ts":     "typescript",
+	".tsx":    "typescript",
+	".mts":    "typescript",
+	".cts":    "typescript",
+	".vue":    "vue",
+	".svelte": "svelte",
+	".css":    "css",
+	".scss":   "scss",
+	".sass":   "sass",
+	".less":   "less",
+	".html":   "html",
+	".htm":    "html",
+	".erb":    "erb",
+	".haml":   "haml",
+
+	// スクリプト言語
+	".py":   "python",
+	".pyi":  "python",
+	".rb":   "ruby",
+	".rake": "ruby",
+	".php":  "php",
+	".pl":   "perl",
+	".pm":   "perl",
+	".lua":  "lua",
+	".r":    "r",
+	".jl":   "julia",
+	".ex":   "elixir",
+	".exs":  "elixir",
+	".erl":  "erlang",
+	".hs":   "haskell",
+	".ml":   "ocaml",
+	".nim":  "nim",
+	".sol":  "solidity",
+	".sh":   "shell",
+	".bash": "shell",
+	".zsh":  "shell",
+	".fish": "fish",
+	".ps1":  "powershell",
+	".psm1": "powershell",
+	".vim":  "vim",
+
+	// データ・設定
+	".json":       "json",
+	".jsonc":      "json",
+	".xml":        "xml",
+	".yaml":       "yaml",
+	".yml":        "yaml",
+	".toml":       "toml",
+	".ini":        "ini",
+	".cfg":        "ini",
+	".properties": "properties",
+	".proto":      "protobuf",
+	".graphql":    "graphql",
+	".gql":        "graphql",
+	".sql":        "sql",
+	".tf":         "terraform",
+	".tfvars":     "terraform",
+	".hcl":        "hcl",
+	".nix":        "nix",
+	".cmake":      "cmake",
+	".mk":         "makefile",
+	".bzl":        "starlark",
+	".star":       "starlark",
+	".dockerfile": "dockerfile",
+
+	// ドキュメント
+	".md":    "markdown",
+	".mdx":   "markdown",
+	".rst":   "restructuredtext",
+	".adoc":  "asciidoc",
+	".tex":   "latex",
+	".ipynb": "jupyter",
+}
+
+// defaultLanguageFilenames はファイル名と言語の対応表です
+var defaultLanguageFilenames = map[string]string{
+	"Dockerfile":      "dockerfile",
+	"Containerfile":   "dockerfile",
+	"Makefile":        "makefile",
+	"GNUmakefile":     "makefile",
+	"makefile":        "makefile",
+	"CMakeLists.txt":  "cmake",
+	"BUILD":           "starlark",
+	"BUILD.bazel":     "starlark",
+	"WORKSPACE":       "starlark",
+	"WORKSPACE.bazel": "starlark",
+	"MODULE.bazel":    "starlark",
+	"Tiltfile":        "starlark",
+	"Jenkinsfile":     "groovy",
+	"Gemfile":         "ruby",
+	"Rakefile":        "ruby",
+	"Vagrantfile":     "ruby",
+	"Podfile":         "ruby",
+	"Fastfile":        "ruby",
+	"Brewfile":        "ruby",
+	"Pipfile":         "toml",
+	"Cargo.lock":      "toml",
+	"go.mod":          "go-module",
+	"go.sum":          "go-checksums",
+	"go.work":         "go-module",
+	".bashrc":         "shell",
+	".bash_profile":   "shell",
+	".zshrc":          "shell",
+	".profile":        "shell",
+	"justfile":        "just",
+	"Justfile":        "just",
+}
+
+// defaultLanguagePathRules はパスの慣習による言語判定ルールです（拡張子より優先）
+var defaultLanguagePathRules = []struct {
+	pattern  string
+	language string
+}{
+	{".github/workflows/*.yml", "github-actions"},
+	{".github/workflows/*.yaml", "github-actions"},
+	{".github/actions/**/action.yml", "github-actions"},
+	{".github/actions/**/action.yaml", "github-actions"},
+	{".gitlab-ci.yml", "gitlab-ci"},
+	{".circleci/config.yml", "circleci"},
+	{"**/charts/*/templates/**/*.yaml", "helm"},
+	{"**/charts/*/templates/**/*.tpl", "helm"},
+}
+
+// shebangInterpreters はshebangのインタプリタ名と言語の対応表です
+var shebangInterpreters = map[string]string{
+	"sh":      "shell",
+	"bash":    "shell",
+	"zsh":     "shell",
+	"dash":    "shell",
+	"ksh":     "shell",
+	"fish":    "fish",
+	"python":  "python",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"ts-node": "typescript",
+	"bun":     "typescript",
+	"lua":     "lua",
+	"Rscript": "r",
+	"pwsh":    "powershell",
+	"awk":     "awk",
+	"tclsh":   "tcl",
+	"make":    "makefile",
+}
+
+// languageDisambiguation は同じ拡張子を持つ複数言語を内容から判別するルールです
+type languageDisambiguation struct {
+	language string
+	pattern  *regexp.Regexp
+}
+
+// defaultLanguageDisambiguations は拡張子ごとの判別ルールです（内容がない場合は拡張子表の言語になる）
+var defaultLanguageDisambiguations = map[string][]languageDisambiguation{
+	".h": {
+		{"objective-c", regexp.MustCompile(`(?m)^\s*(@interface|@protocol|@property|#import)\b`)},
+		{"cpp", regexp.MustCompile(`(?m)(\b(class|namespace|template)\b\s*[\w<]|\bstd::|^\s*(public|private|protected)\s*:|\bconstexpr\b)`)},
+	},
+	".m": {
+		{"objective-c", regexp.MustCompile(`(?m)^\s*(@interface|@implementation|@protocol|#import)\b`)},
+		{"matlab", regexp.MustCompile(`(?m)^\s*(function\b.*=|%\s|end\s*$)`)},
+	},
+	".pl": {
+		{"prolog", regexp.MustCompile(`(?m)^\s*:-|\w+\([^)]*\)\s*:-`)},
+	},
+	".ts": {
+		{"xml", regexp.MustCompile(`^\s*<\?xml|<TS\s+version=`)},
+	},
+}
+
+var shebangPattern = regexp.MustCompile(`^#!\s*(\S+)(?:\s+(\S+))?`)
+var interpreterVersionPattern = regexp.MustCompile(`[\d.]+$`)
+
+// detectShebang は内容の先頭行のshebangから言語を判定します
+func detectShebang(content []byte) string {
+	if len(content) < 2 || content[0] != '#' || content[1] != '!' {
+		return ""
+	}
+
+	firstLine := string(content)
+	if i := strings.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	matches := shebangPattern.FindStringSubmatch(strings.TrimSpace(firstLine))
+	if matches == nil {
+		return ""
+	}
+
+	interpreter := filepath.Base(matches[1])
+	// "#!/usr/bin/env python3" 形式
+	if interpreter == "env" && matches[2] != "" {
+		interpreter = matches[2]
+		if strings.HasPrefix(interpreter, "-") {
+			return ""
+		}
+	}
+	interpreter = interpreterVersionPattern.ReplaceAllString(interpreter, "")
+
+	return shebangInterpreters[interpreter]
+}
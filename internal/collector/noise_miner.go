@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+)
+
+// NoiseMinerOptions はノイズパターン抽出の設定です
+type NoiseMinerOptions struct {
+	MinNGram     int     // 抽出するn-gramの最小単語数（単語単体は誤検出が多いため既定は2）
+	MaxNGram     int     // 抽出するn-gramの最大単語数
+	MinSupport   int     // ルール候補に必要なnoiseドキュメント数
+	MinPrecision float64 // ルール候補に必要な推定精度
+	MaxRules     int     // 提案するルールの最大数
+}
+
+// DefaultNoiseMinerOptions は既定の抽出設定を返します
+func DefaultNoiseMinerOptions() NoiseMinerOptions {
+	return NoiseMinerOptions{
+		MinNGram:     2,
+		MaxNGram:     3,
+		MinSupport:   3,
+		MinPrecision: 0.9,
+		MaxRules:     20,
+	}
+}
+
+// NoiseRule は提案された除外フレーズです
+type NoiseRule struct {
+	Phrase    string
+	NoiseHits int     // フレーズを含むnoiseドキュメント数
+	OtherHits int     // フレーズを含むnoise以外のドキュメント数
+	Precision float64 // NoiseHits / (NoiseHits + OtherHits)
+	Coverage  float64 // NoiseHits / noiseドキュメント総数
+}
+
+// MineNoisePatterns はnoiseに分類されたコメントから頻出フレーズを抽出し、
+// noise以外のコメントに対する推定精度付きで除外ルール候補を返します
+func MineNoisePatterns(noiseComments, otherComments []string, opts NoiseMinerOptions) []NoiseRule {
+	if len(noiseComments) == 0 {
+		return nil
+	}
+
+	noiseCounts := countNGrams(noiseComments, opts.MinNGram, opts.MaxNGram)
+
+	// noise側で十分な出現があるn-gramのみ精度を計算する
+	candidates := make(map[string]bool)
+	for phrase, count := range noiseCounts {
+		if count >= opts.MinSupport && len(phrase) >= 3 {
+			candidates[phrase] = true
+		}
+	}
+
+	otherCounts := make(map[string]int)
+	for _, comment := range otherComments {
+		padded := " " + NormalizeComment(comment) + " "
+		for phrase := range candidates {
+			if strings.Contains(padded, " "+phrase+" ") {
+				otherCounts[phrase]++
+			}
+		}
+	}
+
+	var rules []NoiseRule
+	for phrase := range candidates {
+		noiseHits := noiseCounts[phrase]
+		otherHits := otherCounts[phrase]
+		precision := float64(noiseHits) / float64(noiseHits+otherHits)
+		if precision < opts.MinPrecision {
+			continue
+		}
+		rules = append(rules, NoiseRule{
+			Phrase:    phrase,
+			NoiseHits: noiseHits,
+			OtherHits: otherHits,
+			Precision: precision,
+			Coverage:  float64(noiseHits) / float64(len(noiseComments)),
+		})
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Precision != rules[j].Precision {
+			return rules[i].Precision > rules[j].Precision
+		}
+		if rules[i].NoiseHits != rules[j].NoiseHits {
+			return rules[i].NoiseHits > rules[j].NoiseHits
+		}
+		if len(rules[i].Phrase) != len(rules[j].Phrase) {
+			return len(rules[i].Phrase) < len(rules[j].Phrase)
+		}
+		return rules[i].Phrase < rules[j].Phrase
+	})
+
+	// 既に選ばれた短いフレーズを含む長いフレーズは冗長なので除外する
+	var selected []NoiseRule
+	for _, rule := range rules {
+		if opts.MaxRules > 0 && len(selected) >= opts.MaxRules {
+			break
+		}
+		redundant := false
+		for _, s := range selected {
+			if strings.Contains(" "+rule.Phrase+" ", " "+s.Phrase+" ") {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			selected = append(selected, rule)
+		}
+	}
+
+	return selected
+}
+
+// countNGrams は各n-gramを含むコメント数を数えます
+func countNGrams(comments []string, minN, maxN int) map[string]int {
+	if minN < 1 {
+		minN = 1
+	}
+
+	counts := make(map[string]int)
+	for _, comment := range comments {
+		tokens := strings.Fields(NormalizeComment(comment))
+		seen := make(map[string]bool)
+		for n := minN; n <= maxN; n++ {
+			for i := 0; i+n <= len(tokens); i++ {
+				phrase := strings.Join(tokens[i:i+n], " ")
+				if !seen[phrase] {
+					seen[phrase] = true
+					counts[phrase]++
+				}
+			}
+		}
+	}
+	return counts
+}
@@ -0,0 +1,87 @@
+package collector_test
+
+import (
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/github"
+)
+
+func TestMineNoisePatterns(t *testing.T) {
+	// Arrange
+	noise := []string{
+		"Thanks for the quick turnaround on this!",
+		"Thanks for the quick fix, appreciated.",
+		"Ah I see, thanks for the quick reply.",
+		"Nice catch, will keep in mind for next time.",
+		"Nice catch, will follow up later.",
+		"Nice catch, will address separately.",
+	}
+	other := []string{
+		"Thanks for the patch, but this will panic when the map is nil.",
+		"Please wrap errors with %w so callers can use errors.Is.",
+		"This loop allocates on every iteration; hoist the buffer.",
+	}
+
+	// Act
+	rules := collector.MineNoisePatterns(noise, other, collector.DefaultNoiseMinerOptions())
+
+	// Assert
+	phrases := make(map[string]collector.NoiseRule)
+	for _, rule := range rules {
+		phrases[rule.Phrase] = rule
+		if rule.Precision < 0.9 {
+			t.Errorf("rule %q has precision %.2f below threshold", rule.Phrase, rule.Precision)
+		}
+	}
+
+	if rule, ok := phrases["nice catch"]; !ok {
+		t.Errorf("expected 'nice catch' to be proposed, got %v", rules)
+	} else if rule.NoiseHits != 3 || rule.OtherHits != 0 {
+		t.Errorf("expected 3 noise hits and 0 other hits for 'nice catch', got %d/%d", rule.NoiseHits, rule.OtherHits)
+	}
+	if _, ok := phrases["the quick"]; !ok {
+		t.Errorf("expected 'the quick' to be proposed, got %v", rules)
+	}
+	if _, ok := phrases["thanks for the"]; ok {
+		t.Errorf("'thanks for the' also appears in useful comments and should not be proposed")
+	}
+
+	// Longer phrases containing an accepted shorter phrase are redundant
+	if _, ok := phrases["nice catch will"]; ok {
+		t.Errorf("expected 'nice catch will' to be dropped as redundant with 'nice catch'")
+	}
+
+	// Single words are not proposed by default
+	if _, ok := phrases["nice"]; ok {
+		t.Errorf("expected single words not to be proposed")
+	}
+}
+
+func TestMineNoisePatterns_NoNoise(t *testing.T) {
+	rules := collector.MineNoisePatterns(nil, []string{"some useful comment"}, collector.DefaultNoiseMinerOptions())
+	if len(rules) != 0 {
+		t.Errorf("expected no rules, got %v", rules)
+	}
+}
+
+func TestCommentFilter_AddExcludePhrases(t *testing.T) {
+	filter := collector.NewCommentFilter()
+	filter.AddExcludePhrases([]string{"Nice catch"})
+
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{"Nice catch, will address separately.", false},
+		{"This is nice, catching errors early is good practice here.", true},
+		{"Nice catching of the edge case, but the loop still leaks.", true},
+	}
+
+	for _, tt := range tests {
+		got := filter.IsUseful(github.Comment{Body: tt.body, Author: github.Author{Login: "reviewer1"}})
+		if got != tt.want {
+			t.Errorf("IsUseful(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compilePathPattern はglobパターンをパス全体にマッチする正規表現に変換します
+//
+// "**" はディレクトリをまたいで任意の文字列に、"*" と "?" はディレクトリ区切りを含まない
+// 文字列にマッチします。"/" を含まないパターンは任意のディレクトリのファイル名にマッチします。
+func compilePathPattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if pattern == "" {
+		return nil, fmt.Errorf("empty path pattern")
+	}
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+
+	re, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// globToRegexp はglobパターンをアンカーなしの正規表現に変換します
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
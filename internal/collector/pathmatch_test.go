@@ -0,0 +1,38 @@
+package collector
+
+import "testing"
+
+func TestCompilePathPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{".github/workflows/*.yml", ".github/workflows/ci.yml", true},
+		{".github/workflows/*.yml", ".github/workflows/nested/ci.yml", false},
+		{"**/migrations/**/*.sql", "db/migrations/2024/001.sql", true},
+		{"**/migrations/**/*.sql", "migrations/001.sql", true},
+		{"docs/**", "docs/a/b/c.md", true},
+		{"BUILD.bazel", "a/b/BUILD.bazel", true},
+		{"BUILD.bazel", "BUILD.bazel", true},
+		{"*.pb.go", "api/v1/service.pb.go", true},
+		{"src/?.go", "src/a.go", true},
+		{"src/?.go", "src/ab.go", false},
+		{"./scripts/*", "scripts/deploy", true},
+		{"a+b/*.go", "a+b/x.go", true},
+	}
+
+	for _, tt := range tests {
+		re, err := compilePathPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePathPattern(%q) returned error: %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("pattern %q match %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := compilePathPattern(""); err == nil {
+		t.Error("expected error for empty pattern")
+	}
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RootProject はどのプロジェクトにも属さないファイルのプロジェクト（リポジトリルート）です
+const RootProject = "."
+
+// DefaultProjectMarkers はプロジェクト・モジュールのルートを示すファイル名です
+var DefaultProjectMarkers = []string{
+	"go.mod",
+	"package.json",
+	"Cargo.toml",
+	"pyproject.toml",
+	"BUILD",
+	"BUILD.bazel",
+}
+
+// ProjectResolver はモノレポ内でファイルが属するプロジェクトを判定します
+//
+// 設定されたプレフィックスにマッチするディレクトリを優先し、次にマーカーファイルを含む
+// 最も深いディレクトリをプロジェクトのルートとします。
+type ProjectResolver struct {
+	prefixes []*regexp.Regexp
+	roots    map[string]bool
+}
+
+// NewProjectResolver はリポジトリのファイル一覧からProjectResolverを作成します
+//
+// prefixes は "services/*" のようなディレクトリのglobパターンで、マッチしたディレクトリを
+// マーカーファイルの有無に関わらずプロジェクトのルートとして扱います。
+func NewProjectResolver(files []string, markers []string, prefixes []string) (*ProjectResolver, error) {
+	if len(markers) == 0 {
+		markers = DefaultProjectMarkers
+	}
+	markerSet := make(map[string]bool, len(markers))
+	for _, marker := range markers {
+		markerSet[marker] = true
+	}
+
+	resolver := &ProjectResolver{roots: make(map[string]bool)}
+
+	for _, prefix := range prefixes {
+		pattern := strings.Trim(strings.TrimPrefix(prefix, "./"), "/")
+		if pattern == "" {
+			return nil, fmt.Errorf("empty project prefix")
+		}
+		re, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid project prefix %q: %w", prefix, err)
+		}
+		resolver.prefixes = append(resolver.prefixes, re)
+	}
+
+	for _, file := range files {
+		file = strings.TrimPrefix(file, "./")
+		// 依存ライブラリのマニフェストはプロジェクトとして扱わない
+		if isVendored(file) {
+			continue
+		}
+		if markerSet[path.Base(file)] {
+			resolver.roots[path.Dir(file)] = true
+		}
+	}
+
+	return resolver, nil
+}
+
+// Project はファイルが属するプロジェクトのルートディレクトリを返します
+func (r *ProjectResolver) Project(filePath string) string {
+	filePath = strings.TrimPrefix(strings.TrimPrefix(filePath, "./"), "/")
+	if filePath == "" {
+		return RootProject
+	}
+
+	// 設定されたプレフィックスを優先（最も深いものを採用）
+	for dir := path.Dir(filePath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		for _, prefix := range r.prefixes {
+			if prefix.MatchString(dir) {
+				return dir
+			}
+		}
+	}
+
+	for dir := path.Dir(filePath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if r.roots[dir] {
+			return dir
+		}
+	}
+
+	return RootProject
+}
+
+// Roots は検出したプロジェクトのルート数を返します
+func (r *ProjectResolver) Roots() int {
+	return len(r.roots)
+}
@@ -0,0 +1,73 @@
+package collector_test
+
+import (
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+)
+
+func TestProjectResolver_Project(t *testing.T) {
+	files := []string{
+		"go.mod",
+		"services/payment/go.mod",
+		"services/payment/internal/x/y/handler.go",
+		"services/payment/web/package.json",
+		"libs/rust-core/Cargo.toml",
+		"tools/py/pyproject.toml",
+		"third/BUILD.bazel",
+		"web/node_modules/left-pad/package.json",
+		"apps/legacy/main.go",
+	}
+
+	resolver, err := collector.NewProjectResolver(files, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"services/payment/internal/x/y/handler.go", "services/payment"},
+		{"services/payment/web/src/App.tsx", "services/payment/web"},
+		{"libs/rust-core/src/lib.rs", "libs/rust-core"},
+		{"tools/py/app/main.py", "tools/py"},
+		{"third/BUILD.bazel", "third"},
+		{"web/node_modules/left-pad/index.js", "."},
+		{"apps/legacy/main.go", "."},
+		{"main.go", "."},
+		{"", "."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := resolver.Project(tt.path); got != tt.want {
+				t.Errorf("Project(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectResolver_ConfiguredPrefixes(t *testing.T) {
+	// Arrange: 設定されたプレフィックスはマーカーファイルより優先される
+	files := []string{
+		"services/payment/go.mod",
+		"services/payment/cmd/server/go.mod",
+	}
+
+	resolver, err := collector.NewProjectResolver(files, []string{"go.mod"}, []string{"services/*", "apps/*/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act & Assert
+	if got := resolver.Project("services/payment/cmd/server/main.go"); got != "services/payment" {
+		t.Errorf("expected configured prefix to win, got %q", got)
+	}
+	if got := resolver.Project("apps/legacy/src/index.js"); got != "apps/legacy" {
+		t.Errorf("expected apps/legacy without marker file, got %q", got)
+	}
+	if got := resolver.Project("services/README.md"); got != "." {
+		t.Errorf("expected root project for file directly under services/, got %q", got)
+	}
+}
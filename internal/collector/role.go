@@ -0,0 +1,37 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/pankona/knowledges/internal/github"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// LabelRoles は各コメントにPR内での投稿者の役割を設定します
+//
+// PR作成者のコメントは pr_author、スレッドを起こしたことのあるユーザーは reviewer、
+// 他者のスレッドに返信しただけのユーザーは third_party になります。
+func LabelRoles(comments []github.Comment, prAuthor string) []github.Comment {
+	reviewers := make(map[string]bool)
+	for _, comment := range comments {
+		if comment.ThreadPosition == 0 && !strings.EqualFold(comment.Author.Login, prAuthor) {
+			reviewers[strings.ToLower(comment.Author.Login)] = true
+		}
+	}
+
+	labeled := make([]github.Comment, len(comments))
+	for i, comment := range comments {
+		login := strings.ToLower(comment.Author.Login)
+		switch {
+		case prAuthor != "" && strings.EqualFold(comment.Author.Login, prAuthor):
+			comment.Role = models.CommentRolePRAuthor
+		case reviewers[login]:
+			comment.Role = models.CommentRoleReviewer
+		default:
+			comment.Role = models.CommentRoleThirdParty
+		}
+		labeled[i] = comment
+	}
+
+	return labeled
+}
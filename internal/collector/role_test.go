@@ -0,0 +1,38 @@
+package collector_test
+
+import (
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/internal/github"
+)
+
+func TestLabelRoles(t *testing.T) {
+	// Arrange
+	comments := []github.Comment{
+		{Author: github.Author{Login: "reviewer1"}, Body: "Please handle the error here.", ThreadPosition: 0},
+		{Author: github.Author{Login: "pr-author"}, Body: "I did it this way because the caller already logs it.", ThreadPosition: 1},
+		{Author: github.Author{Login: "bystander"}, Body: "FWIW the caller drops it in some paths.", ThreadPosition: 2},
+		{Author: github.Author{Login: "PR-Author"}, Body: "Note for reviewers: this is a temporary shim.", ThreadPosition: 0},
+		{Author: github.Author{Login: "reviewer1"}, Body: "Agreed with the point above about logging.", ThreadPosition: 1},
+	}
+
+	// Act
+	labeled := collector.LabelRoles(comments, "pr-author")
+
+	// Assert
+	want := []string{"reviewer", "pr_author", "third_party", "pr_author", "reviewer"}
+	if len(labeled) != len(want) {
+		t.Fatalf("expected %d comments, got %d", len(want), len(labeled))
+	}
+	for i, comment := range labeled {
+		if comment.Role != want[i] {
+			t.Errorf("comment %d (%s): expected role %q, got %q", i, comment.Author.Login, want[i], comment.Role)
+		}
+	}
+
+	// The input slice should not be modified
+	if comments[0].Role != "" {
+		t.Errorf("expected input comments to be left untouched, got role %q", comments[0].Role)
+	}
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"regexp"
+
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// severityMarker は明示的な重要度マーカーのパターンです
+type severityMarker struct {
+	severity string
+	pattern  *regexp.Regexp
+}
+
+// 先頭の接頭辞（"nit:", "[must]" など）を優先し、本文中の表現はその後に判定する
+var severityMarkers = []severityMarker{
+	{models.SeverityNit, regexp.MustCompile(`(?i)^\s*(\[|\()?(nit|nits|nitpick|nit-pick)(\]|\))?\s*[:：\]\)]?(\s|$)`)},
+	{models.SeverityMinor, regexp.MustCompile(`(?i)^\s*(\[|\()?(minor|optional|non[- ]blocking|suggestion|imo|imho|fyi)(\]|\))?\s*[:：,]`)},
+	{models.SeverityMinor, regexp.MustCompile(`(?i)^\s*(\[|\()(minor|optional|non[- ]blocking|imo|imho)(\]|\))`)},
+	{models.SeverityBlocker, regexp.MustCompile(`(?i)^\s*(\[|\()?(blocker|blocking|must|must[- ]fix|critical)(\]|\))?\s*[:：]`)},
+	{models.SeverityBlocker, regexp.MustCompile(`(?i)^\s*(\[|\()(blocker|blocking|must|must[- ]fix|critical)(\]|\))`)},
+	{models.SeverityMajor, regexp.MustCompile(`(?i)^\s*(\[|\()?(major|should|important)(\]|\))?\s*[:：]`)},
+	{models.SeverityMajor, regexp.MustCompile(`(?i)^\s*(\[|\()(major|should|important)(\]|\))`)},
+
+	// 本文中の表現（マージを止める表現を優先する。"optional" はフィールドや引数にもよく使われるため接頭辞のみ）
+	{models.SeverityBlocker, regexp.MustCompile(`(?i)\b(do not merge|don't merge|must be fixed before merg\w*|blocking this pr)\b`)},
+	{models.SeverityMinor, regexp.MustCompile(`(?i)\b(non[- ]blocking|not a blocker|feel free to ignore)\b`)},
+	{models.SeverityMinor, regexp.MustCompile(`(?i)\b(imo|imho|just a thought|take it or leave it)\b`)},
+}
+
+// DetectSeverity はコメント本文の明示的なマーカーから重要度を判定します
+//
+// マーカーがない場合は空文字を返し、LLMによる判定に委ねます。
+func DetectSeverity(body string) string {
+	for _, marker := range severityMarkers {
+		if marker.pattern.MatchString(body) {
+			return marker.severity
+		}
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package collector_test
+
+import (
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+)
+
+func TestDetectSeverity(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"nit: extra blank line", "nit"},
+		{"[nit] could be a const", "nit"},
+		{"Nitpick: typo in comment", "nit"},
+		{"[must] validate the signature before use", "blocker"},
+		{"Blocker: this deletes all rows", "blocker"},
+		{"Please do not merge until the migration is reverted.", "blocker"},
+		{"Major: this breaks backwards compatibility", "major"},
+		{"[should] add a timeout here", "major"},
+		{"IMO this reads better as a switch", "minor"},
+		{"Optional: we could cache this", "minor"},
+		{"Non-blocking, but the name is a bit vague.", "minor"},
+		{"This is non-blocking: consider renaming.", "minor"},
+		{"This will panic when the slice is empty.", ""},
+		{"A nitrogen sensor reading is expected here.", ""},
+		{"Must we keep this flag around?", ""},
+		{"The optional parameter should default to nil here.", ""},
+		{"This optional field is dropped on save, do not merge.", "blocker"},
+	}
+
+	for _, tt := range tests {
+		got := collector.DetectSeverity(tt.body)
+		if got != tt.want {
+			t.Errorf("DetectSeverity(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// SourceTree はドキュメントの参照先を確認するローカルの作業ツリーです
+type SourceTree interface {
+	// ReadFile はファイルを読み込みます。存在しない場合は fs.ErrNotExist を返します
+	ReadFile(path string) ([]byte, error)
+	// GrepFiles は単語を含むファイルを返します
+	GrepFiles(ctx context.Context, word string) ([]string, error)
+}
+
+// StalenessResult は鮮度判定の結果です
+type StalenessResult struct {
+	Status string // models.Staleness*
+	Path   string // 現在の参照先のパス（obsoleteの場合は空）
+	Reason string
+}
+
+// StalenessChecker はドキュメントの参照先が作業ツリーに残っているかを判定します
+type StalenessChecker struct {
+	tree      SourceTree
+	extractor *FileInfoExtractor
+}
+
+// NewStalenessChecker は新しいStalenessCheckerを作成します
+func NewStalenessChecker(tree SourceTree) *StalenessChecker {
+	return &StalenessChecker{
+		tree:      tree,
+		extractor: NewFileInfoExtractor(),
+	}
+}
+
+// Check はドキュメントの参照先の鮮度を判定します
+//
+// filePath は記録時のパス、path はリネームを反映した現在のパスです。ファイルが存在し
+// シンボルも定義されていれば current（パスが変わっていれば moved）とします。ファイルや
+// シンボルが見つからない場合は、シンボルの定義を他のファイルから探して moved とし、
+// 見つからなければ obsolete とします。
+func (c *StalenessChecker) Check(ctx context.Context, filePath, path, symbol string) (StalenessResult, error) {
+	content, err := c.tree.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return StalenessResult{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	exists := err == nil
+
+	if exists && (symbol == "" || DefinesSymbol(c.extractor.DetectLanguage(path, content), content, symbol)) {
+		if path == filePath {
+			return StalenessResult{Status: models.StalenessCurrent, Path: path}, nil
+		}
+		return StalenessResult{Status: models.StalenessMoved, Path: path, Reason: "file renamed"}, nil
+	}
+
+	if symbol != "" {
+		moved, err := c.findSymbol(ctx, path, symbol)
+		if err != nil {
+			return StalenessResult{}, err
+		}
+		if moved != "" {
+			return StalenessResult{Status: models.StalenessMoved, Path: moved, Reason: fmt.Sprintf("%s moved", symbol)}, nil
+		}
+	}
+
+	if exists {
+		return StalenessResult{Status: models.StalenessObsolete, Reason: fmt.Sprintf("%s no longer defined in %s", symbol, path)}, nil
+	}
+	return StalenessResult{Status: models.StalenessObsolete, Reason: fmt.Sprintf("%s no longer exists", path)}, nil
+}
+
+// findSymbol はシンボルを定義している別のファイルを探します
+//
+// 同じ言語のファイルで定義が1つだけ見つかった場合のみそのパスを返します。
+func (c *StalenessChecker) findSymbol(ctx context.Context, path, symbol string) (string, error) {
+	name := symbolName(symbol)
+	files, err := c.tree.GrepFiles(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to search for %s: %w", name, err)
+	}
+
+	language := c.extractor.DetectLanguage(path, nil)
+	var candidates []string
+	for _, file := range files {
+		if file == path || isVendored(file) {
+			continue
+		}
+		content, err := c.tree.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if fileLanguage := c.extractor.DetectLanguage(file, content); fileLanguage != language {
+			continue
+		}
+		if DefinesSymbol(language, content, symbol) {
+			candidates = append(candidates, file)
+		}
+	}
+
+	if len(candidates) != 1 {
+		// 見つからない、または複数の候補があり特定できない
+		return "", nil
+	}
+	return candidates[0], nil
+}
+
+// DefinesSymbol はファイル内容がシンボル（"Type.Method" 形式を含む）を定義しているかを判定します
+//
+// シンボル抽出に対応していない言語は、シンボル名が単語として含まれていれば定義とみなします。
+func DefinesSymbol(language string, content []byte, symbol string) bool {
+	name := symbolName(symbol)
+	if name == "" {
+		return false
+	}
+	owner := ""
+	if i := strings.LastIndex(symbol, "."); i >= 0 {
+		owner = symbol[:i]
+	}
+
+	syntax, ok := symbolSyntaxes[language]
+	if !ok {
+		return regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`).Match(content)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		defined, _, receiver := matchSymbol(syntax, line)
+		if defined != name {
+			continue
+		}
+		// Goのメソッドはレシーバの型も一致する必要がある
+		if receiver != "" && owner != "" && receiver != owner {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// symbolName は "Type.Method" 形式のシンボルから最後の名前を返します
+func symbolName(symbol string) string {
+	if i := strings.LastIndex(symbol, "."); i >= 0 {
+		return symbol[i+1:]
+	}
+	return symbol
+}
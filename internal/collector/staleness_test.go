@@ -0,0 +1,124 @@
+package collector_test
+
+import (
+	"context"
+	"io/fs"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// fakeSourceTree はメモリ上の作業ツリーです
+type fakeSourceTree map[string]string
+
+func (t fakeSourceTree) ReadFile(path string) ([]byte, error) {
+	content, ok := t[path]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func (t fakeSourceTree) GrepFiles(ctx context.Context, word string) ([]string, error) {
+	re := regexp.MustCompile(`\b` + regexp.QuoteMeta(word) + `\b`)
+	var files []string
+	for path, content := range t {
+		if re.MatchString(content) {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func TestStalenessChecker_Check(t *testing.T) {
+	tree := fakeSourceTree{
+		"services/payment/refund.go":  "package payment\n\nfunc (s *Service) Refund(id string) error {\n\treturn nil\n}\n",
+		"services/payment/charge.go":  "package payment\n\nfunc (s *Service) Charge() error {\n\treturn s.Refund(\"x\")\n}\n",
+		"services/billing/invoice.go": "package billing\n\nfunc Issue() {}\n",
+		"app/models/order.rb":         "class Order\n  def cancel\n  end\nend\n",
+		"docs/refund.md":              "Refund flow\n",
+	}
+	checker := collector.NewStalenessChecker(tree)
+
+	tests := []struct {
+		name       string
+		filePath   string
+		path       string
+		symbol     string
+		wantStatus string
+		wantPath   string
+	}{
+		{"file and symbol exist", "services/payment/refund.go", "services/payment/refund.go", "Service.Refund", models.StalenessCurrent, "services/payment/refund.go"},
+		{"file exists without symbol", "services/billing/invoice.go", "services/billing/invoice.go", "", models.StalenessCurrent, "services/billing/invoice.go"},
+		{"file renamed", "pkg/payment/refund.go", "services/payment/refund.go", "", models.StalenessMoved, "services/payment/refund.go"},
+		{"symbol moved to another file", "services/billing/refund.go", "services/billing/refund.go", "Service.Refund", models.StalenessMoved, "services/payment/refund.go"},
+		{"symbol removed from file", "services/billing/invoice.go", "services/billing/invoice.go", "Void", models.StalenessObsolete, ""},
+		{"receiver does not match", "services/billing/invoice.go", "services/billing/invoice.go", "Invoice.Refund", models.StalenessObsolete, ""},
+		{"file deleted", "lib/legacy.go", "lib/legacy.go", "", models.StalenessObsolete, ""},
+		{"ruby method still defined", "app/models/order.rb", "app/models/order.rb", "Order.cancel", models.StalenessCurrent, "app/models/order.rb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			result, err := checker.Check(context.Background(), tt.filePath, tt.path, tt.symbol)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Status != tt.wantStatus || result.Path != tt.wantPath {
+				t.Errorf("Check() = %+v, want status %q path %q", result, tt.wantStatus, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestStalenessChecker_Check_AmbiguousSymbolIsObsolete(t *testing.T) {
+	// Arrange: 削除されたファイルのシンボルが複数のファイルに定義されている
+	tree := fakeSourceTree{
+		"a/handler.go": "package a\n\nfunc Handle() {}\n",
+		"b/handler.go": "package b\n\nfunc Handle() {}\n",
+	}
+	checker := collector.NewStalenessChecker(tree)
+
+	// Act
+	result, err := checker.Check(context.Background(), "c/handler.go", "c/handler.go", "Handle")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != models.StalenessObsolete {
+		t.Errorf("expected obsolete when the symbol cannot be located uniquely, got %+v", result)
+	}
+}
+
+func TestDefinesSymbol(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		content  string
+		symbol   string
+		want     bool
+	}{
+		{"go function", "go", "func Refund() {}\n", "Refund", true},
+		{"go call is not a definition", "go", "func Charge() {\n\tRefund()\n}\n", "Refund", false},
+		{"python method", "python", "class Order:\n    def cancel(self):\n        pass\n", "Order.cancel", true},
+		{"typescript class", "typescript", "export class OrderService {\n}\n", "OrderService", true},
+		{"unsupported language falls back to words", "yaml", "refund_window: 30\n", "refund_window", true},
+		{"empty symbol", "go", "func Refund() {}\n", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collector.DefinesSymbol(tt.language, []byte(tt.content), tt.symbol); got != tt.want {
+				t.Errorf("DefinesSymbol(%q) = %v, want %v", tt.symbol, got, tt.want)
+			}
+		})
+	}
+}
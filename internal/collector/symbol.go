@@ -0,0 +1,360 @@
+package collector
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// symbolPattern はシンボル定義行のパターンです
+type symbolPattern struct {
+	re *regexp.Regexp
+	// isType は型（クラス・構造体など）の定義かどうかです
+	isType bool
+}
+
+// symbolSyntax は言語ごとのシンボル抽出方法です
+type symbolSyntax struct {
+	patterns []symbolPattern
+	// indentBased はブロックをインデントで表す言語かどうかです（Python, Ruby）
+	indentBased bool
+}
+
+// 関数と判定してはいけない制御構文のキーワード
+var controlKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "new": true, "else": true, "do": true, "try": true,
+	"foreach": true, "using": true, "lock": true, "synchronized": true,
+	"when": true, "match": true, "sizeof": true, "typeof": true,
+	"case": true, "await": true, "throw": true, "yield": true, "defer": true, "go": true,
+}
+
+var firstWordPattern = regexp.MustCompile(`^\s*(\w+)`)
+
+var (
+	goSymbolSyntax = symbolSyntax{patterns: []symbolPattern{
+		{re: regexp.MustCompile(`^func\s+\(\s*(?:\w+\s+)?\*?\s*(\w+)(?:\[[^\]]*\])?\s*\)\s*(\w+)`)},
+		{re: regexp.MustCompile(`^func\s+(\w+)`)},
+		{re: regexp.MustCompile(`^type\s+(\w+)`), isType: true},
+	}}
+
+	pythonSymbolSyntax = symbolSyntax{indentBased: true, patterns: []symbolPattern{
+		{re: regexp.MustCompile(`^\s*class\s+(\w+)`), isType: true},
+		{re: regexp.MustCompile(`^\s*(?:async\s+)?def\s+(\w+)`)},
+	}}
+
+	rubySymbolSyntax = symbolSyntax{indentBased: true, patterns: []symbolPattern{
+		{re: regexp.MustCompile(`^\s*(?:class|module)\s+([\w:]+)`), isType: true},
+		{re: regexp.MustCompile(`^\s*def\s+(?:self\.)?(\w+[?!=]?)`)},
+	}}
+
+	braceSymbolSyntax = symbolSyntax{patterns: []symbolPattern{
+		{re: regexp.MustCompile(`\bimpl(?:<[^>]*>)?\s+(?:[\w:]+(?:<[^>]*>)?\s+for\s+)?(\w+)`), isType: true},
+		{re: regexp.MustCompile(`\b(?:class|interface|struct|enum|trait|object|record|protocol|extension)\s+(\w+)`), isType: true},
+		{re: regexp.MustCompile(`\b(?:function|func|fn|fun|def)\s*\*?\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(\w+)\s*[<(]`)},
+		{re: regexp.MustCompile(`\b(?:const|let|var)\s+(\w+)\s*(?::[^=]+)?=\s*(?:async\s*)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|\w+\s*=>)`)},
+		{re: regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|static|final|abstract|override|async|virtual|open|suspend|inline|export|default|readonly|get|set)\s+)*(?:[\w<>\[\],.?*&:]+\s+)*?(\w+)\s*\([^;]*$`)},
+	}}
+)
+
+// symbolSyntaxes は言語ごとのシンボル抽出方法です（Goはgo/parserを優先して使用）
+var symbolSyntaxes = map[string]symbolSyntax{
+	"go":          goSymbolSyntax,
+	"python":      pythonSymbolSyntax,
+	"ruby":        rubySymbolSyntax,
+	"javascript":  braceSymbolSyntax,
+	"typescript":  braceSymbolSyntax,
+	"java":        braceSymbolSyntax,
+	"kotlin":      braceSymbolSyntax,
+	"scala":       braceSymbolSyntax,
+	"groovy":      braceSymbolSyntax,
+	"csharp":      braceSymbolSyntax,
+	"php":         braceSymbolSyntax,
+	"rust":        braceSymbolSyntax,
+	"swift":       braceSymbolSyntax,
+	"dart":        braceSymbolSyntax,
+	"c":           braceSymbolSyntax,
+	"cpp":         braceSymbolSyntax,
+	"objective-c": braceSymbolSyntax,
+	"vue":         braceSymbolSyntax,
+	"svelte":      braceSymbolSyntax,
+}
+
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@ ?(.*)$`)
+
+// ExtractSymbol はコメント対象行を囲む関数・メソッド・型の名前を返します
+//
+// PRのheadコミット時点のファイル内容があればそれを使用し、なければ差分のハンクから推定します。
+// メソッドは "Type.Method" の形式で返します。判定できない場合は空文字列を返します。
+func ExtractSymbol(language string, content []byte, line int, diffHunk string) string {
+	if len(content) > 0 && line > 0 {
+		if language == "go" {
+			if symbol, ok := goEnclosingSymbol(content, line); ok {
+				return symbol
+			}
+		}
+		if syntax, ok := symbolSyntaxes[language]; ok {
+			if symbol := enclosingSymbol(syntax, strings.Split(string(content), "\n"), line-1); symbol != "" {
+				return symbol
+			}
+		}
+	}
+
+	if diffHunk != "" {
+		return hunkSymbol(language, diffHunk, line)
+	}
+	return ""
+}
+
+// goEnclosingSymbol はGoのASTから行を含む宣言を探します
+//
+// 構文エラーで宣言が取得できない場合は ok = false を返します。
+func goEnclosingSymbol(content []byte, line int) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil || (err != nil && len(file.Decls) == 0) {
+		return "", false
+	}
+
+	contains := func(start, end token.Pos) bool {
+		return fset.Position(start).Line <= line && line <= fset.Position(end).Line
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			start := d.Pos()
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			if !contains(start, d.End()) {
+				continue
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				if recv := goReceiverTypeName(d.Recv.List[0].Type); recv != "" {
+					return recv + "." + d.Name.Name, true
+				}
+			}
+			return d.Name.Name, true
+
+		case *ast.GenDecl:
+			start := d.Pos()
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			if !contains(start, d.End()) {
+				continue
+			}
+			for _, spec := range d.Specs {
+				if !contains(spec.Pos(), spec.End()) {
+					continue
+				}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					return s.Name.Name, true
+				case *ast.ValueSpec:
+					if len(s.Names) > 0 {
+						return s.Names[0].Name, true
+					}
+				}
+			}
+			// ドキュメントコメントや括弧の行は最初の型を対象とする
+			if len(d.Specs) > 0 {
+				if s, ok := d.Specs[0].(*ast.TypeSpec); ok {
+					return s.Name.Name, true
+				}
+			}
+		}
+	}
+
+	return "", true
+}
+
+// goReceiverTypeName はレシーバの型名を返します（ポインタや型パラメータは除去）
+func goReceiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return goReceiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return goReceiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// enclosingSymbol はヒューリスティックで対象行（0始まり）を囲むシンボルを探します
+//
+// ネストした関数では最も外側の関数を採用し、それを囲む型があれば "Type.function" とします。
+func enclosingSymbol(syntax symbolSyntax, lines []string, target int) string {
+	if target < 0 || target >= len(lines) {
+		return ""
+	}
+
+	var function string
+	for i := target; i >= 0; i-- {
+		name, isType, receiver := matchSymbol(syntax, lines[i])
+		if name == "" || !encloses(syntax, lines, i, target) {
+			continue
+		}
+
+		switch {
+		case receiver != "":
+			// Goのメソッド定義のようにレシーバの型が定義行に含まれる場合
+			return receiver + "." + name
+		case isType && function != "":
+			return name + "." + function
+		case isType:
+			return name
+		default:
+			function = name
+		}
+	}
+
+	return function
+}
+
+// matchSymbol は行がシンボル定義であれば名前を返します
+func matchSymbol(syntax symbolSyntax, line string) (name string, isType bool, receiver string) {
+	// "return foo(" や "if (" のような文は定義ではない
+	if first := firstWordPattern.FindStringSubmatch(line); first != nil && controlKeywords[first[1]] {
+		return "", false, ""
+	}
+
+	for _, pattern := range syntax.patterns {
+		matches := pattern.re.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		if len(matches) == 3 {
+			return matches[2], false, matches[1]
+		}
+		if controlKeywords[matches[1]] {
+			continue
+		}
+		return matches[1], pattern.isType, ""
+	}
+	return "", false, ""
+}
+
+// encloses は定義行 start のブロックが対象行を含むかを判定します
+func encloses(syntax symbolSyntax, lines []string, start, target int) bool {
+	if start == target {
+		return true
+	}
+
+	if syntax.indentBased {
+		defIndent := indentWidth(lines[start])
+		for i := start + 1; i <= target; i++ {
+			if strings.TrimSpace(lines[i]) == "" {
+				continue
+			}
+			if indentWidth(lines[i]) <= defIndent {
+				return i == target && isBlockEnd(lines[i])
+			}
+		}
+		return true
+	}
+
+	depth := 0
+	opened := false
+	for i := start; i < target; i++ {
+		for _, c := range stripStringLiterals(lines[i]) {
+			switch c {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+		if opened && depth <= 0 {
+			return false
+		}
+	}
+	// 宣言の直後の行（開き括弧が次の行にある場合など）も含める
+	return opened || target-start <= 2
+}
+
+// isBlockEnd はRubyの "end" のようなブロック終端行かどうかを判定します
+func isBlockEnd(line string) bool {
+	return strings.TrimSpace(line) == "end"
+}
+
+// indentWidth は行頭の空白幅を返します（タブは4文字として扱う）
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+var stringLiteralPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|` + "`[^`]*`" + `|//.*$`)
+
+// stripStringLiterals は括弧の数え間違いを防ぐため文字列リテラルと行コメントを取り除きます
+func stripStringLiterals(line string) string {
+	return stringLiteralPattern.ReplaceAllString(line, "")
+}
+
+// hunkSymbol は差分のハンクからシンボルを推定します
+//
+// ハンク内の新しい側の行でシンボルを探し、見つからなければハンクヘッダーの関数コンテキスト
+// （"@@ -10,5 +10,6 @@ func (s *Service) Refund(" の後半）を使用します。
+func hunkSymbol(language, diffHunk string, line int) string {
+	hunkLines := strings.Split(diffHunk, "\n")
+	matches := hunkHeaderPattern.FindStringSubmatch(hunkLines[0])
+	if matches == nil {
+		return ""
+	}
+	startLine, _ := strconv.Atoi(matches[1])
+	headerContext := strings.TrimSpace(matches[2])
+
+	syntax, ok := symbolSyntaxes[language]
+	if !ok {
+		syntax = braceSymbolSyntax
+	}
+
+	// 新しい側の行を再構成（削除行は除く）
+	var newLines []string
+	for _, l := range hunkLines[1:] {
+		if strings.HasPrefix(l, "-") || strings.HasPrefix(l, `\`) {
+			continue
+		}
+		if len(l) > 0 {
+			l = l[1:]
+		}
+		newLines = append(newLines, l)
+	}
+
+	target := len(newLines) - 1
+	if line >= startLine && line-startLine < len(newLines) {
+		target = line - startLine
+	}
+	if symbol := enclosingSymbol(syntax, newLines, target); symbol != "" {
+		return symbol
+	}
+
+	if headerContext != "" {
+		name, _, receiver := matchSymbol(syntax, headerContext)
+		switch {
+		case receiver != "":
+			return receiver + "." + name
+		case name != "":
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+package collector_test
+
+import (
+	"testing"
+
+	"github.com/pankona/knowledges/internal/collector"
+)
+
+const goSource = `package orders
+
+import "context"
+
+// OrderService は注文を扱います
+type OrderService struct {
+	repo Repository
+}
+
+// Refund は注文を返金します
+func (s *OrderService) Refund(ctx context.Context, id string) error {
+	order, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+	return order.Refund()
+}
+
+func NewOrderService(repo Repository) *OrderService {
+	return &OrderService{repo: repo}
+}
+
+type Cache[K comparable, V any] struct{}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	var zero V
+	return zero, false
+}
+`
+
+func TestExtractSymbol_Go(t *testing.T) {
+	tests := []struct {
+		name string
+		line int
+		want string
+	}{
+		{"method body", 12, "OrderService.Refund"},
+		{"method doc comment", 10, "OrderService.Refund"},
+		{"struct field", 7, "OrderService"},
+		{"function", 20, "NewOrderService"},
+		{"generic receiver", 26, "Cache.Get"},
+		{"import line", 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collector.ExtractSymbol("go", []byte(goSource), tt.line, "")
+			if got != tt.want {
+				t.Errorf("ExtractSymbol(line %d) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractSymbol_Heuristics(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		source   string
+		line     int
+		want     string
+	}{
+		{
+			name:     "python method",
+			language: "python",
+			source: `class OrderService:
+    def __init__(self, repo):
+        self.repo = repo
+
+    def refund(self, order_id):
+        order = self.repo.find(order_id)
+        return order.refund()
+
+def helper():
+    pass
+`,
+			line: 6,
+			want: "OrderService.refund",
+		},
+		{
+			name:     "python top-level function after class",
+			language: "python",
+			source: `class OrderService:
+    def refund(self):
+        pass
+
+def helper():
+    return 1
+`,
+			line: 6,
+			want: "helper",
+		},
+		{
+			name:     "typescript class method",
+			language: "typescript",
+			source: `export class OrderService {
+  constructor(private repo: Repository) {}
+
+  async refund(id: string): Promise<void> {
+    const order = await this.repo.find(id);
+    if (!order) {
+      throw new Error("not found");
+    }
+    await order.refund();
+  }
+}
+`,
+			line: 7,
+			want: "OrderService.refund",
+		},
+		{
+			name:     "javascript arrow function",
+			language: "javascript",
+			source: `const formatPrice = (amount) => {
+  return amount.toFixed(2);
+};
+
+export function total(items) {
+  return items.reduce((sum, item) => sum + item.price, 0);
+}
+`,
+			line: 6,
+			want: "total",
+		},
+		{
+			name:     "java method after closed method",
+			language: "java",
+			source: `public class OrderService {
+    public void cancel(String id) {
+        repo.delete(id);
+    }
+
+    public Refund refund(String id) throws IOException {
+        Order order = repo.find(id);
+        return order.refund();
+    }
+}
+`,
+			line: 8,
+			want: "OrderService.refund",
+		},
+		{
+			name:     "rust impl block",
+			language: "rust",
+			source: `impl Display for Order {
+    fn fmt(&self, f: &mut Formatter) -> Result {
+        write!(f, "{}", self.id)
+    }
+}
+`,
+			line: 3,
+			want: "Order.fmt",
+		},
+		{
+			name:     "ruby method",
+			language: "ruby",
+			source: `class OrderService
+  def refund(id)
+    order = find(id)
+    order.refund!
+  end
+end
+`,
+			line: 4,
+			want: "OrderService.refund",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collector.ExtractSymbol(tt.language, []byte(tt.source), tt.line, "")
+			if got != tt.want {
+				t.Errorf("ExtractSymbol(line %d) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractSymbol_DiffHunk(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		hunk     string
+		line     int
+		want     string
+	}{
+		{
+			name:     "definition inside hunk",
+			language: "go",
+			hunk: "@@ -18,6 +18,8 @@ import \"context\"\n" +
+				" \n" +
+				" func (s *OrderService) Refund(ctx context.Context, id string) error {\n" +
+				"-\torder := s.repo.Find(id)\n" +
+				"+\torder, err := s.repo.Find(ctx, id)\n" +
+				"+\tif err != nil {\n" +
+				"+\t\treturn err\n" +
+				"+\t}",
+			line: 22,
+			want: "OrderService.Refund",
+		},
+		{
+			name:     "function context in hunk header",
+			language: "go",
+			hunk: "@@ -40,3 +40,3 @@ func (s *OrderService) Refund(ctx context.Context, id string) error {\n" +
+				" \tlog.Printf(\"refund %s\", id)\n" +
+				"-\treturn nil\n" +
+				"+\treturn order.Refund()",
+			line: 41,
+			want: "OrderService.Refund",
+		},
+		{
+			name:     "python header context",
+			language: "python",
+			hunk: "@@ -10,2 +10,2 @@ def refund(self, order_id):\n" +
+				"-        return None\n" +
+				"+        return order.refund()",
+			line: 10,
+			want: "refund",
+		},
+		{
+			name:     "no context",
+			language: "go",
+			hunk:     "@@ -1,2 +1,2 @@\n-package a\n+package b",
+			line:     1,
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collector.ExtractSymbol(tt.language, nil, tt.line, tt.hunk)
+			if got != tt.want {
+				t.Errorf("ExtractSymbol() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
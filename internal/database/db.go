@@ -75,6 +75,36 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to create documents table: %w", err)
 	}
 
+	// 後から追加されたカラム（既存DBにも適用するためALTER TABLEで追加）
+	documentColumns := []column{
+		{name: "analysis_method", definition: "TEXT NOT NULL DEFAULT 'llm'"},
+		{name: "comment_role", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "pr_author", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "text_hash", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "duplicate_group", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "duplicate_of", definition: "INTEGER"},
+		{name: "severity", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "file_role", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "owners", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "project", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "symbol", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "current_path", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "staleness", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "staleness_checked_at", definition: "DATETIME"},
+		{name: "llm_driver", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "llm_model", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "prompt_version", definition: "TEXT NOT NULL DEFAULT ''"},
+		{name: "repair_count", definition: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "llm_input_tokens", definition: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "llm_output_tokens", definition: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "llm_tokens_estimated", definition: "INTEGER NOT NULL DEFAULT 0"},
+		{name: "llm_latency_ms", definition: "INTEGER NOT NULL DEFAULT 0"},
+	}
+
+	if err := addColumns(db, "documents", documentColumns); err != nil {
+		return err
+	}
+
 	// インデックスの作成
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_documents_file_path ON documents(file_path)",
@@ -83,6 +113,16 @@ func Migrate(db *sql.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_documents_comment_type ON documents(comment_type)",
 		"CREATE INDEX IF NOT EXISTS idx_documents_repository ON documents(repository)",
 		"CREATE INDEX IF NOT EXISTS idx_documents_commented_at ON documents(commented_at)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_analysis_method ON documents(analysis_method)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_comment_role ON documents(comment_role)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_duplicate_group ON documents(duplicate_group)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_severity ON documents(severity)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_file_role ON documents(file_role)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_project ON documents(project)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_symbol ON documents(symbol)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_current_path ON documents(current_path)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_staleness ON documents(staleness)",
+		"CREATE INDEX IF NOT EXISTS idx_documents_prompt_version ON documents(prompt_version)",
 	}
 
 	for _, index := range indexes {
@@ -109,5 +149,192 @@ func Migrate(db *sql.DB) error {
 		return fmt.Errorf("failed to create collection_progress table: %w", err)
 	}
 
+	// codeownersテーブルの作成（オーナー解決に使用したCODEOWNERSの変更検知用）
+	createCodeOwnersTable := `
+	CREATE TABLE IF NOT EXISTS codeowners (
+		repository TEXT PRIMARY KEY,
+		path TEXT NOT NULL,
+		content_hash TEXT NOT NULL,
+		resolved_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`
+
+	if _, err := db.Exec(createCodeOwnersTable); err != nil {
+		return fmt.Errorf("failed to create codeowners table: %w", err)
+	}
+
+	// path_aliasesテーブルの作成（リネーム前のパスと現在のパスの対応）
+	createPathAliasesTable := `
+	CREATE TABLE IF NOT EXISTS path_aliases (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		repository TEXT NOT NULL,
+		alias_path TEXT NOT NULL,
+		current_path TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(repository, alias_path)
+	)`
+
+	if _, err := db.Exec(createPathAliasesTable); err != nil {
+		return fmt.Errorf("failed to create path_aliases table: %w", err)
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_path_aliases_current_path ON path_aliases(repository, current_path)"); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	// llm_cacheテーブルの作成（プロンプト・ドライバー・モデルごとのLLMの分析結果）
+	createLLMCacheTable := `
+	CREATE TABLE IF NOT EXISTS llm_cache (
+		cache_key TEXT PRIMARY KEY,
+		driver TEXT NOT NULL,
+		model TEXT NOT NULL,
+		result TEXT NOT NULL,
+		repair_count INTEGER NOT NULL DEFAULT 0,
+		hit_count INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		last_hit_at DATETIME
+	)`
+
+	if _, err := db.Exec(createLLMCacheTable); err != nil {
+		return fmt.Errorf("failed to create llm_cache table: %w", err)
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_llm_cache_created_at ON llm_cache(created_at)"); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	// collection_runsテーブルの作成（収集の実行ごとの記録）
+	createRunsTable := `
+	CREATE TABLE IF NOT EXISTS collection_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		repository TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME NOT NULL,
+		documents INTEGER NOT NULL DEFAULT 0,
+		cancelled INTEGER NOT NULL DEFAULT 0
+	)`
+
+	if _, err := db.Exec(createRunsTable); err != nil {
+		return fmt.Errorf("failed to create collection_runs table: %w", err)
+	}
+
+	// llm_usageテーブルの作成（実行ごと・ドライバーごとのLLMの使用量。失敗した呼び出しを含む）
+	createUsageTable := `
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		run_id INTEGER NOT NULL REFERENCES collection_runs(id) ON DELETE CASCADE,
+		driver TEXT NOT NULL,
+		model TEXT NOT NULL,
+		calls INTEGER NOT NULL DEFAULT 0,
+		input_tokens INTEGER NOT NULL DEFAULT 0,
+		output_tokens INTEGER NOT NULL DEFAULT 0,
+		estimated_calls INTEGER NOT NULL DEFAULT 0,
+		latency_ms INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (run_id, driver, model)
+	)`
+
+	if _, err := db.Exec(createUsageTable); err != nil {
+		return fmt.Errorf("failed to create llm_usage table: %w", err)
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_collection_runs_repository ON collection_runs(repository, started_at)"); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	// analysis_versionsテーブルの作成（再分析したドキュメントの分析結果の履歴）
+	// kcollector -pr-url で再収集するとドキュメントは削除・再作成されるため、
+	// 履歴はドキュメントのIDではなくコメント（リポジトリ・PR・コメントURL）に紐付ける
+	createAnalysisVersionsTable := `
+	CREATE TABLE IF NOT EXISTS analysis_versions (
+		repository TEXT NOT NULL,
+		pr_number INTEGER NOT NULL,
+		comment_url TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		summary TEXT NOT NULL,
+		comment_type TEXT NOT NULL,
+		tags TEXT,
+		relevance_score REAL NOT NULL,
+		severity TEXT NOT NULL DEFAULT '',
+		analysis_method TEXT NOT NULL,
+		llm_driver TEXT NOT NULL DEFAULT '',
+		llm_model TEXT NOT NULL DEFAULT '',
+		prompt_version TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (repository, pr_number, comment_url, version)
+	)`
+
+	if _, err := db.Exec(createAnalysisVersionsTable); err != nil {
+		return fmt.Errorf("failed to create analysis_versions table: %w", err)
+	}
+
+	// eval_runsテーブルの作成（評価データセットによる分類の評価の実行ごとの集計）
+	createEvalRunsTable := `
+	CREATE TABLE IF NOT EXISTS eval_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		dataset TEXT NOT NULL,
+		dataset_hash TEXT NOT NULL,
+		driver TEXT NOT NULL,
+		model TEXT NOT NULL DEFAULT '',
+		prompt_version TEXT NOT NULL DEFAULT '',
+		cases INTEGER NOT NULL,
+		failed INTEGER NOT NULL DEFAULT 0,
+		accuracy REAL NOT NULL,
+		tag_overlap REAL NOT NULL DEFAULT 0,
+		severity_accuracy REAL NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL
+	)`
+
+	if _, err := db.Exec(createEvalRunsTable); err != nil {
+		return fmt.Errorf("failed to create eval_runs table: %w", err)
+	}
+
+	// eval_resultsテーブルの作成（評価のケースごとの期待値と予測）
+	createEvalResultsTable := `
+	CREATE TABLE IF NOT EXISTS eval_results (
+		run_id INTEGER NOT NULL REFERENCES eval_runs(id) ON DELETE CASCADE,
+		case_id TEXT NOT NULL,
+		expected_type TEXT NOT NULL,
+		predicted_type TEXT NOT NULL DEFAULT '',
+		expected_tags TEXT NOT NULL DEFAULT '',
+		predicted_tags TEXT NOT NULL DEFAULT '',
+		expected_severity TEXT NOT NULL DEFAULT '',
+		predicted_severity TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (run_id, case_id)
+	)`
+
+	if _, err := db.Exec(createEvalResultsTable); err != nil {
+		return fmt.Errorf("failed to create eval_results table: %w", err)
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_eval_runs_dataset ON eval_runs(dataset, id)"); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
 	return nil
-}
\ No newline at end of file
+}
+
+// column は追加カラムの定義です
+type column struct {
+	name       string
+	definition string
+}
+
+// addColumns はテーブルに存在しないカラムのみを追加します
+func addColumns(db *sql.DB, table string, columns []column) error {
+	for _, c := range columns {
+		var count int
+		query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+		if err := db.QueryRow(query, table, c.name).Scan(&count); err != nil {
+			return fmt.Errorf("failed to inspect column %s.%s: %w", table, c.name, err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, c.name, c.definition)
+		if _, err := db.Exec(alter); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, c.name, err)
+		}
+	}
+
+	return nil
+}
@@ -143,4 +143,46 @@ func TestMigrate_Idempotent(t *testing.T) {
 	if count != 1 {
 		t.Errorf("expected 1 documents table, got %d", count)
 	}
-}
\ No newline at end of file
+}
+
+func TestMigrate_AddsColumnsToExistingTable(t *testing.T) {
+	// Arrange - create an old documents table without the added columns
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+	db, err := database.New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE documents (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		summary TEXT NOT NULL,
+		original_comment TEXT NOT NULL,
+		file_path TEXT NOT NULL,
+		directory_path TEXT NOT NULL,
+		language TEXT NOT NULL,
+		repository TEXT NOT NULL,
+		pr_number INTEGER NOT NULL,
+		comment_type TEXT NOT NULL,
+		commented_at DATETIME NOT NULL
+	)`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+
+	// Assert
+	ctx := context.Background()
+	var count int
+	query := `SELECT COUNT(*) FROM pragma_table_info('documents') WHERE name = 'analysis_method'`
+	if err := db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		t.Fatalf("failed to check column: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected analysis_method column to be added")
+	}
+}
@@ -0,0 +1,25 @@
+package dbtest
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/pankona/knowledges/internal/database"
+)
+
+// New は一時ディレクトリにマイグレーション済みのデータベースを作成します（テストの終了時に閉じる）
+func New(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,221 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CommandExecutor は外部コマンドを実行するインターフェース
+type CommandExecutor interface {
+	Execute(ctx context.Context, cmd string, args ...string) ([]byte, error)
+}
+
+// DefaultCommandExecutor は実際のコマンドを実行します
+type DefaultCommandExecutor struct{}
+
+func (e *DefaultCommandExecutor) Execute(ctx context.Context, cmd string, args ...string) ([]byte, error) {
+	command := exec.CommandContext(ctx, cmd, args...)
+	return command.Output()
+}
+
+// Rename はコミットでのファイルのリネームを表現します
+type Rename struct {
+	Commit     string
+	Time       time.Time
+	OldPath    string
+	NewPath    string
+	Similarity int
+}
+
+// Repository はローカルのgitチェックアウトを操作します
+type Repository struct {
+	dir      string
+	executor CommandExecutor
+}
+
+// NewRepository は新しいRepositoryを作成します
+func NewRepository(dir string) *Repository {
+	return &Repository{
+		dir:      dir,
+		executor: &DefaultCommandExecutor{},
+	}
+}
+
+// SetExecutor はコマンド実行器を設定します（テスト用）
+func (r *Repository) SetExecutor(executor CommandExecutor) {
+	r.executor = executor
+}
+
+// Dir はチェックアウトのディレクトリを返します
+func (r *Repository) Dir() string {
+	return r.dir
+}
+
+// run はチェックアウトのディレクトリでgitコマンドを実行します
+func (r *Repository) run(ctx context.Context, args ...string) ([]byte, error) {
+	output, err := r.executor.Execute(ctx, "git", append([]string{"-C", r.dir}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	return output, nil
+}
+
+// ReadFile は作業ツリーのファイルを読み込みます
+//
+// path はリポジトリルートからのスラッシュ区切りの相対パスです。
+func (r *Repository) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(r.dir, filepath.FromSlash(path)))
+}
+
+// GrepFiles は単語を含む追跡対象のファイルを返します
+//
+// git grep -w で単語単位の固定文字列として検索します。マッチしない場合は空を返します。
+func (r *Repository) GrepFiles(ctx context.Context, word string) ([]string, error) {
+	output, err := r.run(ctx, "-c", "core.quotePath=false", "grep", "-l", "-w", "-F", "-e", word)
+	if err != nil {
+		// git grep はマッチしない場合に終了コード1を返す
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// commitHeaderPrefix はlog出力でコミット行を識別するための接頭辞です
+const commitHeaderPrefix = "commit\t"
+
+// Renames は履歴全体のリネームを古い順に返します
+//
+// git log --name-status -M の結果からリネーム（R）のみを抽出します。
+func (r *Repository) Renames(ctx context.Context) ([]Rename, error) {
+	output, err := r.run(ctx,
+		"-c", "core.quotePath=false",
+		"log", "--name-status", "-M", "--diff-filter=R", "--no-merges",
+		"--format="+commitHeaderPrefix+"%H\t%ct",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	renames, err := parseRenames(output)
+	if err != nil {
+		return nil, err
+	}
+
+	// git log は新しい順のため、古い順に並べ替える
+	for i, j := 0, len(renames)-1; i < j; i, j = i+1, j-1 {
+		renames[i], renames[j] = renames[j], renames[i]
+	}
+	return renames, nil
+}
+
+// parseRenames はgit logの出力をパースします
+func parseRenames(output []byte) ([]Rename, error) {
+	var renames []Rename
+	var commit string
+	var committedAt time.Time
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, commitHeaderPrefix) {
+			fields := strings.Split(strings.TrimPrefix(line, commitHeaderPrefix), "\t")
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("unexpected commit line: %q", line)
+			}
+			unix, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid commit time in %q: %w", line, err)
+			}
+			commit = fields[0]
+			committedAt = time.Unix(unix, 0).UTC()
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || !strings.HasPrefix(fields[0], "R") {
+			continue
+		}
+		similarity, _ := strconv.Atoi(strings.TrimPrefix(fields[0], "R"))
+
+		renames = append(renames, Rename{
+			Commit:     commit,
+			Time:       committedAt,
+			OldPath:    fields[1],
+			NewPath:    fields[2],
+			Similarity: similarity,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read git log output: %w", err)
+	}
+	return renames, nil
+}
+
+// ResolvePath はリネーム履歴をたどり、指定時刻より後のリネームを適用した現在のパスを返します
+//
+// renames は古い順である必要があります。
+func ResolvePath(renames []Rename, path string, since time.Time) string {
+	current := path
+	for _, rename := range renames {
+		if !rename.Time.After(since) {
+			continue
+		}
+		if rename.OldPath == current {
+			current = rename.NewPath
+		}
+	}
+	return current
+}
+
+// PathAliases はリネーム履歴から過去のパスと現在のパスの対応を作成します
+//
+// A → B → C のようにリネームが連鎖した場合、A と B の両方が C に対応します。
+func PathAliases(renames []Rename) map[string]string {
+	current := make(map[string]string)   // 過去のパス → 現在のパス
+	origins := make(map[string][]string) // 現在のパス → 過去のパス
+
+	for _, rename := range renames {
+		moved := origins[rename.OldPath]
+		delete(origins, rename.OldPath)
+		if _, ok := current[rename.OldPath]; !ok {
+			moved = append(moved, rename.OldPath)
+		}
+
+		for _, origin := range moved {
+			if origin == rename.NewPath {
+				// 元のパスに戻された場合はエイリアスではなくなる
+				delete(current, origin)
+				continue
+			}
+			current[origin] = rename.NewPath
+			origins[rename.NewPath] = append(origins[rename.NewPath], origin)
+		}
+	}
+
+	return current
+}
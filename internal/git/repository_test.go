@@ -0,0 +1,179 @@
+package git_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/git"
+)
+
+// MockCommandExecutor は外部コマンド実行をモックします
+type MockCommandExecutor struct {
+	output   string
+	err      error
+	lastCmd  string
+	lastArgs []string
+}
+
+func (m *MockCommandExecutor) Execute(ctx context.Context, cmd string, args ...string) ([]byte, error) {
+	m.lastCmd = cmd
+	m.lastArgs = args
+	return []byte(m.output), m.err
+}
+
+func TestRepository_Renames_Success(t *testing.T) {
+	// Arrange: git log は新しい順に出力する
+	mockExecutor := &MockCommandExecutor{
+		output: "commit\tbbb\t1700000200\n" +
+			"\n" +
+			"R100\tpkg/payment/charge.go\tservices/payment/charge.go\n" +
+			"R087\tpkg/payment/refund.go\tservices/payment/refund.go\n" +
+			"commit\taaa\t1700000100\n" +
+			"\n" +
+			"R095\tlib/charge.go\tpkg/payment/charge.go\n",
+	}
+
+	repo := git.NewRepository("/src/repo")
+	repo.SetExecutor(mockExecutor)
+
+	// Act
+	renames, err := repo.Renames(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(renames) != 3 {
+		t.Fatalf("expected 3 renames, got %d", len(renames))
+	}
+	if renames[0].Commit != "aaa" || renames[0].OldPath != "lib/charge.go" || renames[0].Similarity != 95 {
+		t.Errorf("expected oldest rename first, got %+v", renames[0])
+	}
+	if !renames[0].Time.Equal(time.Unix(1700000100, 0)) {
+		t.Errorf("unexpected commit time: %v", renames[0].Time)
+	}
+	if mockExecutor.lastCmd != "git" || mockExecutor.lastArgs[0] != "-C" || mockExecutor.lastArgs[1] != "/src/repo" {
+		t.Errorf("expected git -C /src/repo, got %s %v", mockExecutor.lastCmd, mockExecutor.lastArgs)
+	}
+}
+
+func TestRepository_Renames_CommandError(t *testing.T) {
+	// Arrange
+	mockExecutor := &MockCommandExecutor{err: fmt.Errorf("not a git repository")}
+
+	repo := git.NewRepository("/tmp/not-a-repo")
+	repo.SetExecutor(mockExecutor)
+
+	// Act
+	_, err := repo.Renames(context.Background())
+
+	// Assert
+	if err == nil {
+		t.Error("expected error when git fails")
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	t1 := time.Unix(1700000100, 0)
+	t2 := time.Unix(1700000200, 0)
+	renames := []git.Rename{
+		{Time: t1, OldPath: "lib/charge.go", NewPath: "pkg/payment/charge.go"},
+		{Time: t2, OldPath: "pkg/payment/charge.go", NewPath: "services/payment/charge.go"},
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		since time.Time
+		want  string
+	}{
+		{"follows the whole chain", "lib/charge.go", t1.Add(-time.Hour), "services/payment/charge.go"},
+		{"ignores renames before the comment", "lib/charge.go", t1, "lib/charge.go"},
+		{"intermediate path", "pkg/payment/charge.go", t1, "services/payment/charge.go"},
+		{"never renamed", "main.go", time.Time{}, "main.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := git.ResolvePath(renames, tt.path, tt.since); got != tt.want {
+				t.Errorf("ResolvePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathAliases(t *testing.T) {
+	renames := []git.Rename{
+		{OldPath: "a.go", NewPath: "b.go"},
+		{OldPath: "b.go", NewPath: "c.go"},
+		{OldPath: "x.go", NewPath: "y.go"},
+		{OldPath: "y.go", NewPath: "x.go"},
+	}
+
+	got := git.PathAliases(renames)
+
+	want := map[string]string{
+		"a.go": "c.go",
+		"b.go": "c.go",
+		"y.go": "x.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PathAliases() = %v, want %v", got, want)
+	}
+}
+
+func TestRepository_GrepFiles(t *testing.T) {
+	// Arrange
+	mockExecutor := &MockCommandExecutor{output: "services/payment/refund.go\nservices/payment/refund_test.go\n"}
+
+	repo := git.NewRepository("/src/repo")
+	repo.SetExecutor(mockExecutor)
+
+	// Act
+	files, err := repo.GrepFiles(context.Background(), "Refund")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"services/payment/refund.go", "services/payment/refund_test.go"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("GrepFiles() = %v, want %v", files, want)
+	}
+	args := strings.Join(mockExecutor.lastArgs, " ")
+	if !strings.Contains(args, "grep -l -w -F -e Refund") {
+		t.Errorf("expected a fixed-string word grep, got %v", mockExecutor.lastArgs)
+	}
+}
+
+func TestRepository_ReadFile(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0o755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg", "main.go"), []byte("package pkg\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	repo := git.NewRepository(dir)
+
+	// Act
+	content, err := repo.ReadFile("pkg/main.go")
+	_, missingErr := repo.ReadFile("pkg/missing.go")
+
+	// Assert
+	if err != nil || string(content) != "package pkg\n" {
+		t.Errorf("ReadFile() = %q, %v", content, err)
+	}
+	if !errors.Is(missingErr, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing file, got %v", missingErr)
+	}
+}
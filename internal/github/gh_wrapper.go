@@ -3,13 +3,18 @@ package github
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// ErrNotFound はGitHub上にファイルなどが存在しないこと（HTTP 404）を表します
+var ErrNotFound = errors.New("not found")
+
 // PullRequest はPRの情報を表現します
 type PullRequest struct {
 	Number    int       `json:"number"`
@@ -18,6 +23,10 @@ type PullRequest struct {
 	CreatedAt time.Time `json:"createdAt"`
 	Author    Author    `json:"author"`
 	Labels    []Label   `json:"labels,omitempty"`
+
+	// HeadRefOid / BaseRefOid はPRのhead/baseコミットのSHAです
+	HeadRefOid string `json:"headRefOid,omitempty"`
+	BaseRefOid string `json:"baseRefOid,omitempty"`
 }
 
 // Label はPRのラベル情報を表現します
@@ -38,6 +47,13 @@ type Comment struct {
 	URL        string    `json:"url"`
 	FilePath   string    `json:"filePath"`   // GraphQLレスポンスから抽出
 	LineNumber int       `json:"lineNumber"` // GraphQLレスポンスから抽出
+
+	// ThreadPosition はレビュースレッド内での位置です（0 = スレッドの起点）
+	ThreadPosition int `json:"threadPosition"`
+	// Role はPR内でのコメント投稿者の役割です（collector.LabelRolesで設定）
+	Role string `json:"role,omitempty"`
+	// DiffHunk はコメント対象行を含む差分のハンクです
+	DiffHunk string `json:"diffHunk,omitempty"`
 }
 
 // GraphQLレスポンス用の構造体
@@ -55,6 +71,7 @@ type graphQLResponse struct {
 								Body      string `json:"body"`
 								CreatedAt string `json:"createdAt"`
 								URL       string `json:"url"`
+								DiffHunk  string `json:"diffHunk"`
 							} `json:"nodes"`
 						} `json:"comments"`
 					} `json:"nodes"`
@@ -64,6 +81,9 @@ type graphQLResponse struct {
 	} `json:"data"`
 }
 
+// prListFields は gh pr list/view で取得するフィールドです
+const prListFields = "number,title,url,createdAt,author,headRefOid,baseRefOid"
+
 // CommandExecutor は外部コマンドを実行するインターフェース
 type CommandExecutor interface {
 	Execute(ctx context.Context, cmd string, args ...string) ([]byte, error)
@@ -103,7 +123,7 @@ func (g *GHWrapper) GetMergedPRs(ctx context.Context, limit int) ([]PullRequest,
 		"--repo", g.repo,
 		"--state", "merged",
 		"--limit", fmt.Sprintf("%d", limit),
-		"--json", "number,title,url,createdAt,author",
+		"--json", prListFields,
 	}
 
 	output, err := g.executor.Execute(ctx, "gh", args...)
@@ -127,7 +147,7 @@ func (g *GHWrapper) GetMergedPRsWithLabel(ctx context.Context, limit int, label
 		"--state", "merged",
 		"--limit", fmt.Sprintf("%d", limit),
 		"--search", fmt.Sprintf("label:%s", label),
-		"--json", "number,title,url,createdAt,author,labels",
+		"--json", prListFields+",labels",
 	}
 
 	output, err := g.executor.Execute(ctx, "gh", args...)
@@ -178,7 +198,7 @@ func (g *GHWrapper) GetMergedPRsExcludingBots(ctx context.Context, limit int, la
 		args = append(args, "--search", strings.Join(searchTerms, " "))
 	}
 
-	args = append(args, "--json", "number,title,url,createdAt,author,labels")
+	args = append(args, "--json", prListFields+",labels")
 
 	output, err := g.executor.Execute(ctx, "gh", args...)
 	if err != nil {
@@ -211,6 +231,7 @@ func (g *GHWrapper) GetPRComments(ctx context.Context, prNumber int) ([]Comment,
 								body
 								createdAt
 								url
+								diffHunk
 							}
 						}
 					}
@@ -243,7 +264,7 @@ func (g *GHWrapper) GetPRComments(ctx context.Context, prNumber int) ([]Comment,
 
 	var comments []Comment
 	for _, thread := range response.Data.Repository.PullRequest.ReviewThreads.Nodes {
-		for _, comment := range thread.Comments.Nodes {
+		for position, comment := range thread.Comments.Nodes {
 			createdAt, err := time.Parse(time.RFC3339, comment.CreatedAt)
 			if err != nil {
 				// Skip invalid timestamps but continue processing
@@ -257,6 +278,9 @@ func (g *GHWrapper) GetPRComments(ctx context.Context, prNumber int) ([]Comment,
 				URL:        comment.URL,
 				FilePath:   thread.Path,
 				LineNumber: thread.Line,
+
+				ThreadPosition: position,
+				DiffHunk:       comment.DiffHunk,
 			})
 		}
 	}
@@ -274,7 +298,7 @@ func (g *GHWrapper) GetPR(ctx context.Context, prNumber int) (*PullRequest, erro
 	args := []string{
 		"pr", "view", strconv.Itoa(prNumber),
 		"--repo", g.repo,
-		"--json", "number,title,url,createdAt,author",
+		"--json", prListFields,
 	}
 
 	output, err := g.executor.Execute(ctx, "gh", args...)
@@ -290,6 +314,79 @@ func (g *GHWrapper) GetPR(ctx context.Context, prNumber int) (*PullRequest, erro
 	return &pr, nil
 }
 
+// GetFileContent は指定したコミット時点のファイル内容を取得します
+func (g *GHWrapper) GetFileContent(ctx context.Context, path, ref string) ([]byte, error) {
+	owner, name := parseRepo(g.repo)
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository format: %s", g.repo)
+	}
+
+	endpoint := fmt.Sprintf("repos/%s/%s/contents/%s", owner, name, url.PathEscape(path))
+	endpoint = strings.ReplaceAll(endpoint, "%2F", "/")
+	if ref != "" {
+		endpoint += "?ref=" + url.QueryEscape(ref)
+	}
+
+	args := []string{
+		"api", endpoint,
+		"-H", "Accept: application/vnd.github.raw",
+	}
+
+	output, err := g.executor.Execute(ctx, "gh", args...)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%s at %s: %w", path, ref, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to fetch %s at %s: %w", path, ref, err)
+	}
+
+	return output, nil
+}
+
+// isNotFound はghコマンドのエラーがHTTP 404によるものかどうかを判定します
+//
+// gh api は404の場合に "gh: Not Found (HTTP 404)" を標準エラー出力に出力します。
+func isNotFound(err error) bool {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && strings.Contains(string(exitErr.Stderr), "(HTTP 404)") {
+		return true
+	}
+	return strings.Contains(err.Error(), "(HTTP 404)")
+}
+
+// ListFiles は指定したコミット時点のリポジトリ内の全ファイルパスを取得します
+//
+// refが空の場合はデフォルトブランチを参照します。
+func (g *GHWrapper) ListFiles(ctx context.Context, ref string) ([]string, error) {
+	owner, name := parseRepo(g.repo)
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository format: %s", g.repo)
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	args := []string{
+		"api", fmt.Sprintf("repos/%s/%s/git/trees/%s?recursive=1", owner, name, url.PathEscape(ref)),
+		"--jq", `.tree[] | select(.type == "blob") | .path`,
+	}
+
+	output, err := g.executor.Execute(ctx, "gh", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files at %s: %w", ref, err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+
+	return files, nil
+}
+
 // parseRepo はrepo文字列を owner/name に分割します
 func parseRepo(repo string) (owner, name string) {
 	parts := strings.Split(repo, "/")
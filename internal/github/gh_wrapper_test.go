@@ -2,6 +2,7 @@ package github_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -68,7 +69,7 @@ func TestGHWrapper_GetMergedPRs_Success(t *testing.T) {
 		"--repo", "owner/repo",
 		"--state", "merged",
 		"--limit", "2",
-		"--json", "number,title,url,createdAt,author",
+		"--json", "number,title,url,createdAt,author,headRefOid,baseRefOid",
 	}
 	if !equalStringSlices(mockExecutor.lastArgs, expectedArgs) {
 		t.Errorf("expected args %v, got %v", expectedArgs, mockExecutor.lastArgs)
@@ -271,7 +272,8 @@ func TestGHWrapper_GetPRComments_Success(t *testing.T) {
 											"author": { "login": "reviewer1" },
 											"body": "Consider using a more descriptive variable name here.",
 											"createdAt": "2024-01-15T10:00:00Z",
-											"url": "https://github.com/owner/repo/pull/123#discussion_r1"
+											"url": "https://github.com/owner/repo/pull/123#discussion_r1",
+											"diffHunk": "@@ -40,3 +40,3 @@ func main() {\n-\tr := newRepo()\n+\tu := newRepo()"
 										},
 										{
 											"author": { "login": "author1" },
@@ -337,6 +339,15 @@ func TestGHWrapper_GetPRComments_Success(t *testing.T) {
 	if comment1.LineNumber != 42 {
 		t.Errorf("expected line number 42, got %d", comment1.LineNumber)
 	}
+	if comment1.DiffHunk == "" || comments[2].DiffHunk != "" {
+		t.Errorf("expected diff hunk only on the first comment, got %q and %q", comment1.DiffHunk, comments[2].DiffHunk)
+	}
+	if comment1.ThreadPosition != 0 {
+		t.Errorf("expected thread position 0, got %d", comment1.ThreadPosition)
+	}
+	if comments[1].ThreadPosition != 1 {
+		t.Errorf("expected reply to have thread position 1, got %d", comments[1].ThreadPosition)
+	}
 
 	// Verify GraphQL command was called correctly
 	if mockExecutor.lastCmd != "gh" {
@@ -451,7 +462,7 @@ func TestGHWrapper_GetPR_Success(t *testing.T) {
 	expectedArgs := []string{
 		"pr", "view", "123",
 		"--repo", "owner/repo",
-		"--json", "number,title,url,createdAt,author",
+		"--json", "number,title,url,createdAt,author,headRefOid,baseRefOid",
 	}
 	if !equalStringSlices(mockExecutor.lastArgs, expectedArgs) {
 		t.Errorf("expected args %v, got %v", expectedArgs, mockExecutor.lastArgs)
@@ -492,6 +503,128 @@ func TestParsePRCreatedAt(t *testing.T) {
 	}
 }
 
+func TestGHWrapper_GetFileContent_Success(t *testing.T) {
+	// Arrange
+	mockExecutor := &MockCommandExecutor{
+		output: "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage v1\n",
+	}
+
+	wrapper := github.NewGHWrapper("owner/repo")
+	wrapper.SetExecutor(mockExecutor)
+
+	// Act
+	content, err := wrapper.GetFileContent(context.Background(), "api/v1/service pb.go", "abc123")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(content) != mockExecutor.output {
+		t.Errorf("unexpected content: %q", content)
+	}
+
+	expectedArgs := []string{
+		"api", "repos/owner/repo/contents/api/v1/service%20pb.go?ref=abc123",
+		"-H", "Accept: application/vnd.github.raw",
+	}
+	if !equalStringSlices(mockExecutor.lastArgs, expectedArgs) {
+		t.Errorf("expected args %v, got %v", expectedArgs, mockExecutor.lastArgs)
+	}
+}
+
+func TestGHWrapper_GetFileContent_CommandError(t *testing.T) {
+	// Arrange
+	mockExecutor := &MockCommandExecutor{err: fmt.Errorf("404 Not Found")}
+
+	wrapper := github.NewGHWrapper("owner/repo")
+	wrapper.SetExecutor(mockExecutor)
+
+	// Act
+	_, err := wrapper.GetFileContent(context.Background(), "deleted.go", "abc123")
+
+	// Assert
+	if err == nil {
+		t.Error("expected error when file is missing")
+	}
+}
+
+func TestGHWrapper_GetFileContent_NotFound(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		notFound bool
+	}{
+		{"missing file", fmt.Errorf("gh: Not Found (HTTP 404)"), true},
+		{"rate limited", fmt.Errorf("gh: API rate limit exceeded (HTTP 403)"), false},
+		{"network error", fmt.Errorf("dial tcp: lookup api.github.com: no such host"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			wrapper := github.NewGHWrapper("owner/repo")
+			wrapper.SetExecutor(&MockCommandExecutor{err: tt.err})
+
+			// Act
+			_, err := wrapper.GetFileContent(context.Background(), "CODEOWNERS", "abc123")
+
+			// Assert
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, github.ErrNotFound) != tt.notFound {
+				t.Errorf("expected errors.Is(err, ErrNotFound) to be %v, got %v", tt.notFound, err)
+			}
+		})
+	}
+}
+
+func TestGHWrapper_ListFiles_Success(t *testing.T) {
+	// Arrange
+	mockExecutor := &MockCommandExecutor{
+		output: "go.mod\nservices/payment/go.mod\nservices/payment/main.go\n\n",
+	}
+
+	wrapper := github.NewGHWrapper("owner/repo")
+	wrapper.SetExecutor(mockExecutor)
+
+	// Act
+	files, err := wrapper.ListFiles(context.Background(), "")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedFiles := []string{"go.mod", "services/payment/go.mod", "services/payment/main.go"}
+	if !equalStringSlices(files, expectedFiles) {
+		t.Errorf("expected files %v, got %v", expectedFiles, files)
+	}
+
+	expectedArgs := []string{
+		"api", "repos/owner/repo/git/trees/HEAD?recursive=1",
+		"--jq", `.tree[] | select(.type == "blob") | .path`,
+	}
+	if !equalStringSlices(mockExecutor.lastArgs, expectedArgs) {
+		t.Errorf("expected args %v, got %v", expectedArgs, mockExecutor.lastArgs)
+	}
+}
+
+func TestGHWrapper_ListFiles_CommandError(t *testing.T) {
+	// Arrange
+	mockExecutor := &MockCommandExecutor{err: fmt.Errorf("409 Git Repository is empty")}
+
+	wrapper := github.NewGHWrapper("owner/repo")
+	wrapper.SetExecutor(mockExecutor)
+
+	// Act
+	_, err := wrapper.ListFiles(context.Background(), "abc123")
+
+	// Assert
+	if err == nil {
+		t.Error("expected error when listing files fails")
+	}
+}
+
 // MockCommandExecutor は外部コマンド実行をモックします
 type MockCommandExecutor struct {
 	output   string
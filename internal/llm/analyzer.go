@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// Analyzer はプロンプトを送ってLLMの出力を受け取る呼び出し先です
+//
+// Driver はリトライ・出力の修正・キャッシュを行い、1回分の呼び出しを Analyzer に任せます。
+// 再試行で解消する可能性のあるエラー（タイムアウトや一時的な障害）は retryable でマークして返します。
+type Analyzer interface {
+	Analyze(ctx context.Context, request Request) (*Response, error)
+}
+
+// Request はLLMへの1回分の呼び出しです
+type Request struct {
+	Prompt string
+	// Format は期待する応答の形式です（JSONモードに対応する呼び出し先で使用）
+	Format Format
+}
+
+// Response はLLMの1回分の応答です
+type Response struct {
+	Output []byte
+	// InputTokens と OutputTokens はAPIが返したトークン数です（返さない場合は推定値）
+	InputTokens  int
+	OutputTokens int
+	// Estimated はトークン数を推定したかどうかです
+	Estimated bool
+}
+
+// estimatedResponse はトークン数をプロンプトと出力から推定した応答を作成します
+func estimatedResponse(request Request, output []byte) *Response {
+	return &Response{
+		Output:       output,
+		InputTokens:  EstimateTokens(request.Prompt),
+		OutputTokens: EstimateTokens(string(output)),
+		Estimated:    true,
+	}
+}
+
+// Format はLLMに期待する応答のJSONの形式です
+type Format int
+
+const (
+	// FormatObject は1件の分析結果のJSONオブジェクトです
+	FormatObject Format = iota
+	// FormatArray はバッチ分析の結果のJSON配列です
+	FormatArray
+)
+
+// ドライバーの種類（llm.drivers.<name>.type）
+const (
+	DriverTypeCommand   = "command"
+	DriverTypeAnthropic = "anthropic"
+	DriverTypeOpenAI    = "openai"
+	DriverTypeOllama    = "ollama"
+)
+
+// NewAnalyzerFromConfig は設定ファイルのドライバー定義から呼び出し先を作成します
+//
+// type が未設定の場合はコマンドを実行します。コマンドの場合、model_flag が設定されていれば
+// "<model_flag> <model>" を引数の末尾に追加します。
+func NewAnalyzerFromConfig(cfg config.DriverConfig) (Analyzer, error) {
+	switch cfg.Type {
+	case "", DriverTypeCommand:
+		args := append([]string{}, cfg.Args...)
+		if cfg.ModelFlag != "" && cfg.Model != "" {
+			args = append(args, cfg.ModelFlag, cfg.Model)
+		}
+		analyzer := NewCommandAnalyzer(cfg.Command, args)
+		analyzer.timeout = cfg.Timeout
+		return analyzer, nil
+	case DriverTypeAnthropic:
+		return NewAnthropicAnalyzer(cfg)
+	case DriverTypeOpenAI:
+		return NewOpenAIAnalyzer(cfg)
+	case DriverTypeOllama:
+		return NewOllamaAnalyzer(cfg)
+	default:
+		return nil, fmt.Errorf("unknown driver type %q", cfg.Type)
+	}
+}
@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// Anthropic Messages API の既定値
+const (
+	DefaultAnthropicEndpoint  = "https://api.anthropic.com"
+	DefaultAnthropicAPIKeyEnv = "ANTHROPIC_API_KEY"
+	anthropicVersion          = "2023-06-01"
+)
+
+// AnthropicAnalyzer は Anthropic Messages API（/v1/messages）を呼び出します
+//
+// JSONモードでは応答の先頭（"{" か "["）をアシスタントのメッセージとして与え、
+// JSONだけを続けて出力させます。
+type AnthropicAnalyzer struct {
+	api *httpAPI
+}
+
+// NewAnthropicAnalyzer はドライバー定義から AnthropicAnalyzer を作成します
+func NewAnthropicAnalyzer(cfg config.DriverConfig) (*AnthropicAnalyzer, error) {
+	api, err := newHTTPAPI(cfg, DefaultAnthropicEndpoint, DefaultAnthropicAPIKeyEnv, true)
+	if err != nil {
+		return nil, err
+	}
+	if api.maxTokens <= 0 {
+		api.maxTokens = DefaultMaxTokens
+	}
+	return &AnthropicAnalyzer{api: api}, nil
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// settings はキャッシュキーに含める Anthropic API の設定を返します
+func (a *AnthropicAnalyzer) settings() string {
+	return DriverTypeAnthropic + " " + a.api.settings()
+}
+
+// Analyze はプロンプトをユーザーのメッセージとして送り、テキストの応答を返します
+func (a *AnthropicAnalyzer) Analyze(ctx context.Context, request Request) (*Response, error) {
+	messages := []anthropicMessage{{Role: "user", Content: request.Prompt}}
+	prefill := ""
+	if a.api.jsonMode {
+		prefill = "{"
+		if request.Format == FormatArray {
+			prefill = "["
+		}
+		messages = append(messages, anthropicMessage{Role: "assistant", Content: prefill})
+	}
+
+	headers := map[string]string{
+		"x-api-key":         a.api.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	body := anthropicRequest{
+		Model:       a.api.model,
+		MaxTokens:   a.api.maxTokens,
+		Temperature: a.api.temperature,
+		Messages:    messages,
+	}
+
+	var response anthropicResponse
+	if err := a.api.post(ctx, "/v1/messages", headers, body, &response); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	text.WriteString(prefill)
+	for _, content := range response.Content {
+		if content.Type == "text" {
+			text.WriteString(content.Text)
+		}
+	}
+	if text.Len() == len(prefill) {
+		return nil, retryable(fmt.Errorf("LLM API returned no text (stop_reason: %s)", response.StopReason))
+	}
+	return &Response{
+		Output:       []byte(text.String()),
+		InputTokens:  response.Usage.InputTokens,
+		OutputTokens: response.Usage.OutputTokens,
+	}, nil
+}
@@ -0,0 +1,235 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BatchItem はバッチ分析する1件のコメントです
+type BatchItem struct {
+	// ID は応答の結果とコメントを対応付けるIDです（バッチ内で一意）
+	ID string
+	// Context はバッチプロンプトに含めるコメントごとの情報です
+	Context string
+	// Prompt は応答に含まれなかった場合に単独で分析するときのプロンプトです
+	Prompt string
+}
+
+// BatchResult はバッチ分析の1件ごとの結果です
+type BatchResult struct {
+	ID     string
+	Result *AnalysisResult
+	// Driver は結果を生成したドライバーです（失敗した場合はnil）
+	Driver *Driver
+	// Single はバッチの応答に含まれず単独で分析した結果かどうかです
+	Single bool
+	Err    error
+}
+
+// batchEntry はバッチ分析の応答に含まれる1件の結果です
+type batchEntry struct {
+	ID string `json:"id"`
+	AnalysisResult
+}
+
+// BuildBatchPrompt は共通の指示と複数のコメントから1つのプロンプトを作成します
+func BuildBatchPrompt(instructions string, items []BatchItem) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(instructions))
+	fmt.Fprintf(&b, "\n\nAnalyze each of the following %d code review comments. Each comment starts with a line \"### Comment <id>\".\n", len(items))
+	b.WriteString("Return a JSON array with exactly one object per comment, in the same order. Each object must have an \"id\" field with the comment's id in addition to the fields above.\n")
+	b.WriteString("Return only the JSON array, no other text.\n")
+	for _, item := range items {
+		fmt.Fprintf(&b, "\n### Comment %s\n%s\n", item.ID, strings.TrimSpace(item.Context))
+	}
+	return b.String()
+}
+
+// AnalyzeBatch は複数のコメントを1回の呼び出しで分析します
+//
+// 結果は items の順に返します。キャッシュが設定されている場合、単独のプロンプトに対応する
+// 結果があるコメントはバッチに含めず、得られた結果は単独のプロンプトに対応付けて保存します
+// （バッチの組み合わせが変わってもキャッシュを使えるようにするため）。
+// 応答に含まれなかったコメント（バッチ全体の失敗を含む）は単独のプロンプトで1件ずつ分析し直します。
+// 分析するコメントが1件の場合は最初から単独で分析します。
+func (d *Driver) AnalyzeBatch(ctx context.Context, instructions string, items []BatchItem) []BatchResult {
+	results := make([]BatchResult, len(items))
+	var pending []int
+	for i, item := range items {
+		results[i].ID = item.ID
+		if result := d.cachedResult(ctx, item.Prompt); result != nil {
+			results[i].Result, results[i].Driver = result, d
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	var batchErr error
+	if len(pending) > 1 {
+		batch := make([]BatchItem, len(pending))
+		for j, i := range pending {
+			batch[j] = items[i]
+		}
+		var entries map[string]*AnalysisResult
+		entries, batchErr = d.analyzeBatch(ctx, BuildBatchPrompt(instructions, batch), batch)
+		for _, i := range pending {
+			if result, ok := entries[items[i].ID]; ok {
+				results[i].Result, results[i].Driver = result, d
+				d.storeResult(ctx, items[i].Prompt, result)
+			}
+		}
+	}
+
+	for _, i := range pending {
+		if results[i].Result != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			results[i].Err = errors.Join(batchErr, err)
+			continue
+		}
+		if items[i].Prompt == "" {
+			results[i].Err = fmt.Errorf("prompt cannot be empty")
+			continue
+		}
+
+		result, err := d.analyzeComment(ctx, items[i].Prompt)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		d.storeResult(ctx, items[i].Prompt, result)
+		results[i].Result, results[i].Driver, results[i].Single = result, d, len(pending) > 1
+	}
+	return results
+}
+
+// analyzeBatch はバッチプロンプトを実行し、IDごとの結果を返します
+//
+// 一部のコメントしか含まない応答は成功として扱い、含まれたものだけを返します。
+// 呼び出しの使用量（再試行を含む）は返した結果に均等に割り当てます。
+func (d *Driver) analyzeBatch(ctx context.Context, prompt string, items []BatchItem) (map[string]*AnalysisResult, error) {
+	var entries map[string]*AnalysisResult
+	var usage Usage
+	err := d.retry.Do(ctx, func(attempt int) error {
+		output, err := d.call(ctx, Request{Prompt: prompt, Format: FormatArray}, &usage)
+		if err != nil {
+			return err
+		}
+		if len(output) == 0 {
+			return retryable(fmt.Errorf("LLM returned empty response"))
+		}
+
+		entries, err = parseBatchOutput(output, items)
+		return err
+	}, func(attempt int, delay time.Duration, err error) {
+		if d.onRetry != nil {
+			d.onRetry(d, attempt, d.retry.attempts(), delay, err)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 呼び出しの使用量は結果が得られたコメントで均等に分ける
+	var answered []*AnalysisResult
+	for _, item := range items {
+		if result, ok := entries[item.ID]; ok {
+			answered = append(answered, result)
+		}
+	}
+	for i, part := range usage.Split(len(answered)) {
+		answered[i].Usage = part
+	}
+	return entries, nil
+}
+
+// parseBatchOutput はバッチ分析の応答をパースし、IDごとの結果を返します
+//
+// 未知のIDや重複したIDの結果は無視します（重複時は先の結果を採用）。スキーマに違反する結果は
+// 含めず、単独での分析（違反時は修正を依頼）に回します。
+func parseBatchOutput(output []byte, items []BatchItem) (map[string]*AnalysisResult, error) {
+	var entries []batchEntry
+	if err := json.Unmarshal(extractJSONArray(output), &entries); err != nil {
+		return nil, retryable(fmt.Errorf("failed to parse LLM batch output: %w", err))
+	}
+
+	known := make(map[string]bool, len(items))
+	for _, item := range items {
+		known[item.ID] = true
+	}
+
+	results := make(map[string]*AnalysisResult, len(entries))
+	answered := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		id := strings.TrimSpace(entry.ID)
+		if !known[id] || answered[id] {
+			continue
+		}
+		answered[id] = true
+
+		result := entry.AnalysisResult
+		if result.Validate() != nil {
+			continue
+		}
+		results[id] = &result
+	}
+	if len(answered) == 0 {
+		return nil, retryable(fmt.Errorf("LLM batch output contains no results for the requested comments"))
+	}
+	return results, nil
+}
+
+// AnalyzeBatch は先頭のドライバーから順にバッチ分析し、失敗したコメントだけを次のドライバーで分析します
+func (c *Chain) AnalyzeBatch(ctx context.Context, instructions string, items []BatchItem) []BatchResult {
+	results := make([]BatchResult, len(items))
+	pending := make([]int, len(items))
+	errs := make([][]error, len(items))
+	for i, item := range items {
+		results[i].ID = item.ID
+		pending[i] = i
+	}
+	if len(c.drivers) == 0 {
+		for i := range results {
+			results[i].Err = fmt.Errorf("no LLM drivers configured")
+		}
+		return results
+	}
+
+	for d, driver := range c.drivers {
+		batch := make([]BatchItem, len(pending))
+		for j, i := range pending {
+			batch[j] = items[i]
+		}
+
+		var failed []int
+		var lastErr error
+		for j, result := range driver.AnalyzeBatch(ctx, instructions, batch) {
+			i := pending[j]
+			if result.Err == nil {
+				results[i] = result
+				continue
+			}
+			errs[i] = append(errs[i], fmt.Errorf("%s: %w", driver.Name(), result.Err))
+			failed = append(failed, i)
+			lastErr = result.Err
+		}
+
+		pending = failed
+		if len(pending) == 0 || ctx.Err() != nil {
+			break
+		}
+		if c.onFallback != nil && d < len(c.drivers)-1 {
+			c.onFallback(driver, lastErr)
+		}
+	}
+
+	for _, i := range pending {
+		results[i].Err = errors.Join(errs[i]...)
+	}
+	return results
+}
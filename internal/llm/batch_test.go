@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// promptExecutor はプロンプトに応じて出力を返すモックです
+type promptExecutor struct {
+	respond func(input string) (string, error)
+	inputs  []string
+}
+
+func (p *promptExecutor) Execute(ctx context.Context, cmd string, args []string, input string) ([]byte, error) {
+	p.inputs = append(p.inputs, input)
+	output, err := p.respond(input)
+	return []byte(output), err
+}
+
+func batchItems() []BatchItem {
+	return []BatchItem{
+		{ID: "c1", Context: "Comment: check nil", Prompt: "single c1"},
+		{ID: "c2", Context: "Comment: add test", Prompt: "single c2"},
+		{ID: "c3", Context: "Comment: rename var", Prompt: "single c3"},
+	}
+}
+
+func TestBuildBatchPrompt(t *testing.T) {
+	// Act
+	prompt := BuildBatchPrompt("Shared instructions", batchItems())
+
+	// Assert
+	if strings.Count(prompt, "Shared instructions") != 1 {
+		t.Errorf("expected the instructions to be sent once:\n%s", prompt)
+	}
+	for _, want := range []string{"3 code review comments", "### Comment c1\nComment: check nil", "### Comment c3\nComment: rename var", "JSON array"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected prompt to contain %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestAnalyzeBatch_MapsResultsByID(t *testing.T) {
+	// Arrange
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		return "Here you go:\n```json\n" + `[
+			{"id": "c3", "summary": "s3", "type": "maintenance"},
+			{"id": "c9", "summary": "unknown", "type": "bug"},
+			{"id": "c1", "summary": "s1", "type": "bug", "tags": ["nil"]},
+			{"id": "c2", "summary": "s2", "type": "testing"},
+			{"id": "c1", "summary": "duplicate", "type": "noise"}
+		]` + "\n```", nil
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+
+	// Act
+	results := driver.AnalyzeBatch(context.Background(), "instructions", batchItems())
+
+	// Assert
+	if len(executor.inputs) != 1 {
+		t.Fatalf("expected a single LLM call, got %d", len(executor.inputs))
+	}
+	want := []string{"s1", "s2", "s3"}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("unexpected error for %s: %v", result.ID, result.Err)
+		}
+		if result.ID != batchItems()[i].ID || result.Result.Summary != want[i] || result.Single || result.Driver != driver {
+			t.Errorf("unexpected result %d: %+v", i, result)
+		}
+	}
+	if len(results[0].Result.Tags) != 1 || results[0].Result.Tags[0] != "nil" {
+		t.Errorf("expected tags of c1, got %v", results[0].Result.Tags)
+	}
+}
+
+func TestAnalyzeBatch_FallsBackToSingleCallsForMissingItems(t *testing.T) {
+	// Arrange
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		switch input {
+		case "single c2":
+			return `{"summary": "s2 single", "type": "testing"}`, nil
+		case "single c3":
+			return "", errors.New("not installed")
+		case "single c1":
+			return "", errors.New("c1 must not be analyzed again")
+		}
+		return `[{"id": "c1", "summary": "s1", "type": "bug"}]`, nil
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+
+	// Act
+	results := driver.AnalyzeBatch(context.Background(), "instructions", batchItems())
+
+	// Assert
+	if len(executor.inputs) != 3 {
+		t.Errorf("expected 1 batch call and 2 single calls, got %v", executor.inputs)
+	}
+	if results[0].Err != nil || results[0].Result.Summary != "s1" || results[0].Single {
+		t.Errorf("expected c1 from the batch, got %+v", results[0])
+	}
+	if results[1].Err != nil || results[1].Result.Summary != "s2 single" || !results[1].Single {
+		t.Errorf("expected c2 from a single call, got %+v", results[1])
+	}
+	if results[2].Err == nil || results[2].Driver != nil {
+		t.Errorf("expected c3 to fail, got %+v", results[2])
+	}
+}
+
+func TestAnalyzeBatch_UnparseableOutputFallsBackToSingleCalls(t *testing.T) {
+	// Arrange
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		if strings.HasPrefix(input, "single ") {
+			return `{"summary": "` + input + `", "type": "bug"}`, nil
+		}
+		return "Sorry, I can only analyze one comment at a time.", nil
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+
+	// Act
+	results := driver.AnalyzeBatch(context.Background(), "instructions", batchItems())
+
+	// Assert
+	for i, result := range results {
+		if result.Err != nil || result.Result.Summary != batchItems()[i].Prompt || !result.Single {
+			t.Errorf("expected %s from a single call, got %+v", batchItems()[i].ID, result)
+		}
+	}
+}
+
+func TestChain_AnalyzeBatch_FallsBackForFailedItems(t *testing.T) {
+	// Arrange
+	primary := newConfiguredDriver(t, "claude", config.DriverConfig{Command: "claude"})
+	primary.SetExecutor(&promptExecutor{respond: func(input string) (string, error) {
+		if strings.HasPrefix(input, "single ") {
+			return "", errors.New("rate limited")
+		}
+		return `[{"id": "c1", "summary": "s1", "type": "bug"}, {"id": "c3", "summary": "s3", "type": "bug"}]`, nil
+	}})
+	secondary := &promptExecutor{respond: func(input string) (string, error) {
+		return `{"summary": "from gemini", "type": "testing"}`, nil
+	}}
+	fallback := newConfiguredDriver(t, "gemini", config.DriverConfig{Command: "gemini"})
+	fallback.SetExecutor(secondary)
+
+	chain := NewChain(primary, fallback)
+	var failed []string
+	chain.SetFallbackHandler(func(driver *Driver, err error) {
+		failed = append(failed, driver.Name())
+	})
+
+	// Act
+	results := chain.AnalyzeBatch(context.Background(), "instructions", batchItems())
+
+	// Assert
+	if results[0].Driver != primary || results[2].Driver != primary {
+		t.Errorf("expected c1 and c3 from claude, got %+v", results)
+	}
+	if results[1].Err != nil || results[1].Driver != fallback || results[1].Result.Summary != "from gemini" {
+		t.Errorf("expected c2 from gemini, got %+v", results[1])
+	}
+	if len(secondary.inputs) != 1 || secondary.inputs[0] != "single c2" {
+		t.Errorf("expected only c2 to be sent to gemini, got %v", secondary.inputs)
+	}
+	if len(failed) != 1 || failed[0] != "claude" {
+		t.Errorf("expected a fallback notification for claude, got %v", failed)
+	}
+}
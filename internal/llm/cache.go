@@ -0,0 +1,290 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache はLLMの分析結果をプロンプト・ドライバー・モデルごとに保存するキャッシュです
+//
+// キャッシュの読み書きに失敗しても分析は続けるため、Driver はエラーを無視します。
+// エラーの記録は実装側で行います。
+type Cache interface {
+	// Get はキーに対応する分析結果を返します（見つからない場合や期限切れの場合は nil）
+	Get(ctx context.Context, key string) (*AnalysisResult, error)
+	// Put はドライバーの分析結果をキーに対応付けて保存します
+	Put(ctx context.Context, key string, driver *Driver, result *AnalysisResult) error
+}
+
+// SetCache は分析結果のキャッシュを設定します（nil でキャッシュしない）
+func (d *Driver) SetCache(cache Cache) {
+	d.cache = cache
+}
+
+// settingsDescriber は結果に影響する呼び出し先の設定を返す Analyzer です
+//
+// 設定を変更したときに以前の設定の結果を使わないよう、キャッシュキーに含めます。
+type settingsDescriber interface {
+	settings() string
+}
+
+// cacheKey はドライバー名・モデル名・呼び出し先の設定・プロンプトから内容に基づくキャッシュキーを作成します
+func (d *Driver) cacheKey(prompt string) string {
+	var settings string
+	if describer, ok := d.analyzer.(settingsDescriber); ok {
+		settings = describer.settings()
+	}
+	hash := sha256.New()
+	for _, part := range []string{d.name, d.model, settings, prompt} {
+		fmt.Fprintf(hash, "%d:%s\n", len(part), part)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// cachedResult はプロンプトに対応するキャッシュ済みの分析結果を返します
+//
+// 現在のスキーマに違反する結果（分類体系の変更前に保存されたものなど）は使いません。
+func (d *Driver) cachedResult(ctx context.Context, prompt string) *AnalysisResult {
+	if d.cache == nil {
+		return nil
+	}
+	result, err := d.cache.Get(ctx, d.cacheKey(prompt))
+	if err != nil || result == nil || result.Validate() != nil {
+		return nil
+	}
+	result.Cached = true
+	return result
+}
+
+// storeResult は分析結果をプロンプトに対応付けてキャッシュします
+//
+// 得られた結果は中断後の再実行でも使えるよう、キャンセルされていても保存します。
+func (d *Driver) storeResult(ctx context.Context, prompt string, result *AnalysisResult) {
+	if d.cache == nil {
+		return
+	}
+	_ = d.cache.Put(context.WithoutCancel(ctx), d.cacheKey(prompt), d, result)
+}
+
+// CacheStats はキャッシュの利用状況です
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	// Errors はデータベースの読み書きに失敗した回数です
+	Errors int64
+}
+
+// SQLCache はSQLiteの llm_cache テーブルに分析結果を保存するキャッシュです
+//
+// テーブルは database.Migrate で作成します。複数のワーカーから並行に使用できます。
+// SetDeferWrites を設定すると、ワーカーからは読み込みだけを行い、書き込みは Flush を
+// 呼んだgoroutineでまとめて行います（SQLiteへの書き込みを1つのgoroutineに限るため）。
+type SQLCache struct {
+	db          *sql.DB
+	ttl         time.Duration
+	refresh     bool
+	deferWrites bool
+	now         func() time.Time
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+
+	mu      sync.Mutex
+	lastErr error
+	pending []cacheWrite
+}
+
+// cacheWrite は Flush まで保留している書き込みです
+type cacheWrite struct {
+	query string
+	args  []interface{}
+	// description は失敗したときのエラーメッセージに使う書き込みの説明です
+	description string
+}
+
+// NewSQLCache は有効期間 ttl のキャッシュを作成します（0以下の場合は期限なし）
+func NewSQLCache(db *sql.DB, ttl time.Duration) *SQLCache {
+	return &SQLCache{db: db, ttl: ttl, now: time.Now}
+}
+
+// SetRefresh は保存済みの結果を使わずに分析し直すかどうかを設定します（新しい結果で上書きする）
+func (c *SQLCache) SetRefresh(refresh bool) {
+	c.refresh = refresh
+}
+
+// SetDeferWrites は書き込み（結果の保存とヒット回数の記録）を Flush まで保留するかどうかを設定します
+//
+// 保留中の結果は Get で返しません。
+func (c *SQLCache) SetDeferWrites(deferWrites bool) {
+	c.deferWrites = deferWrites
+}
+
+// SetClock は現在時刻の取得方法を設定します（テスト用）
+func (c *SQLCache) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+// Get はキーに対応する有効期間内の分析結果を返し、ヒット回数を記録します
+func (c *SQLCache) Get(ctx context.Context, key string) (*AnalysisResult, error) {
+	if c.refresh {
+		c.misses.Add(1)
+		return nil, nil
+	}
+
+	var data string
+	var repairs int
+	var createdAt time.Time
+	query := `SELECT result, repair_count, created_at FROM llm_cache WHERE cache_key = ?`
+	err := c.db.QueryRowContext(ctx, query, key).Scan(&data, &repairs, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && c.expired(createdAt)) {
+		c.misses.Add(1)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, c.fail(fmt.Errorf("failed to read LLM cache: %w", err))
+	}
+
+	var result AnalysisResult
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		c.misses.Add(1)
+		return nil, c.fail(fmt.Errorf("failed to decode LLM cache entry: %w", err))
+	}
+	result.Repairs = repairs
+
+	update := cacheWrite{
+		query:       `UPDATE llm_cache SET hit_count = hit_count + 1, last_hit_at = ? WHERE cache_key = ?`,
+		args:        []interface{}{c.timestamp(), key},
+		description: "record LLM cache hit",
+	}
+	c.write(ctx, update)
+	c.hits.Add(1)
+	return &result, nil
+}
+
+// Put は分析結果を保存します（同じキーの結果は上書きし、有効期間を新しくする）
+func (c *SQLCache) Put(ctx context.Context, key string, driver *Driver, result *AnalysisResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to encode LLM cache entry: %w", err))
+	}
+
+	put := cacheWrite{
+		query: `
+		INSERT INTO llm_cache (cache_key, driver, model, result, repair_count, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET
+			result = excluded.result,
+			repair_count = excluded.repair_count,
+			created_at = excluded.created_at,
+			hit_count = 0,
+			last_hit_at = NULL`,
+		args:        []interface{}{key, driver.Name(), driver.Model(), string(data), result.Repairs, c.timestamp()},
+		description: "write LLM cache",
+	}
+	return c.write(ctx, put)
+}
+
+// write は書き込みを実行します（SetDeferWrites が設定されている場合は Flush まで保留する）
+func (c *SQLCache) write(ctx context.Context, w cacheWrite) error {
+	if c.deferWrites {
+		c.mu.Lock()
+		c.pending = append(c.pending, w)
+		c.mu.Unlock()
+		return nil
+	}
+	if _, err := c.db.ExecContext(ctx, w.query, w.args...); err != nil {
+		return c.fail(fmt.Errorf("failed to %s: %w", w.description, err))
+	}
+	return nil
+}
+
+// Flush は保留中の書き込みを1つのトランザクションで実行します
+//
+// 失敗した場合、保留中の書き込みは破棄します（キャッシュの失敗は分析を止めないため）。
+func (c *SQLCache) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return c.fail(fmt.Errorf("failed to begin LLM cache transaction: %w", err))
+	}
+	defer tx.Rollback()
+	for _, w := range pending {
+		if _, err := tx.ExecContext(ctx, w.query, w.args...); err != nil {
+			return c.fail(fmt.Errorf("failed to %s: %w", w.description, err))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return c.fail(fmt.Errorf("failed to commit LLM cache: %w", err))
+	}
+	return nil
+}
+
+// Prune は有効期間を過ぎた結果を削除し、削除した件数を返します
+func (c *SQLCache) Prune(ctx context.Context) (int64, error) {
+	if c.ttl <= 0 {
+		return 0, nil
+	}
+	res, err := c.db.ExecContext(ctx, `DELETE FROM llm_cache WHERE created_at < ?`, c.timestamp().Add(-c.ttl))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune LLM cache: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Clear は保存済みの全ての結果を削除し、削除した件数を返します
+func (c *SQLCache) Clear(ctx context.Context) (int64, error) {
+	res, err := c.db.ExecContext(ctx, `DELETE FROM llm_cache`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear LLM cache: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Stats はこれまでのヒット・ミス・エラーの回数を返します
+func (c *SQLCache) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Errors: c.errors.Load()}
+}
+
+// LastError は最後に発生したエラーを返します（発生していない場合は nil）
+func (c *SQLCache) LastError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastErr
+}
+
+// expired は保存時刻が有効期間を過ぎているかどうかを返します
+func (c *SQLCache) expired(createdAt time.Time) bool {
+	return c.ttl > 0 && createdAt.Before(c.timestamp().Add(-c.ttl))
+}
+
+// timestamp は保存時刻として使う現在時刻を返します
+//
+// 期限切れの削除を文字列の比較で行えるよう、UTCの秒単位に揃えます。
+func (c *SQLCache) timestamp() time.Time {
+	return c.now().UTC().Truncate(time.Second)
+}
+
+// fail はエラーを記録して返します
+func (c *SQLCache) fail(err error) error {
+	c.errors.Add(1)
+	c.mu.Lock()
+	c.lastErr = err
+	c.mu.Unlock()
+	return err
+}
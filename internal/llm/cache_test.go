@@ -0,0 +1,289 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database/dbtest"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+func newTestCache(t *testing.T, ttl time.Duration) *SQLCache {
+	t.Helper()
+	db := dbtest.New(t)
+	return NewSQLCache(db, ttl)
+}
+
+func countingExecutor() *promptExecutor {
+	return &promptExecutor{respond: func(input string) (string, error) {
+		return `{"summary": "Check errors.", "type": "bug", "relevance_score": 0.8}`, nil
+	}}
+}
+
+func TestAnalyzeComment_UsesCachedResult(t *testing.T) {
+	// Arrange
+	cache := newTestCache(t, time.Hour)
+	executor := countingExecutor()
+	driver := newConfiguredDriver(t, "claude", config.DriverConfig{Command: "claude", Model: "sonnet"})
+	driver.SetExecutor(executor)
+	driver.SetCache(cache)
+
+	// Act
+	first, err := driver.AnalyzeComment(context.Background(), "analyze this")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := driver.AnalyzeComment(context.Background(), "analyze this")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	if len(executor.inputs) != 1 {
+		t.Errorf("expected 1 LLM call, got %d", len(executor.inputs))
+	}
+	if first.Cached || !second.Cached || second.Summary != first.Summary || second.Type != "bug" {
+		t.Errorf("expected the second result to come from the cache, got %+v and %+v", first, second)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Errors != 0 {
+		t.Errorf("unexpected cache stats: %+v", stats)
+	}
+}
+
+func TestAnalyzeComment_CacheKeyIncludesDriverAndModel(t *testing.T) {
+	// Arrange
+	cache := newTestCache(t, time.Hour)
+	executor := countingExecutor()
+	drivers := []*Driver{
+		newConfiguredDriver(t, "claude", config.DriverConfig{Command: "claude", Model: "sonnet"}),
+		newConfiguredDriver(t, "claude", config.DriverConfig{Command: "claude", Model: "opus"}),
+		newConfiguredDriver(t, "gemini", config.DriverConfig{Command: "claude", Model: "sonnet"}),
+	}
+
+	// Act
+	for _, driver := range drivers {
+		driver.SetExecutor(executor)
+		driver.SetCache(cache)
+		if _, err := driver.AnalyzeComment(context.Background(), "analyze this"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Assert
+	if len(executor.inputs) != 3 {
+		t.Errorf("expected each driver and model to call the LLM, got %d calls", len(executor.inputs))
+	}
+}
+
+func TestCacheKey_IncludesDriverSettings(t *testing.T) {
+	// Arrange
+	temperature := 0.2
+	base := config.DriverConfig{Type: DriverTypeOpenAI, Model: "gpt", Endpoint: "http://localhost:8080/v1"}
+	withTemperature := base
+	withTemperature.Temperature = &temperature
+	withEndpoint := base
+	withEndpoint.Endpoint = "http://localhost:9090/v1"
+
+	tests := []struct {
+		name  string
+		left  config.DriverConfig
+		right config.DriverConfig
+		same  bool
+	}{
+		{"same config", base, base, true},
+		{"temperature", base, withTemperature, false},
+		{"endpoint", base, withEndpoint, false},
+		{"command args", config.DriverConfig{Command: "claude", Args: []string{"-p"}}, config.DriverConfig{Command: "claude", Args: []string{"-p", "--model", "opus"}}, false},
+		{"command", config.DriverConfig{Command: "claude"}, config.DriverConfig{Command: "/opt/claude"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			left := newConfiguredDriver(t, "llm", tt.left).cacheKey("analyze this")
+			right := newConfiguredDriver(t, "llm", tt.right).cacheKey("analyze this")
+
+			// Assert
+			if (left == right) != tt.same {
+				t.Errorf("expected same key = %t, got %s and %s", tt.same, left, right)
+			}
+		})
+	}
+}
+
+func TestSQLCache_DeferWritesUntilFlush(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+	cache := NewSQLCache(db, time.Hour)
+	cache.SetDeferWrites(true)
+	executor := countingExecutor()
+	driver := newConfiguredDriver(t, "claude", config.DriverConfig{Command: "claude", Model: "sonnet"})
+	driver.SetExecutor(executor)
+	driver.SetCache(cache)
+	count := func() (entries, hits int) {
+		t.Helper()
+		if err := db.QueryRow("SELECT COUNT(*), COALESCE(SUM(hit_count), 0) FROM llm_cache").Scan(&entries, &hits); err != nil {
+			t.Fatalf("failed to query cache: %v", err)
+		}
+		return entries, hits
+	}
+
+	// Act
+	if _, err := driver.AnalyzeComment(context.Background(), "analyze this"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entriesBefore, _ := count()
+	if err := cache.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := driver.AnalyzeComment(context.Background(), "analyze this")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, hitsBefore := count()
+	if err := cache.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	if entriesBefore != 0 || hitsBefore != 0 {
+		t.Errorf("expected nothing to be written before Flush, got %d entries and %d hits", entriesBefore, hitsBefore)
+	}
+	if !second.Cached || len(executor.inputs) != 1 {
+		t.Errorf("expected the flushed result to be served from the cache, got %+v after %d calls", second, len(executor.inputs))
+	}
+	if entries, hits := count(); entries != 1 || hits != 1 {
+		t.Errorf("expected 1 entry with 1 hit after Flush, got %d entries and %d hits", entries, hits)
+	}
+}
+
+func TestSQLCache_ExpiresAfterTTL(t *testing.T) {
+	// Arrange
+	cache := newTestCache(t, time.Hour)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.SetClock(func() time.Time { return now })
+	executor := countingExecutor()
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetCache(cache)
+	if _, err := driver.AnalyzeComment(context.Background(), "analyze this"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	now = now.Add(2 * time.Hour)
+	pruned, err := cache.Prune(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("expected 1 expired entry to be pruned, got %d", pruned)
+	}
+	result, err := driver.AnalyzeComment(context.Background(), "analyze this")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Cached || len(executor.inputs) != 2 {
+		t.Errorf("expected the expired result to be analyzed again, got %+v after %d calls", result, len(executor.inputs))
+	}
+}
+
+func TestSQLCache_RefreshOverwritesCachedResult(t *testing.T) {
+	// Arrange
+	cache := newTestCache(t, time.Hour)
+	summary := "old summary"
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		return `{"summary": "` + summary + `", "type": "bug"}`, nil
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetCache(cache)
+	if _, err := driver.AnalyzeComment(context.Background(), "analyze this"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	summary = "new summary"
+	cache.SetRefresh(true)
+	if _, err := driver.AnalyzeComment(context.Background(), "analyze this"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache.SetRefresh(false)
+	result, err := driver.AnalyzeComment(context.Background(), "analyze this")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Cached || result.Summary != "new summary" || len(executor.inputs) != 2 {
+		t.Errorf("expected the refreshed result from the cache, got %+v after %d calls", result, len(executor.inputs))
+	}
+}
+
+func TestSQLCache_ClearRemovesAllEntries(t *testing.T) {
+	// Arrange
+	cache := newTestCache(t, 0)
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(countingExecutor())
+	driver.SetCache(cache)
+	for _, prompt := range []string{"first", "second"} {
+		if _, err := driver.AnalyzeComment(context.Background(), prompt); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Act
+	cleared, err := cache.Clear(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cleared != 2 {
+		t.Errorf("expected 2 entries to be cleared, got %d", cleared)
+	}
+}
+
+func TestAnalyzeBatch_SkipsCachedItems(t *testing.T) {
+	// Arrange
+	cache := newTestCache(t, time.Hour)
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		if strings.HasPrefix(input, "single") {
+			return `{"summary": "single", "type": "bug"}`, nil
+		}
+		return `[
+			{"id": "c2", "summary": "s2", "type": "testing"},
+			{"id": "c3", "summary": "s3", "type": "maintenance"}
+		]`, nil
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetCache(cache)
+	if _, err := driver.AnalyzeComment(context.Background(), "single c1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act
+	results := driver.AnalyzeBatch(context.Background(), "instructions", batchItems())
+	again := driver.AnalyzeBatch(context.Background(), "instructions", batchItems())
+
+	// Assert
+	if !results[0].Result.Cached || results[1].Result.Cached || results[2].Result.Cached {
+		t.Errorf("expected only c1 to come from the cache, got %+v", results)
+	}
+	if len(executor.inputs) != 2 || strings.Contains(executor.inputs[1], "### Comment c1") {
+		t.Errorf("expected a batch call without c1, got %q", executor.inputs)
+	}
+	for _, result := range again {
+		if result.Err != nil || !result.Result.Cached {
+			t.Errorf("expected every comment to come from the cache on the second run, got %+v", result)
+		}
+	}
+	if len(executor.inputs) != 2 {
+		t.Errorf("expected no LLM calls on the second run, got %d calls in total", len(executor.inputs))
+	}
+}
@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// Chain は失敗時に次のドライバーへフォールバックするドライバーの列です
+type Chain struct {
+	drivers    []*Driver
+	onFallback func(failed *Driver, err error)
+}
+
+// NewChain は試す順にドライバーを並べたChainを作成します
+func NewChain(drivers ...*Driver) *Chain {
+	return &Chain{drivers: drivers}
+}
+
+// NewChainFromConfig はLLM設定のprimaryとfallbackからChainを作成します
+//
+// 各ドライバーには llm.retry のリトライ設定と llm.max_repairs の修正回数を適用します。
+func NewChainFromConfig(cfg config.LLMConfig) (*Chain, error) {
+	var drivers []*Driver
+	for _, name := range cfg.DriverChain() {
+		driverConfig, ok := cfg.Drivers[name]
+		if !ok {
+			return nil, fmt.Errorf("llm driver %q is not defined", name)
+		}
+		driver, err := NewDriverFromConfig(name, driverConfig)
+		if err != nil {
+			return nil, err
+		}
+		driver.SetRetryPolicy(NewRetryPolicy(cfg.Retry))
+		if cfg.MaxRepairs > 0 {
+			driver.SetMaxRepairs(cfg.MaxRepairs)
+		}
+		drivers = append(drivers, driver)
+	}
+	return NewChain(drivers...), nil
+}
+
+// SetFallbackHandler はドライバーが失敗して次のドライバーへ切り替えるときの通知先を設定します
+func (c *Chain) SetFallbackHandler(handler func(failed *Driver, err error)) {
+	c.onFallback = handler
+}
+
+// SetRetryHandler は全てのドライバーに再試行の通知先を設定します
+func (c *Chain) SetRetryHandler(handler RetryHandler) {
+	for _, driver := range c.drivers {
+		driver.SetRetryHandler(handler)
+	}
+}
+
+// SetRepairHandler は全てのドライバーに出力の修正を依頼するときの通知先を設定します
+func (c *Chain) SetRepairHandler(handler RepairHandler) {
+	for _, driver := range c.drivers {
+		driver.SetRepairHandler(handler)
+	}
+}
+
+// SetUsageHandler は全てのドライバーにLLMを呼び出すたびの使用量の通知先を設定します
+func (c *Chain) SetUsageHandler(handler UsageHandler) {
+	for _, driver := range c.drivers {
+		driver.SetUsageHandler(handler)
+	}
+}
+
+// SetCache は全てのドライバーに分析結果のキャッシュを設定します
+func (c *Chain) SetCache(cache Cache) {
+	for _, driver := range c.drivers {
+		driver.SetCache(cache)
+	}
+}
+
+// Drivers は試す順のドライバーを返します
+func (c *Chain) Drivers() []*Driver {
+	return c.drivers
+}
+
+// AnalyzeComment は先頭のドライバーから順に分析し、最初に成功した結果とドライバーを返します
+//
+// 全てのドライバーが失敗した場合は各ドライバーのエラーをまとめて返します。
+func (c *Chain) AnalyzeComment(ctx context.Context, prompt string) (*AnalysisResult, *Driver, error) {
+	if len(c.drivers) == 0 {
+		return nil, nil, fmt.Errorf("no LLM drivers configured")
+	}
+
+	var errs []error
+	for i, driver := range c.drivers {
+		result, err := driver.AnalyzeComment(ctx, prompt)
+		if err == nil {
+			return result, driver, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", driver.Name(), err))
+
+		if ctx.Err() != nil {
+			break
+		}
+		if c.onFallback != nil && i < len(c.drivers)-1 {
+			c.onFallback(driver, err)
+		}
+	}
+	return nil, nil, errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// recordingExecutor はコマンドと引数を記録するモックです
+type recordingExecutor struct {
+	output []byte
+	err    error
+	cmd    string
+	args   []string
+}
+
+func (r *recordingExecutor) Execute(ctx context.Context, cmd string, args []string, input string) ([]byte, error) {
+	r.cmd = cmd
+	r.args = args
+	return r.output, r.err
+}
+
+// newConfiguredDriver は設定からドライバーを作成します（作成に失敗した場合はテストを中断）
+func newConfiguredDriver(t *testing.T, name string, cfg config.DriverConfig) *Driver {
+	t.Helper()
+	driver, err := NewDriverFromConfig(name, cfg)
+	if err != nil {
+		t.Fatalf("failed to create driver: %v", err)
+	}
+	return driver
+}
+
+func TestNewDriverFromConfig(t *testing.T) {
+	// Arrange
+	executor := &recordingExecutor{output: []byte(`{"summary": "s", "type": "bug"}`)}
+
+	// Act
+	driver, err := NewDriverFromConfig("claude-sonnet", config.DriverConfig{
+		Command:   "claude",
+		Args:      []string{"-p"},
+		Model:     "sonnet",
+		ModelFlag: "--model",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	driver.SetExecutor(executor)
+	_, err = driver.AnalyzeComment(context.Background(), "prompt")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if driver.Name() != "claude-sonnet" || driver.Model() != "sonnet" {
+		t.Errorf("unexpected identity: %s / %s", driver.Name(), driver.Model())
+	}
+	if executor.cmd != "claude" || !reflect.DeepEqual(executor.args, []string{"-p", "--model", "sonnet"}) {
+		t.Errorf("unexpected command: %s %v", executor.cmd, executor.args)
+	}
+}
+
+func TestChain_FallsBackToNextDriver(t *testing.T) {
+	// Arrange
+	primary := newConfiguredDriver(t, "claude", config.DriverConfig{Command: "claude"})
+	primary.SetExecutor(&MockCommandExecutor{err: errors.New("rate limited")})
+	fallback := newConfiguredDriver(t, "gemini", config.DriverConfig{Command: "gemini", Model: "gemini-pro"})
+	fallback.SetExecutor(&MockCommandExecutor{output: []byte(`{"summary": "s", "type": "bug"}`)})
+
+	chain := NewChain(primary, fallback)
+	var failed []string
+	chain.SetFallbackHandler(func(driver *Driver, err error) {
+		failed = append(failed, driver.Name())
+	})
+
+	// Act
+	result, used, err := chain.AnalyzeComment(context.Background(), "prompt")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != "bug" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if used.Name() != "gemini" || used.Model() != "gemini-pro" {
+		t.Errorf("expected gemini to produce the result, got %s", used.Name())
+	}
+	if !reflect.DeepEqual(failed, []string{"claude"}) {
+		t.Errorf("expected fallback notification for claude, got %v", failed)
+	}
+}
+
+func TestChain_AllDriversFail(t *testing.T) {
+	// Arrange
+	first := newConfiguredDriver(t, "claude", config.DriverConfig{Command: "claude"})
+	first.SetExecutor(&MockCommandExecutor{err: errors.New("rate limited")})
+	second := newConfiguredDriver(t, "gemini", config.DriverConfig{Command: "gemini"})
+	second.SetExecutor(&MockCommandExecutor{err: errors.New("not installed")})
+
+	// Act
+	_, used, err := NewChain(first, second).AnalyzeComment(context.Background(), "prompt")
+
+	// Assert
+	if err == nil || used != nil {
+		t.Fatalf("expected error without a driver, got %v / %v", used, err)
+	}
+	if !strings.Contains(err.Error(), "claude") || !strings.Contains(err.Error(), "gemini") {
+		t.Errorf("expected errors of every driver, got %v", err)
+	}
+}
+
+func TestNewChainFromConfig(t *testing.T) {
+	// Arrange
+	cfg := config.LLMConfig{
+		Primary:  "claude",
+		Fallback: []string{"gemini"},
+		Drivers: map[string]config.DriverConfig{
+			"claude": {Command: "claude", Args: []string{"-p"}},
+			"gemini": {Command: "gemini"},
+		},
+	}
+
+	// Act
+	chain, err := NewChainFromConfig(cfg)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, driver := range chain.Drivers() {
+		names = append(names, driver.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"claude", "gemini"}) {
+		t.Errorf("expected [claude gemini], got %v", names)
+	}
+
+	cfg.Fallback = []string{"missing"}
+	if _, err := NewChainFromConfig(cfg); err == nil {
+		t.Error("expected error for an undefined fallback driver")
+	}
+}
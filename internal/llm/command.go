@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// CommandExecutor はLLMコマンドを実行するインターフェース
+type CommandExecutor interface {
+	Execute(ctx context.Context, cmd string, args []string, input string) ([]byte, error)
+}
+
+// DefaultCommandExecutor は実際のコマンドを実行します
+type DefaultCommandExecutor struct{}
+
+func (e *DefaultCommandExecutor) Execute(ctx context.Context, cmd string, args []string, input string) ([]byte, error) {
+	command := exec.CommandContext(ctx, cmd, args...)
+	command.Stdin = bytes.NewBufferString(input)
+	return command.Output()
+}
+
+// CommandAnalyzer はプロンプトを標準入力に渡してLLMのCLIを実行します
+type CommandAnalyzer struct {
+	command  string
+	args     []string
+	executor CommandExecutor
+	// timeout は1回の実行のタイムアウトです（0の場合は呼び出し元のコンテキストのみ）
+	timeout time.Duration
+}
+
+// NewCommandAnalyzer はコマンドと引数から CommandAnalyzer を作成します
+func NewCommandAnalyzer(command string, args []string) *CommandAnalyzer {
+	return &CommandAnalyzer{
+		command:  command,
+		args:     args,
+		executor: &DefaultCommandExecutor{},
+	}
+}
+
+// settings はコマンドと引数（モデルの指定を含む）を返します
+func (a *CommandAnalyzer) settings() string {
+	return fmt.Sprintf("command %q %q", a.command, a.args)
+}
+
+// Analyze はコマンドを1回実行して標準出力を返します
+//
+// CLIにはJSONモードがないため、応答の形式はプロンプトの指示に任せます。
+// トークン数は返さないため、プロンプトと出力から推定します。
+func (a *CommandAnalyzer) Analyze(ctx context.Context, request Request) (*Response, error) {
+	runCtx := ctx
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+
+	output, err := a.executor.Execute(runCtx, a.command, a.args, request.Prompt)
+	if err != nil {
+		err = fmt.Errorf("LLM command failed: %w", err)
+		if isTransientCommandError(ctx, err) {
+			return nil, retryable(err)
+		}
+		return nil, err
+	}
+	return estimatedResponse(request, output), nil
+}
@@ -1,13 +1,13 @@
 package llm
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
-	"regexp"
-	"strings"
+	"time"
+
+	"github.com/pankona/knowledges/pkg/config"
 )
 
 // AnalysisResult はLLMによる分析結果を表現します
@@ -16,56 +16,183 @@ type AnalysisResult struct {
 	Type            string   `json:"type"`
 	Tags            []string `json:"tags"`
 	RelevanceScore  float64  `json:"relevance_score"`
+	Severity        string   `json:"severity,omitempty"`
+	// Repairs はスキーマ違反を指摘して出力を修正させた回数です
+	Repairs         int      `json:"-"`
+	// Cached はキャッシュから取り出した結果かどうかです
+	Cached          bool     `json:"-"`
+	// Usage は結果を得るまでに使ったトークン数と時間です（キャッシュの場合はゼロ）
+	Usage           Usage    `json:"-"`
 }
 
-// CommandExecutor はLLMコマンドを実行するインターフェース
-type CommandExecutor interface {
-	Execute(ctx context.Context, cmd string, args []string, input string) ([]byte, error)
+// Driver はLLMのドライバーです
+//
+// 呼び出し先（コマンドやHTTP API）は Analyzer に任せ、リトライ・出力の修正・キャッシュ・
+// 結果のパースを共通で行います。
+type Driver struct {
+	name     string
+	model    string
+	analyzer Analyzer
+	retry    RetryPolicy
+	onRetry  RetryHandler
+	// maxRepairs はスキーマ違反の出力を修正させる最大回数です
+	maxRepairs int
+	onRepair   RepairHandler
+	cache      Cache
+	onUsage    UsageHandler
 }
 
-// DefaultCommandExecutor は実際のコマンドを実行します
-type DefaultCommandExecutor struct{}
+// RepairHandler はスキーマ違反の出力の修正を依頼する直前に呼ばれます
+//
+// repair は修正の回数（1始まり）、maxRepairs は最大回数です。
+type RepairHandler func(driver *Driver, repair, maxRepairs int, err error)
 
-func (e *DefaultCommandExecutor) Execute(ctx context.Context, cmd string, args []string, input string) ([]byte, error) {
-	command := exec.CommandContext(ctx, cmd, args...)
-	command.Stdin = bytes.NewBufferString(input)
-	return command.Output()
+// NewDriver はコマンドを実行する新しいDriverを作成します
+func NewDriver(command string, args []string) *Driver {
+	return &Driver{
+		name:     command,
+		analyzer: NewCommandAnalyzer(command, args),
+	}
 }
 
-// Driver はLLMコマンドのドライバーです
-type Driver struct {
-	command  string
-	args     []string  
-	executor CommandExecutor
+// NewDriverFromConfig は設定ファイルのドライバー定義からDriverを作成します
+//
+// type に応じてコマンドまたはHTTP APIを呼び出す Analyzer を使います。
+func NewDriverFromConfig(name string, cfg config.DriverConfig) (*Driver, error) {
+	analyzer, err := NewAnalyzerFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("llm driver %q: %w", name, err)
+	}
+	return &Driver{name: name, model: cfg.Model, analyzer: analyzer}, nil
 }
 
-// NewDriver は新しいDriverを作成します
-func NewDriver(command string, args []string) *Driver {
-	return &Driver{
-		command:  command,
-		args:     args,
-		executor: &DefaultCommandExecutor{},
-	}
+// Name はドライバー名を返します
+func (d *Driver) Name() string {
+	return d.name
+}
+
+// Model はドライバーのモデル名を返します（未設定の場合は空文字列）
+func (d *Driver) Model() string {
+	return d.model
+}
+
+// SetRetryPolicy はリトライ設定を設定します
+func (d *Driver) SetRetryPolicy(policy RetryPolicy) {
+	d.retry = policy
+}
+
+// SetRetryHandler は再試行するときの通知先を設定します
+func (d *Driver) SetRetryHandler(handler RetryHandler) {
+	d.onRetry = handler
+}
+
+// SetMaxRepairs はスキーマ違反の出力を修正させる最大回数を設定します（0 で修正しない）
+func (d *Driver) SetMaxRepairs(maxRepairs int) {
+	d.maxRepairs = maxRepairs
+}
+
+// SetRepairHandler は出力の修正を依頼するときの通知先を設定します
+func (d *Driver) SetRepairHandler(handler RepairHandler) {
+	d.onRepair = handler
 }
 
-// SetExecutor はコマンド実行器を設定します（テスト用）
+// SetUsageHandler はLLMを呼び出すたびの使用量の通知先を設定します
+func (d *Driver) SetUsageHandler(handler UsageHandler) {
+	d.onUsage = handler
+}
+
+// SetAnalyzer はLLMの呼び出し先を設定します
+func (d *Driver) SetAnalyzer(analyzer Analyzer) {
+	d.analyzer = analyzer
+}
+
+// SetExecutor はコマンド実行器を設定します（テスト用、コマンドのドライバーのみ）
 func (d *Driver) SetExecutor(executor CommandExecutor) {
-	d.executor = executor
+	if command, ok := d.analyzer.(*CommandAnalyzer); ok {
+		command.executor = executor
+	}
 }
 
 // AnalyzeComment は単一のコメントを分析します
+//
+// キャッシュが設定されている場合、同じプロンプトの結果があればLLMを呼び出さずに返します。
+// リトライ設定がある場合、再試行可能なエラーはバックオフを挟んで再実行します。
+// 結果がスキーマに違反する場合は、違反内容を伝えて最大 maxRepairs 回まで修正させます。
 func (d *Driver) AnalyzeComment(ctx context.Context, prompt string) (*AnalysisResult, error) {
 	if prompt == "" {
 		return nil, fmt.Errorf("prompt cannot be empty")
 	}
+	if result := d.cachedResult(ctx, prompt); result != nil {
+		return result, nil
+	}
+
+	result, err := d.analyzeComment(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	d.storeResult(ctx, prompt, result)
+	return result, nil
+}
 
-	output, err := d.executor.Execute(ctx, d.command, d.args, prompt)
+// analyzeComment はキャッシュを使わずにLLMで分析し、スキーマ違反を修正させます
+func (d *Driver) analyzeComment(ctx context.Context, prompt string) (*AnalysisResult, error) {
+	var usage Usage
+	result, output, err := d.analyzeWithRetry(ctx, prompt, &usage)
 	if err != nil {
-		return nil, fmt.Errorf("LLM command failed: %w", err)
+		return nil, err
+	}
+
+	for repairs := 0; ; repairs++ {
+		var invalid *ValidationError
+		if err := result.Validate(); !errors.As(err, &invalid) {
+			result.Repairs = repairs
+			result.Usage = usage
+			return result, nil
+		}
+		if repairs >= d.maxRepairs {
+			return nil, fmt.Errorf("LLM output is still invalid after %d repairs: %w", repairs, invalid)
+		}
+
+		if d.onRepair != nil {
+			d.onRepair(d, repairs+1, d.maxRepairs, invalid)
+		}
+		result, output, err = d.analyzeWithRetry(ctx, buildRepairPrompt(prompt, output, invalid), &usage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to repair LLM output: %w", err)
+		}
+	}
+}
+
+// analyzeWithRetry はリトライ設定に従ってLLMを呼び出し、結果と出力を返します
+//
+// 失敗した呼び出しを含む全ての呼び出しの使用量を usage に足し合わせます。
+func (d *Driver) analyzeWithRetry(ctx context.Context, prompt string, usage *Usage) (*AnalysisResult, []byte, error) {
+	var result *AnalysisResult
+	var output []byte
+	err := d.retry.Do(ctx, func(attempt int) error {
+		var err error
+		result, output, err = d.analyzeOnce(ctx, prompt, usage)
+		return err
+	}, func(attempt int, delay time.Duration, err error) {
+		if d.onRetry != nil {
+			d.onRetry(d, attempt, d.retry.attempts(), delay, err)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, output, nil
+}
+
+// analyzeOnce はLLMを1回呼び出して結果をパースします
+func (d *Driver) analyzeOnce(ctx context.Context, prompt string, usage *Usage) (*AnalysisResult, []byte, error) {
+	output, err := d.call(ctx, Request{Prompt: prompt, Format: FormatObject}, usage)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	if len(output) == 0 {
-		return nil, fmt.Errorf("LLM returned empty response")
+		return nil, nil, retryable(fmt.Errorf("LLM returned empty response"))
 	}
 
 	// LLMの出力からJSONを抽出（コードブロック対応）
@@ -73,30 +200,31 @@ func (d *Driver) AnalyzeComment(ctx context.Context, prompt string) (*AnalysisRe
 
 	var result AnalysisResult
 	if err := json.Unmarshal(jsonOutput, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse LLM output: %w", err)
+		return nil, nil, retryable(fmt.Errorf("failed to parse LLM output: %w", err))
 	}
 
-	return &result, nil
+	return &result, output, nil
 }
 
-// extractJSON はLLMの出力からJSON部分を抽出します
-func extractJSON(output []byte) []byte {
-	text := string(output)
-	
-	// ```json ... ``` のコードブロックを探す
-	codeBlockPattern := regexp.MustCompile("```(?:json)?\n?([^`]+)```")
-	matches := codeBlockPattern.FindStringSubmatch(text)
-	if len(matches) > 1 {
-		return []byte(strings.TrimSpace(matches[1]))
+// call は呼び出し先を1回呼び出し、使用量を usage に足し合わせて通知します
+func (d *Driver) call(ctx context.Context, request Request, usage *Usage) ([]byte, error) {
+	start := time.Now()
+	response, err := d.analyzer.Analyze(ctx, request)
+
+	used := Usage{Calls: 1, Latency: time.Since(start)}
+	if response != nil {
+		used.InputTokens, used.OutputTokens = response.InputTokens, response.OutputTokens
+		if response.Estimated {
+			used.EstimatedCalls = 1
+		}
 	}
-	
-	// { } で囲まれたJSONを探す
-	jsonPattern := regexp.MustCompile(`(\{[^{}]*(?:\{[^{}]*\}[^{}]*)*\})`)
-	jsonMatches := jsonPattern.FindStringSubmatch(text)
-	if len(jsonMatches) > 1 {
-		return []byte(strings.TrimSpace(jsonMatches[1]))
+	usage.Add(used)
+	if d.onUsage != nil {
+		d.onUsage(d, used)
 	}
-	
-	// そのまま返す（既にJSONの場合）
-	return bytes.TrimSpace(output)
-}
\ No newline at end of file
+
+	if err != nil {
+		return nil, err
+	}
+	return response.Output, nil
+}
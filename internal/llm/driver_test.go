@@ -83,7 +83,7 @@ func TestAnalyzeComment_Success(t *testing.T) {
 func TestAnalyzeComment_WithCodeBlock_Success(t *testing.T) {
 	// Arrange
 	mockExecutor := &MockCommandExecutor{
-		output: []byte("```json\n{\"summary\": \"Feature flag analysis\", \"type\": \"domain\", \"tags\": [\"feature-flag\"], \"relevance_score\": 0.9}\n```"),
+		output: []byte("```json\n{\"summary\": \"Feature flag analysis\", \"type\": \"business\", \"tags\": [\"feature-flag\"], \"relevance_score\": 0.9}\n```"),
 		err:    nil,
 	}
 	
@@ -102,8 +102,8 @@ func TestAnalyzeComment_WithCodeBlock_Success(t *testing.T) {
 		t.Errorf("Expected summary 'Feature flag analysis', got '%s'", result.Summary)
 	}
 	
-	if result.Type != "domain" {
-		t.Errorf("Expected type 'domain', got '%s'", result.Type)
+	if result.Type != "business" {
+		t.Errorf("Expected type 'business', got '%s'", result.Type)
 	}
 }
 
@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// extractJSON はLLMの出力から分析結果のJSONオブジェクトを抽出します
+//
+// 前後の説明文やコードブロックの囲みは無視し、"summary" か "type" を持つ最初の完全な
+// オブジェクトを返します。見つからない場合は最初の空でないオブジェクト、それもなければ
+// 出力をそのまま返します（パースエラーは呼び出し側で扱う）。
+func extractJSON(output []byte) []byte {
+	values := jsonValues(output)
+	for _, value := range values {
+		if isObject(value) && hasAnyKey(value, "summary", "type") {
+			return value
+		}
+	}
+	for _, value := range values {
+		if isObject(value) {
+			return value
+		}
+	}
+	return bytes.TrimSpace(output)
+}
+
+// extractJSONArray はLLMの出力からバッチ分析の結果のJSON配列を抽出します
+//
+// 説明文中のタグの列挙などを結果と取り違えないよう、"id" を持つオブジェクトの配列を優先し、
+// なければ最初の空でない配列を返します。配列が見つからない場合は、"id" を持つオブジェクトを
+// 並べた配列として扱います（1行に1オブジェクトずつ返した場合や、コメントが1件の場合に配列を
+// 省略した場合）。
+func extractJSONArray(output []byte) []byte {
+	values := jsonValues(output)
+	for _, value := range values {
+		if value[0] == '[' && hasObjectWithID(value) {
+			return value
+		}
+	}
+	for _, value := range values {
+		if value[0] == '[' {
+			return value
+		}
+	}
+
+	var objects [][]byte
+	for _, value := range values {
+		if isObject(value) && hasAnyKey(value, "id") {
+			objects = append(objects, value)
+		}
+	}
+	if len(objects) > 0 {
+		return append(append([]byte{'['}, bytes.Join(objects, []byte{','})...), ']')
+	}
+	return bytes.TrimSpace(output)
+}
+
+// hasObjectWithID はJSON配列が "id" を持つオブジェクトを要素に含むかどうかを返します
+func hasObjectWithID(value []byte) bool {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(value, &elements); err != nil {
+		return false
+	}
+	for _, element := range elements {
+		element = bytes.TrimSpace(element)
+		if isObject(element) && hasAnyKey(element, "id") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonValues は出力に含まれる完全なJSONのオブジェクトと配列を先頭から順に返します
+//
+// '{' か '[' の位置からストリーミングデコーダーで1つの値を読み、読めた場合はその値の後ろから、
+// 読めなかった場合（説明文中の括弧や途中で切れた出力）は次の文字から探します。文字列の中の
+// バッククォートや括弧はデコーダーが文字列として扱うため、値の区切りを誤りません。
+// 空のオブジェクトと配列は分析結果ではないため除きます。
+func jsonValues(output []byte) []json.RawMessage {
+	var values []json.RawMessage
+	for i := 0; i < len(output); i++ {
+		if output[i] != '{' && output[i] != '[' {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(output[i:]))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			continue
+		}
+		if !isEmpty(value) {
+			values = append(values, value)
+		}
+		i += int(decoder.InputOffset()) - 1
+	}
+	return values
+}
+
+// isObject はJSONの値がオブジェクトかどうかを返します
+func isObject(value []byte) bool {
+	return len(value) > 0 && value[0] == '{'
+}
+
+// isEmpty はJSONの値が空のオブジェクトか空の配列かどうかを返します
+func isEmpty(value []byte) bool {
+	trimmed := bytes.TrimSpace(value[1 : len(value)-1])
+	return len(trimmed) == 0
+}
+
+// hasAnyKey はJSONオブジェクトがいずれかのキーを持つかどうかを返します
+func hasAnyKey(value []byte, keys ...string) bool {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(value, &object); err != nil {
+		return false
+	}
+	for _, key := range keys {
+		if _, ok := object[key]; ok {
+			return true
+		}
+	}
+	return false
+}
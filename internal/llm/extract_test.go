@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testdata/malformed には実際のLLMの出力で抽出に失敗しやすかったものを集めています
+func readCorpus(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "malformed", name))
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+	return data
+}
+
+// singleCorpus は単独分析の応答のコーパスと期待する結果です
+var singleCorpus = []struct {
+	file          string
+	wantType      string
+	wantSummary   string // 要約に含まれるべき文字列
+	wantParseFail bool
+}{
+	{file: "prose_before_and_after.txt", wantType: "maintenance", wantSummary: "wrapped with %w"},
+	{file: "fenced_summary_with_code_block.txt", wantType: "bug", wantSummary: "defer resp.Body.Close()\n```"},
+	{file: "inline_backticks.txt", wantType: "implementation", wantSummary: "`context.WithTimeout`"},
+	{file: "deeply_nested.txt", wantType: "design", wantSummary: "nested config"},
+	{file: "braces_in_prose.txt", wantType: "bug", wantSummary: "silently dropping"},
+	{file: "go_snippet_before_json.txt", wantType: "maintenance", wantSummary: "retry count"},
+	{file: "escaped_quotes_and_unicode.txt", wantType: "maintenance", wantSummary: `"failed to" を付けて {} を含む値も \ エスケープ`},
+	{file: "truncated.txt", wantParseFail: true},
+	{file: "no_json.txt", wantParseFail: true},
+}
+
+// batchCorpus はバッチ分析の応答のコーパスと結果を期待するIDです
+var batchCorpus = []struct {
+	file    string
+	wantIDs []string
+}{
+	{"batch_with_prose.txt", []string{"c1", "c2"}},
+	{"batch_json_lines.txt", []string{"c1", "c2"}},
+	{"batch_single_object.txt", []string{"c1"}},
+	{"batch_tags_before_results.txt", []string{"c1", "c2"}},
+}
+
+func TestMalformedCorpus_AllFilesAreCovered(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, tt := range singleCorpus {
+		covered[tt.file] = true
+	}
+	for _, tt := range batchCorpus {
+		covered[tt.file] = true
+	}
+
+	entries, err := os.ReadDir(filepath.Join("testdata", "malformed"))
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+	for _, entry := range entries {
+		if !covered[entry.Name()] {
+			t.Errorf("corpus file %s has no expectation", entry.Name())
+		}
+	}
+}
+
+func TestExtractJSON_MalformedCorpus(t *testing.T) {
+	for _, tt := range singleCorpus {
+		t.Run(tt.file, func(t *testing.T) {
+			// Arrange
+			driver := NewDriver("claude", []string{"-p"})
+			driver.SetExecutor(&MockCommandExecutor{output: readCorpus(t, tt.file)})
+
+			// Act
+			result, err := driver.AnalyzeComment(context.Background(), "prompt")
+
+			// Assert
+			if tt.wantParseFail {
+				if err == nil || !strings.Contains(err.Error(), "failed to parse LLM output") {
+					t.Fatalf("expected a parse error, got %+v / %v", result, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != tt.wantType || !strings.Contains(result.Summary, tt.wantSummary) {
+				t.Errorf("unexpected result: type %q, summary %q", result.Type, result.Summary)
+			}
+		})
+	}
+}
+
+func TestExtractJSONArray_MalformedCorpus(t *testing.T) {
+	for _, tt := range batchCorpus {
+		t.Run(tt.file, func(t *testing.T) {
+			// Act
+			results, err := parseBatchOutput(readCorpus(t, tt.file), batchItems())
+
+			// Assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(results) != len(tt.wantIDs) {
+				t.Fatalf("expected results for %v, got %v", tt.wantIDs, results)
+			}
+			for _, id := range tt.wantIDs {
+				if results[id] == nil || results[id].Summary == "" {
+					t.Errorf("expected a result for %s, got %+v", id, results[id])
+				}
+			}
+		})
+	}
+}
+
+func TestJSONValues_SkipsNestedAndEmptyValues(t *testing.T) {
+	// Arrange
+	output := []byte(`prefix {} [] {"a": {"b": [1, {"c": 2}]}} middle [{"d": 3}] {"e": "}"`)
+
+	// Act
+	values := jsonValues(output)
+
+	// Assert
+	if len(values) != 2 {
+		t.Fatalf("expected 2 top-level values, got %d: %s", len(values), values)
+	}
+	if string(values[0]) != `{"a": {"b": [1, {"c": 2}]}}` || string(values[1]) != `[{"d": 3}]` {
+		t.Errorf("unexpected values: %s", values)
+	}
+}
@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// HTTP APIのドライバーの既定値
+const (
+	// DefaultHTTPTimeout は1回の呼び出しのタイムアウトの既定値です
+	DefaultHTTPTimeout = 2 * time.Minute
+	// DefaultMaxTokens は応答の最大トークン数の既定値です（必須のAPIでのみ使用）
+	DefaultMaxTokens = 4096
+)
+
+// 応答の形式（llm.drivers.<name>.response_format）
+const (
+	// ResponseFormatJSON はAPIのJSONモード（構造化出力）を使います（既定値）
+	ResponseFormatJSON = "json"
+	// ResponseFormatText はJSONモードを使わず、プロンプトの指示に任せます
+	ResponseFormatText = "text"
+)
+
+// httpAPI はHTTP APIのドライバーに共通の設定と呼び出し処理です
+type httpAPI struct {
+	endpoint    string
+	apiKey      string
+	model       string
+	temperature *float64
+	maxTokens   int
+	jsonMode    bool
+	client      *http.Client
+}
+
+// newHTTPAPI はドライバー定義から共通の設定を作成します
+//
+// endpoint と api_key_env が未設定の場合は種類ごとの既定値を使います。
+// apiKeyRequired の場合、APIキーの環境変数が空であればエラーを返します。
+func newHTTPAPI(cfg config.DriverConfig, defaultEndpoint, defaultAPIKeyEnv string, apiKeyRequired bool) (*httpAPI, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("model is required for %s drivers", cfg.Type)
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	apiKeyEnv := cfg.APIKeyEnv
+	if apiKeyEnv == "" {
+		apiKeyEnv = defaultAPIKeyEnv
+	}
+	var apiKey string
+	if apiKeyEnv != "" {
+		apiKey = os.Getenv(apiKeyEnv)
+		if apiKey == "" && (apiKeyRequired || cfg.APIKeyEnv != "") {
+			return nil, fmt.Errorf("API key is not set in $%s", apiKeyEnv)
+		}
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+
+	return &httpAPI{
+		endpoint:    strings.TrimRight(endpoint, "/"),
+		apiKey:      apiKey,
+		model:       cfg.Model,
+		temperature: cfg.Temperature,
+		maxTokens:   cfg.MaxTokens,
+		jsonMode:    cfg.ResponseFormat != ResponseFormatText,
+		client:      &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// settings はエンドポイントとモデル・サンプリングの設定を返します（APIキーとタイムアウトは含まない）
+func (a *httpAPI) settings() string {
+	temperature := "default"
+	if a.temperature != nil {
+		temperature = fmt.Sprint(*a.temperature)
+	}
+	return fmt.Sprintf("endpoint=%s model=%s temperature=%s max_tokens=%d json=%t",
+		a.endpoint, a.model, temperature, a.maxTokens, a.jsonMode)
+}
+
+// post はJSONのリクエストを送り、成功した応答をJSONとして response にデコードします
+//
+// 通信エラー・タイムアウト・429・5xxは再試行可能なエラーとして返します。
+func (a *httpAPI) post(ctx context.Context, path string, headers map[string]string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode LLM API request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create LLM API request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("LLM API request failed: %w", err)
+		if ctx.Err() == nil {
+			return retryable(err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryable(fmt.Errorf("failed to read LLM API response: %w", err))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("LLM API returned %s: %s", resp.Status, truncate(strings.TrimSpace(string(data)), 500))
+		if isTransientStatus(resp.StatusCode) {
+			return retryable(err)
+		}
+		return err
+	}
+
+	if err := json.Unmarshal(data, response); err != nil {
+		return retryable(fmt.Errorf("failed to decode LLM API response: %w", err))
+	}
+	return nil
+}
+
+// isTransientStatus は再試行で解消する可能性のあるHTTPステータスかどうかを判定します
+func isTransientStatus(status int) bool {
+	switch {
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	case status >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+// truncate はエラーメッセージに含める応答を最大 n バイトに切り詰めます
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,292 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// apiStub はリクエストを記録して決まった応答を返すHTTP APIの代わりです
+type apiStub struct {
+	server   *httptest.Server
+	requests []map[string]interface{}
+	headers  []http.Header
+	paths    []string
+}
+
+func newAPIStub(t *testing.T, respond func(w http.ResponseWriter, body map[string]interface{})) *apiStub {
+	t.Helper()
+	stub := &apiStub{}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		stub.requests = append(stub.requests, body)
+		stub.headers = append(stub.headers, r.Header.Clone())
+		stub.paths = append(stub.paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		respond(w, body)
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func temperature(v float64) *float64 {
+	return &v
+}
+
+func TestAnthropicAnalyzer_PrefillsJSON(t *testing.T) {
+	// Arrange
+	t.Setenv("TEST_ANTHROPIC_KEY", "secret")
+	stub := newAPIStub(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		writeJSON(w, map[string]interface{}{
+			"content":     []map[string]string{{"type": "text", "text": `"summary": "Check errors.", "type": "bug", "relevance_score": 0.9}`}},
+			"stop_reason": "end_turn",
+		})
+	})
+	driver := newConfiguredDriver(t, "api", config.DriverConfig{
+		Type:        DriverTypeAnthropic,
+		Model:       "claude-sonnet",
+		Endpoint:    stub.server.URL + "/",
+		APIKeyEnv:   "TEST_ANTHROPIC_KEY",
+		Temperature: temperature(0),
+	})
+
+	// Act
+	result, err := driver.AnalyzeComment(context.Background(), "analyze this")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Summary != "Check errors." || result.Type != "bug" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if stub.paths[0] != "/v1/messages" || stub.headers[0].Get("x-api-key") != "secret" || stub.headers[0].Get("anthropic-version") == "" {
+		t.Errorf("unexpected request: %s %v", stub.paths[0], stub.headers[0])
+	}
+	request := stub.requests[0]
+	if request["model"] != "claude-sonnet" || request["max_tokens"] != float64(DefaultMaxTokens) || request["temperature"] != float64(0) {
+		t.Errorf("unexpected request body: %v", request)
+	}
+	messages := request["messages"].([]interface{})
+	last := messages[len(messages)-1].(map[string]interface{})
+	if len(messages) != 2 || last["role"] != "assistant" || last["content"] != "{" {
+		t.Errorf("expected the response to be prefilled with {, got %v", messages)
+	}
+}
+
+func TestAnthropicAnalyzer_RequiresAPIKey(t *testing.T) {
+	// Arrange
+	t.Setenv(DefaultAnthropicAPIKeyEnv, "")
+
+	// Act
+	_, err := NewDriverFromConfig("api", config.DriverConfig{Type: DriverTypeAnthropic, Model: "claude-sonnet"})
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "$"+DefaultAnthropicAPIKeyEnv) {
+		t.Errorf("expected missing API key error, got %v", err)
+	}
+}
+
+func TestOpenAIAnalyzer_UsesJSONModeForSingleComments(t *testing.T) {
+	// Arrange
+	t.Setenv("TEST_OPENAI_KEY", "secret")
+	stub := newAPIStub(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		content := `{"summary": "s", "type": "bug"}`
+		if _, ok := body["response_format"]; !ok {
+			content = `[{"id": "c1", "summary": "s1", "type": "bug"}, {"id": "c2", "summary": "s2", "type": "testing"}]`
+		}
+		writeJSON(w, map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": content}, "finish_reason": "stop"}},
+		})
+	})
+	driver := newConfiguredDriver(t, "api", config.DriverConfig{
+		Type:      DriverTypeOpenAI,
+		Model:     "gpt-4o-mini",
+		Endpoint:  stub.server.URL,
+		APIKeyEnv: "TEST_OPENAI_KEY",
+		MaxTokens: 512,
+	})
+
+	// Act
+	_, singleErr := driver.AnalyzeComment(context.Background(), "analyze this")
+	results := driver.AnalyzeBatch(context.Background(), "instructions", batchItems()[:2])
+
+	// Assert
+	if singleErr != nil {
+		t.Fatalf("unexpected error: %v", singleErr)
+	}
+	for _, result := range results {
+		if result.Err != nil || result.Single {
+			t.Errorf("expected %s from the batch call, got %+v", result.ID, result)
+		}
+	}
+	if len(stub.requests) != 2 || stub.paths[0] != "/chat/completions" {
+		t.Fatalf("expected 2 requests to /chat/completions, got %v", stub.paths)
+	}
+	if stub.headers[0].Get("Authorization") != "Bearer secret" {
+		t.Errorf("expected bearer authorization, got %v", stub.headers[0])
+	}
+	format, _ := stub.requests[0]["response_format"].(map[string]interface{})
+	if format["type"] != "json_object" || stub.requests[0]["max_tokens"] != float64(512) {
+		t.Errorf("expected JSON mode for the single comment, got %v", stub.requests[0])
+	}
+	if _, ok := stub.requests[0]["temperature"]; ok {
+		t.Errorf("expected the API default temperature, got %v", stub.requests[0]["temperature"])
+	}
+}
+
+func TestOllamaAnalyzer_RequestsStructuredOutput(t *testing.T) {
+	tests := []struct {
+		name           string
+		responseFormat string
+		wantFormat     interface{}
+	}{
+		{"json mode", "", "json"},
+		{"text mode", ResponseFormatText, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			stub := newAPIStub(t, func(w http.ResponseWriter, body map[string]interface{}) {
+				writeJSON(w, map[string]interface{}{
+					"message": map[string]string{"role": "assistant", "content": `{"summary": "s", "type": "design"}`},
+					"done":    true,
+				})
+			})
+			driver := newConfiguredDriver(t, "local", config.DriverConfig{
+				Type:           DriverTypeOllama,
+				Model:          "llama3",
+				Endpoint:       stub.server.URL,
+				Temperature:    temperature(0.2),
+				ResponseFormat: tt.responseFormat,
+			})
+
+			// Act
+			result, err := driver.AnalyzeComment(context.Background(), "analyze this")
+
+			// Assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != "design" {
+				t.Errorf("unexpected result: %+v", result)
+			}
+			request := stub.requests[0]
+			if stub.paths[0] != "/api/chat" || request["stream"] != false || request["format"] != tt.wantFormat {
+				t.Errorf("unexpected request to %s: %v", stub.paths[0], request)
+			}
+			options, _ := request["options"].(map[string]interface{})
+			if options["temperature"] != 0.2 {
+				t.Errorf("expected temperature in options, got %v", request["options"])
+			}
+		})
+	}
+}
+
+func TestOllamaAnalyzer_BatchUsesArraySchema(t *testing.T) {
+	// Arrange
+	stub := newAPIStub(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		writeJSON(w, map[string]interface{}{
+			"message": map[string]string{"content": `[{"id": "c1", "summary": "s1", "type": "bug"}, {"id": "c2", "summary": "s2", "type": "bug"}]`},
+		})
+	})
+	driver := newConfiguredDriver(t, "local", config.DriverConfig{Type: DriverTypeOllama, Model: "llama3", Endpoint: stub.server.URL})
+
+	// Act
+	results := driver.AnalyzeBatch(context.Background(), "instructions", batchItems()[:2])
+
+	// Assert
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Fatalf("unexpected errors: %+v", results)
+	}
+	format, _ := stub.requests[0]["format"].(map[string]interface{})
+	if format["type"] != "array" {
+		t.Errorf("expected an array schema, got %v", stub.requests[0]["format"])
+	}
+}
+
+func TestHTTPAnalyzer_RetriesTransientStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"rate limited", http.StatusTooManyRequests, 2, false},
+		{"server error", http.StatusServiceUnavailable, 2, false},
+		{"bad request", http.StatusBadRequest, 1, true},
+		{"unauthorized", http.StatusUnauthorized, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var calls atomic.Int32
+			stub := newAPIStub(t, func(w http.ResponseWriter, body map[string]interface{}) {
+				if calls.Add(1) == 1 {
+					w.WriteHeader(tt.status)
+					writeJSON(w, map[string]string{"error": "try later"})
+					return
+				}
+				writeJSON(w, map[string]interface{}{"message": map[string]string{"content": `{"summary": "s", "type": "bug"}`}})
+			})
+			driver := newConfiguredDriver(t, "local", config.DriverConfig{Type: DriverTypeOllama, Model: "llama3", Endpoint: stub.server.URL})
+			driver.SetRetryPolicy(RetryPolicy{MaxAttempts: 3})
+
+			// Act
+			_, err := driver.AnalyzeComment(context.Background(), "analyze this")
+
+			// Assert
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), "try later") {
+				t.Errorf("expected the API error in the message, got %v", err)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls.Load())
+			}
+		})
+	}
+}
+
+func TestHTTPAnalyzer_TimeoutIsRetryable(t *testing.T) {
+	// Arrange
+	release := make(chan struct{})
+	stub := newAPIStub(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		<-release
+	})
+	defer close(release)
+	analyzer, err := NewOllamaAnalyzer(config.DriverConfig{
+		Type:     DriverTypeOllama,
+		Model:    "llama3",
+		Endpoint: stub.server.URL,
+		Timeout:  50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err = analyzer.Analyze(context.Background(), Request{Prompt: "analyze this"})
+
+	// Assert
+	if err == nil || !IsRetryable(err) {
+		t.Errorf("expected a retryable timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// DefaultOllamaEndpoint はローカルの Ollama の既定のURLです
+const DefaultOllamaEndpoint = "http://localhost:11434"
+
+// ollamaArraySchema はバッチ分析の応答をオブジェクトの配列に限定するJSONスキーマです
+var ollamaArraySchema = json.RawMessage(`{"type": "array", "items": {"type": "object"}}`)
+
+// OllamaAnalyzer は Ollama の Chat API（/api/chat）を呼び出します
+//
+// JSONモードでは format に単独分析は "json"、バッチ分析は配列のJSONスキーマを指定します。
+type OllamaAnalyzer struct {
+	api *httpAPI
+}
+
+// NewOllamaAnalyzer はドライバー定義から OllamaAnalyzer を作成します（APIキーは使わない）
+func NewOllamaAnalyzer(cfg config.DriverConfig) (*OllamaAnalyzer, error) {
+	api, err := newHTTPAPI(cfg, DefaultOllamaEndpoint, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return &OllamaAnalyzer{api: api}, nil
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// settings はキャッシュキーに含める Ollama API の設定を返します
+func (a *OllamaAnalyzer) settings() string {
+	return DriverTypeOllama + " " + a.api.settings()
+}
+
+// Analyze はプロンプトをユーザーのメッセージとして送り、ストリーミングせずに応答を返します
+func (a *OllamaAnalyzer) Analyze(ctx context.Context, request Request) (*Response, error) {
+	body := ollamaRequest{
+		Model:    a.api.model,
+		Messages: []ollamaMessage{{Role: "user", Content: request.Prompt}},
+	}
+	if a.api.jsonMode {
+		body.Format = json.RawMessage(`"json"`)
+		if request.Format == FormatArray {
+			body.Format = ollamaArraySchema
+		}
+	}
+	if a.api.temperature != nil || a.api.maxTokens > 0 {
+		body.Options = &ollamaOptions{Temperature: a.api.temperature, NumPredict: a.api.maxTokens}
+	}
+
+	var response ollamaResponse
+	if err := a.api.post(ctx, "/api/chat", nil, body, &response); err != nil {
+		return nil, err
+	}
+	if response.Message.Content == "" {
+		return nil, retryable(fmt.Errorf("LLM API returned an empty message"))
+	}
+	return &Response{
+		Output:       []byte(response.Message.Content),
+		InputTokens:  response.PromptEvalCount,
+		OutputTokens: response.EvalCount,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// OpenAI互換の Chat Completions API の既定値
+const (
+	DefaultOpenAIEndpoint  = "https://api.openai.com/v1"
+	DefaultOpenAIAPIKeyEnv = "OPENAI_API_KEY"
+)
+
+// OpenAIAnalyzer は OpenAI互換の Chat Completions API（/chat/completions）を呼び出します
+//
+// endpoint を変えることで、OpenAI互換のAPIを持つローカルのサーバーも使えます。
+// APIキーは環境変数が設定されている場合のみ送ります。JSONモードは単独分析でのみ使います
+// （response_format の json_object は応答をオブジェクトに限定するため）。
+type OpenAIAnalyzer struct {
+	api *httpAPI
+}
+
+// NewOpenAIAnalyzer はドライバー定義から OpenAIAnalyzer を作成します
+func NewOpenAIAnalyzer(cfg config.DriverConfig) (*OpenAIAnalyzer, error) {
+	api, err := newHTTPAPI(cfg, DefaultOpenAIEndpoint, DefaultOpenAIAPIKeyEnv, false)
+	if err != nil {
+		return nil, err
+	}
+	return &OpenAIAnalyzer{api: api}, nil
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type openAIRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIMessage       `json:"messages"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// settings はキャッシュキーに含める OpenAI 互換 API の設定を返します
+func (a *OpenAIAnalyzer) settings() string {
+	return DriverTypeOpenAI + " " + a.api.settings()
+}
+
+// Analyze はプロンプトをユーザーのメッセージとして送り、最初の候補の応答を返します
+//
+// usage を返さない互換サーバーの場合、トークン数はプロンプトと出力から推定します。
+func (a *OpenAIAnalyzer) Analyze(ctx context.Context, request Request) (*Response, error) {
+	body := openAIRequest{
+		Model:       a.api.model,
+		Messages:    []openAIMessage{{Role: "user", Content: request.Prompt}},
+		Temperature: a.api.temperature,
+		MaxTokens:   a.api.maxTokens,
+	}
+	if a.api.jsonMode && request.Format == FormatObject {
+		body.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+	}
+
+	headers := map[string]string{}
+	if a.api.apiKey != "" {
+		headers["Authorization"] = "Bearer " + a.api.apiKey
+	}
+
+	var response openAIResponse
+	if err := a.api.post(ctx, "/chat/completions", headers, body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+		return nil, retryable(fmt.Errorf("LLM API returned no choices"))
+	}
+	output := []byte(response.Choices[0].Message.Content)
+	if response.Usage == nil {
+		return estimatedResponse(request, output), nil
+	}
+	return &Response{
+		Output:       output,
+		InputTokens:  response.Usage.PromptTokens,
+		OutputTokens: response.Usage.CompletionTokens,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os/exec"
+	"time"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+// RetryPolicy はLLM呼び出しのリトライ設定です
+//
+// ゼロ値は再試行しない（1回だけ実行する）設定です。
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// RetryHandler は再試行の直前に呼ばれます
+//
+// attempt は失敗した試行の回数（1始まり）、maxAttempts は最大試行回数です。
+type RetryHandler func(driver *Driver, attempt, maxAttempts int, delay time.Duration, err error)
+
+// NewRetryPolicy は設定ファイルのリトライ設定からRetryPolicyを作成します
+func NewRetryPolicy(cfg config.RetryConfig) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:  cfg.MaxAttempts,
+		InitialDelay: cfg.InitialDelay,
+		MaxDelay:     cfg.MaxDelay,
+	}
+}
+
+// attempts は最大試行回数を返します（最低1回）
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Delay はattempt回目の失敗後に待つ時間を返します
+//
+// InitialDelay から倍々に増やした値（MaxDelay が上限）を基準に、その半分から全体の範囲で
+// ランダムにずらします（equal jitter）。
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if p.InitialDelay <= 0 {
+		return 0
+	}
+
+	delay := p.InitialDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// Do は再試行可能なエラーの間、バックオフを挟んで fn を繰り返し実行します
+//
+// コンテキストがキャンセルされた場合は待機を中断して直前のエラーを返します。
+func (p RetryPolicy) Do(ctx context.Context, fn func(attempt int) error, onRetry func(attempt int, delay time.Duration, err error)) error {
+	maxAttempts := p.attempts()
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = fn(attempt)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		delay := p.Delay(attempt)
+		if onRetry != nil {
+			onRetry(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retry aborted after %d attempts: %w", attempt, errors.Join(err, ctx.Err()))
+		case <-timer.C:
+		}
+	}
+
+	if maxAttempts > 1 {
+		return fmt.Errorf("giving up after %d attempts: %w", maxAttempts, err)
+	}
+	return err
+}
+
+// retryableError は再試行で解消する可能性のあるエラーです
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// retryable はエラーを再試行可能としてマークします
+func retryable(err error) error {
+	return &retryableError{err: err}
+}
+
+// IsRetryable は再試行可能なエラー（タイムアウト、非ゼロ終了、パースできない出力）かどうかを判定します
+func IsRetryable(err error) bool {
+	var r *retryableError
+	return errors.As(err, &r)
+}
+
+// isTransientCommandError はコマンドのエラーが一時的なものかどうかを判定します
+//
+// 呼び出し元のコンテキストが終了している場合は再試行しません。
+func isTransientCommandError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sequenceExecutor は呼び出しごとに異なる結果を返すモックです
+type sequenceExecutor struct {
+	outputs []string
+	errs    []error
+	calls   int
+}
+
+func (s *sequenceExecutor) Execute(ctx context.Context, cmd string, args []string, input string) ([]byte, error) {
+	i := s.calls
+	s.calls++
+	if i >= len(s.outputs) {
+		i = len(s.outputs) - 1
+	}
+	return []byte(s.outputs[i]), s.errs[i]
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, InitialDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 300 * time.Millisecond},
+		{10, 300 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := policy.Delay(tt.attempt)
+			if delay < tt.base/2 || delay > tt.base {
+				t.Fatalf("Delay(%d) = %v, want between %v and %v", tt.attempt, delay, tt.base/2, tt.base)
+			}
+		}
+	}
+}
+
+func TestAnalyzeComment_RetriesUnparseableOutput(t *testing.T) {
+	// Arrange
+	executor := &sequenceExecutor{
+		outputs: []string{"I cannot answer that", "", `{"summary": "s", "type": "bug"}`},
+		errs:    []error{nil, nil, nil},
+	}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	var retried []int
+	driver.SetRetryHandler(func(d *Driver, attempt, maxAttempts int, delay time.Duration, err error) {
+		retried = append(retried, attempt)
+		if maxAttempts != 3 {
+			t.Errorf("expected max attempts 3, got %d", maxAttempts)
+		}
+	})
+
+	// Act
+	result, err := driver.AnalyzeComment(context.Background(), "prompt")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != "bug" || executor.calls != 3 {
+		t.Errorf("expected success on the third attempt, got %+v after %d calls", result, executor.calls)
+	}
+	if len(retried) != 2 || retried[0] != 1 || retried[1] != 2 {
+		t.Errorf("expected retry notifications for attempts 1 and 2, got %v", retried)
+	}
+}
+
+func TestAnalyzeComment_RetriesNonZeroExit(t *testing.T) {
+	// Arrange
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	if _, ok := exitErr.(*exec.ExitError); !ok {
+		t.Skipf("cannot produce an exit error: %v", exitErr)
+	}
+	executor := &sequenceExecutor{outputs: []string{""}, errs: []error{exitErr}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond})
+
+	// Act
+	_, err := driver.AnalyzeComment(context.Background(), "prompt")
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "giving up after 3 attempts") {
+		t.Fatalf("expected to give up after 3 attempts, got %v", err)
+	}
+	if executor.calls != 3 {
+		t.Errorf("expected 3 calls, got %d", executor.calls)
+	}
+}
+
+func TestAnalyzeComment_DoesNotRetryPermanentErrors(t *testing.T) {
+	// Arrange: コマンドが見つからないなどのエラーは再試行しない
+	executor := &sequenceExecutor{outputs: []string{""}, errs: []error{exec.ErrNotFound}}
+	driver := NewDriver("missing-cli", nil)
+	driver.SetExecutor(executor)
+	driver.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond})
+
+	// Act
+	_, err := driver.AnalyzeComment(context.Background(), "prompt")
+
+	// Assert
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("expected a single call, got %d", executor.calls)
+	}
+}
+
+func TestAnalyzeComment_StopsRetryingOnCancel(t *testing.T) {
+	// Arrange
+	executor := &sequenceExecutor{outputs: []string{"not json"}, errs: []error{nil}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, InitialDelay: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	driver.SetRetryHandler(func(d *Driver, attempt, maxAttempts int, delay time.Duration, err error) {
+		cancel()
+	})
+
+	// Act
+	_, err := driver.AnalyzeComment(ctx, "prompt")
+
+	// Assert
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("expected no attempt after cancel, got %d calls", executor.calls)
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Run は収集や再分析の1回の実行の記録です（collection_runs と llm_usage に保存）
+type Run struct {
+	Repository string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Documents は保存・分析したドキュメントの数です
+	Documents int
+	Cancelled bool
+	Usages    []DriverUsage
+}
+
+// SaveRun は実行とそのLLMの使用量を保存し、実行のIDを返します
+func SaveRun(ctx context.Context, db *sql.DB, run *Run) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO collection_runs (repository, started_at, finished_at, documents, cancelled)
+		VALUES (?, ?, ?, ?, ?)`,
+		run.Repository, run.StartedAt.UTC(), run.FinishedAt.UTC(), run.Documents, run.Cancelled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save collection run: %w", err)
+	}
+	runID, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get collection run id: %w", err)
+	}
+
+	for _, usage := range run.Usages {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO llm_usage (run_id, driver, model, calls, input_tokens, output_tokens, estimated_calls, latency_ms)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, usage.Driver, usage.Model, usage.Calls, usage.InputTokens, usage.OutputTokens,
+			usage.EstimatedCalls, usage.Latency.Milliseconds())
+		if err != nil {
+			return 0, fmt.Errorf("failed to save LLM usage: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit collection run: %w", err)
+	}
+	return runID, nil
+}
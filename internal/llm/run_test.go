@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pankona/knowledges/internal/database/dbtest"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+func TestSaveRun_RecordsUsagePerDriverAndModel(t *testing.T) {
+	// Arrange
+	db := dbtest.New(t)
+
+	claude, err := NewDriverFromConfig("claude", config.DriverConfig{Command: "claude", Model: "sonnet"})
+	if err != nil {
+		t.Fatalf("Failed to create driver: %v", err)
+	}
+	local := NewDriver("local", nil)
+	recorder := NewUsageRecorder()
+	recorder.Record(claude, Usage{Calls: 1, InputTokens: 1000, OutputTokens: 200, Latency: time.Second})
+	recorder.Record(local, Usage{Calls: 1, InputTokens: 50, OutputTokens: 10, EstimatedCalls: 1})
+	recorder.Record(claude, Usage{Calls: 1, InputTokens: 500, OutputTokens: 100, Latency: time.Second})
+
+	startedAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	run := &Run{
+		Repository: "owner/repo",
+		StartedAt:  startedAt,
+		FinishedAt: startedAt.Add(time.Minute),
+		Documents:  3,
+		Usages:     recorder.Totals(),
+	}
+
+	// Act
+	runID, err := SaveRun(context.Background(), db, run)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(run.Usages) != 2 || run.Usages[0].Driver != "claude" || run.Usages[1].Driver != "local" {
+		t.Fatalf("expected the usages sorted by driver, got %+v", run.Usages)
+	}
+	var calls, input, output, latency int
+	err = db.QueryRow(`
+		SELECT calls, input_tokens, output_tokens, latency_ms FROM llm_usage
+		WHERE run_id = ? AND driver = 'claude' AND model = 'sonnet'`, runID).Scan(&calls, &input, &output, &latency)
+	if err != nil {
+		t.Fatalf("failed to query usage: %v", err)
+	}
+	if calls != 2 || input != 1500 || output != 300 || latency != 2000 {
+		t.Errorf("expected the calls of a driver to be added up, got %d calls, %d/%d tokens, %dms", calls, input, output, latency)
+	}
+	var repository string
+	var documents int
+	err = db.QueryRow("SELECT repository, documents FROM collection_runs WHERE id = ?", runID).Scan(&repository, &documents)
+	if err != nil {
+		t.Fatalf("failed to query run: %v", err)
+	}
+	if repository != "owner/repo" || documents != 3 {
+		t.Errorf("expected the run of owner/repo with 3 documents, got %s with %d", repository, documents)
+	}
+}
@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BatchAnalyzer はコメントをまとめて分析するLLMです（Chain と Driver が実装）
+type BatchAnalyzer interface {
+	AnalyzeBatch(ctx context.Context, instructions string, items []BatchItem) []BatchResult
+}
+
+// BatchRequest は BatchRunner で分析する1件のコメントです
+type BatchRequest struct {
+	// Instructions はプロンプトの共通の指示です（同じ指示のコメントだけをまとめる）
+	Instructions string
+	Context      string
+	Prompt       string
+}
+
+// BatchRunner は同じ指示のコメントを BatchSize 件までまとめ、Parallel 個まで並行に分析します
+//
+// Parallel と BatchSize が1未満の場合は1として扱います。
+type BatchRunner struct {
+	Parallel  int
+	BatchSize int
+	// NewAnalyzer はワーカーごとのLLMを作成します
+	NewAnalyzer func() (BatchAnalyzer, error)
+	// Progress は各バッチの分析が終わるたびに、分析したコメントの数とともに呼ばれます
+	Progress func(done, total int)
+}
+
+// Run はコメントを分析し、requests の順に結果を返します
+//
+// LLMの作成に失敗した場合は結果を返しません。キャンセルされた場合は、分析していない
+// コメントの結果に ctx.Err() を設定し、分析済みの結果とともに ctx.Err() を返します。
+func (r *BatchRunner) Run(ctx context.Context, requests []BatchRequest) ([]BatchResult, error) {
+	parallel := r.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	batchSize := r.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	var batches [][]int
+	groups := make(map[string]int)
+	for i, request := range requests {
+		index, ok := groups[request.Instructions]
+		if !ok || len(batches[index]) >= batchSize {
+			index = len(batches)
+			groups[request.Instructions] = index
+			batches = append(batches, nil)
+		}
+		batches[index] = append(batches[index], i)
+	}
+
+	results := make([]BatchResult, len(requests))
+	queue := make(chan []int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	done := 0
+	for i := 0; i < parallel && i < len(batches); i++ {
+		analyzer, err := r.NewAnalyzer()
+		if err != nil {
+			firstErr = err
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range queue {
+				r.analyze(ctx, analyzer, requests, batch, results)
+
+				mu.Lock()
+				done += len(batch)
+				if r.Progress != nil {
+					r.Progress(done, len(requests))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	if firstErr == nil {
+		for _, batch := range batches {
+			queue <- batch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return results, ctx.Err()
+}
+
+// analyze はバッチのコメントをまとめて分析し、results の対応する位置に結果を設定します
+func (r *BatchRunner) analyze(ctx context.Context, analyzer BatchAnalyzer, requests []BatchRequest, batch []int, results []BatchResult) {
+	items := make([]BatchItem, len(batch))
+	for i, index := range batch {
+		items[i] = BatchItem{ID: fmt.Sprintf("c%d", i+1), Context: requests[index].Context, Prompt: requests[index].Prompt}
+	}
+	if err := ctx.Err(); err != nil {
+		for i, index := range batch {
+			results[index] = BatchResult{ID: items[i].ID, Err: err}
+		}
+		return
+	}
+
+	analyzed := analyzer.AnalyzeBatch(ctx, requests[batch[0]].Instructions, items)
+	for i, index := range batch {
+		results[index] = analyzed[i]
+	}
+}
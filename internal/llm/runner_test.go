@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingAnalyzer は受け取ったバッチを記録し、コンテキストを要約として返すテスト用のLLMです
+type recordingAnalyzer struct {
+	mu      sync.Mutex
+	batches []string
+}
+
+func (a *recordingAnalyzer) AnalyzeBatch(ctx context.Context, instructions string, items []BatchItem) []BatchResult {
+	contexts := make([]string, len(items))
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		contexts[i] = item.Context
+		results[i] = BatchResult{ID: item.ID, Result: &AnalysisResult{Summary: item.Context}}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.batches = append(a.batches, fmt.Sprintf("%s:%s", instructions, strings.Join(contexts, ",")))
+	return results
+}
+
+func TestBatchRunner_Run_GroupsByInstructions(t *testing.T) {
+	// Arrange
+	analyzer := &recordingAnalyzer{}
+	var progress []int
+	runner := &BatchRunner{
+		Parallel:    1,
+		BatchSize:   2,
+		NewAnalyzer: func() (BatchAnalyzer, error) { return analyzer, nil },
+		Progress:    func(done, total int) { progress = append(progress, done) },
+	}
+	requests := []BatchRequest{
+		{Instructions: "go", Context: "a"},
+		{Instructions: "ts", Context: "b"},
+		{Instructions: "go", Context: "c"},
+		{Instructions: "go", Context: "d"},
+	}
+
+	// Act
+	results, err := runner.Run(context.Background(), requests)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"go:a,c", "ts:b", "go:d"}
+	if strings.Join(analyzer.batches, " ") != strings.Join(want, " ") {
+		t.Errorf("expected batches %v, got %v", want, analyzer.batches)
+	}
+	for i, request := range requests {
+		if results[i].Result == nil || results[i].Result.Summary != request.Context {
+			t.Errorf("expected result %d to be for %q, got %+v", i, request.Context, results[i])
+		}
+	}
+	if fmt.Sprint(progress) != "[2 3 4]" {
+		t.Errorf("expected progress after each batch, got %v", progress)
+	}
+}
+
+func TestBatchRunner_Run_Cancelled(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	analyzer := &recordingAnalyzer{}
+	runner := &BatchRunner{
+		Parallel:    2,
+		BatchSize:   1,
+		NewAnalyzer: func() (BatchAnalyzer, error) { return analyzer, nil },
+	}
+
+	// Act
+	results, err := runner.Run(ctx, []BatchRequest{{Context: "a"}, {Context: "b"}})
+
+	// Assert
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(analyzer.batches) != 0 {
+		t.Errorf("expected nothing to be analyzed, got %v", analyzer.batches)
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("expected result %d to be cancelled, got %+v", i, result)
+		}
+	}
+}
+
+func TestBatchRunner_Run_NewAnalyzerFails(t *testing.T) {
+	// Arrange
+	runner := &BatchRunner{
+		Parallel:    2,
+		BatchSize:   1,
+		NewAnalyzer: func() (BatchAnalyzer, error) { return nil, errors.New("no driver") },
+	}
+
+	// Act
+	results, err := runner.Run(context.Background(), []BatchRequest{{Context: "a"}})
+
+	// Assert
+	if err == nil || results != nil {
+		t.Errorf("expected the error without results, got %v, %v", results, err)
+	}
+}
+
+func TestBatchRunner_Run_ClampsParallelAndBatchSize(t *testing.T) {
+	// Arrange
+	analyzer := &recordingAnalyzer{}
+	runner := &BatchRunner{
+		Parallel:    0,
+		BatchSize:   -1,
+		NewAnalyzer: func() (BatchAnalyzer, error) { return analyzer, nil },
+	}
+
+	// Act
+	results, err := runner.Run(context.Background(), []BatchRequest{{Context: "a"}, {Context: "b"}})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(analyzer.batches) != 2 {
+		t.Errorf("expected one comment per batch on a single worker, got %v", analyzer.batches)
+	}
+	for i, result := range results {
+		if result.Result == nil {
+			t.Errorf("expected result %d to be analyzed, got %+v", i, result)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Usage はLLMの呼び出しで使ったトークン数と時間です
+type Usage struct {
+	// Calls は呼び出しの回数です（再試行・修正の依頼を含む）
+	Calls        int
+	InputTokens  int
+	OutputTokens int
+	// EstimatedCalls はトークン数を推定した呼び出しの回数です（CLIのドライバーなど）
+	EstimatedCalls int
+	// Latency は呼び出しにかかった時間の合計です
+	Latency time.Duration
+}
+
+// UsageHandler はLLMを呼び出すたびに、その呼び出しの使用量とともに呼ばれます（失敗した呼び出しを含む）
+type UsageHandler func(driver *Driver, usage Usage)
+
+// Add は別の使用量を足し合わせます
+func (u *Usage) Add(other Usage) {
+	u.Calls += other.Calls
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.EstimatedCalls += other.EstimatedCalls
+	u.Latency += other.Latency
+}
+
+// Estimated はトークン数に推定値を含むかどうかを返します
+func (u Usage) Estimated() bool {
+	return u.EstimatedCalls > 0
+}
+
+// Split は使用量を n 件に均等に分けます（割り切れない分は先頭から1ずつ配分）
+//
+// バッチ分析の1回の呼び出しの使用量を、結果が得られたコメントに割り当てるために使います。
+// 推定したトークン数を含む場合は、全ての件が推定値を含むよう EstimatedCalls を最低1にします
+// （そのため EstimatedCalls の合計は元の値より大きくなることがあります）。
+func (u Usage) Split(n int) []Usage {
+	if n <= 0 {
+		return nil
+	}
+	parts := make([]Usage, n)
+	for i := range parts {
+		parts[i] = Usage{
+			Calls:          share(u.Calls, n, i),
+			InputTokens:    share(u.InputTokens, n, i),
+			OutputTokens:   share(u.OutputTokens, n, i),
+			EstimatedCalls: share(u.EstimatedCalls, n, i),
+			Latency:        u.Latency / time.Duration(n),
+		}
+		if u.Estimated() && parts[i].EstimatedCalls == 0 {
+			parts[i].EstimatedCalls = 1
+		}
+	}
+	return parts
+}
+
+// share は total を n 件に分けたときの i 件目の値を返します
+func share(total, n, i int) int {
+	value := total / n
+	if i < total%n {
+		value++
+	}
+	return value
+}
+
+// DriverUsage はドライバー・モデルごとの使用量です
+type DriverUsage struct {
+	Driver string
+	Model  string
+	Usage
+}
+
+// UsageRecorder は実行中のLLMの呼び出しの使用量をドライバー・モデルごとに集計します
+//
+// 並列のワーカーから呼ばれるため、集計はロックで保護します。
+type UsageRecorder struct {
+	mu     sync.Mutex
+	usages map[[2]string]*Usage
+}
+
+// NewUsageRecorder は空の UsageRecorder を作成します
+func NewUsageRecorder() *UsageRecorder {
+	return &UsageRecorder{usages: make(map[[2]string]*Usage)}
+}
+
+// Record は1回分のLLMの呼び出しの使用量を加算します（UsageHandler として使用）
+func (r *UsageRecorder) Record(driver *Driver, usage Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := [2]string{driver.Name(), driver.Model()}
+	total, ok := r.usages[key]
+	if !ok {
+		total = &Usage{}
+		r.usages[key] = total
+	}
+	total.Add(usage)
+}
+
+// Totals はドライバー・モデルごとの使用量をドライバー名の順で返します
+func (r *UsageRecorder) Totals() []DriverUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	totals := make([]DriverUsage, 0, len(r.usages))
+	for key, usage := range r.usages {
+		totals = append(totals, DriverUsage{Driver: key[0], Model: key[1], Usage: *usage})
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Driver != totals[j].Driver {
+			return totals[i].Driver < totals[j].Driver
+		}
+		return totals[i].Model < totals[j].Model
+	})
+	return totals
+}
+
+// EstimateTokens はテキストのトークン数を推定します
+//
+// トークン数を返さないCLIのドライバーで使います。英数字や記号は約4文字、日本語などは
+// 約1文字で1トークンとして数えます。
+func EstimateTokens(text string) int {
+	ascii, others := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			others++
+		}
+	}
+	return (ascii+3)/4 + others
+}
@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"Check the error here.", 6},
+		{"エラーを確認", 6},
+		{"nil チェック", 5},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestUsage_Split(t *testing.T) {
+	// Arrange
+	usage := Usage{Calls: 1, InputTokens: 100, OutputTokens: 11}
+
+	// Act
+	parts := usage.Split(3)
+
+	// Assert
+	var total Usage
+	for _, part := range parts {
+		total.Add(part)
+	}
+	if total.Calls != 1 || total.InputTokens != 100 || total.OutputTokens != 11 {
+		t.Errorf("expected the parts to add up to the original usage, got %+v", total)
+	}
+	if parts[0].InputTokens != 34 || parts[2].InputTokens != 33 || parts[0].OutputTokens != 4 || parts[2].OutputTokens != 3 {
+		t.Errorf("expected an even split, got %+v", parts)
+	}
+	for i, part := range parts {
+		if part.Estimated() {
+			t.Errorf("expected part %d of exact counts not to be estimated, got %+v", i, part)
+		}
+	}
+}
+
+func TestUsage_Split_KeepsEstimatedOnEveryPart(t *testing.T) {
+	// Arrange: CLIのドライバーでは1回の呼び出しのトークン数が推定値
+	usage := Usage{Calls: 1, InputTokens: 90, OutputTokens: 30, EstimatedCalls: 1}
+
+	// Act
+	parts := usage.Split(3)
+
+	// Assert
+	for i, part := range parts {
+		if !part.Estimated() {
+			t.Errorf("expected part %d to be marked as estimated, got %+v", i, part)
+		}
+		if part.InputTokens != 30 || part.OutputTokens != 10 {
+			t.Errorf("expected part %d to get an even share of the tokens, got %+v", i, part)
+		}
+	}
+}
+
+func TestAnalyzeComment_AccumulatesUsageOfRetriesAndRepairs(t *testing.T) {
+	// Arrange
+	calls := 0
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		calls++
+		switch calls {
+		case 1:
+			return "not json", nil
+		case 2:
+			return `{"summary": "s", "type": "domain"}`, nil
+		default:
+			return `{"summary": "s", "type": "bug"}`, nil
+		}
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetRetryPolicy(RetryPolicy{MaxAttempts: 2})
+	driver.SetMaxRepairs(1)
+	var reported []Usage
+	driver.SetUsageHandler(func(d *Driver, usage Usage) {
+		reported = append(reported, usage)
+	})
+
+	// Act
+	result, err := driver.AnalyzeComment(context.Background(), "analyze this")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(reported) != 3 {
+		t.Fatalf("expected a usage report for each of 3 calls, got %+v", reported)
+	}
+	var want Usage
+	for _, usage := range reported {
+		want.Add(usage)
+	}
+	got := result.Usage
+	if got.Calls != 3 || got.InputTokens != want.InputTokens || got.OutputTokens != want.OutputTokens || !got.Estimated() {
+		t.Errorf("expected the result to carry the usage of every call %+v, got %+v", want, got)
+	}
+	if got.InputTokens < EstimateTokens("analyze this")*3 {
+		t.Errorf("expected the repair prompt to be counted, got %d input tokens", got.InputTokens)
+	}
+}
+
+func TestAnalyzeBatch_SplitsUsageAmongAnsweredComments(t *testing.T) {
+	// Arrange
+	stub := newAPIStub(t, func(w http.ResponseWriter, body map[string]interface{}) {
+		writeJSON(w, map[string]interface{}{
+			"message":           map[string]string{"content": `[{"id": "c1", "summary": "s1", "type": "bug"}, {"id": "c2", "summary": "s2", "type": "bug"}, {"id": "c3", "summary": "s3", "type": "bug"}]`},
+			"prompt_eval_count": 300,
+			"eval_count":        90,
+		})
+	})
+	driver := newConfiguredDriver(t, "local", config.DriverConfig{Type: DriverTypeOllama, Model: "llama3", Endpoint: stub.server.URL})
+	var mu sync.Mutex
+	var total Usage
+	driver.SetUsageHandler(func(d *Driver, usage Usage) {
+		mu.Lock()
+		defer mu.Unlock()
+		total.Add(usage)
+	})
+
+	// Act
+	results := driver.AnalyzeBatch(context.Background(), "instructions", batchItems())
+
+	// Assert
+	if total.Calls != 1 || total.InputTokens != 300 || total.OutputTokens != 90 || total.Estimated() {
+		t.Errorf("expected the API usage to be reported once, got %+v", total)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Fatalf("unexpected error: %v", result.Err)
+		}
+		if result.Result.Usage.InputTokens != 100 || result.Result.Usage.OutputTokens != 30 {
+			t.Errorf("expected an even share of the batch usage for %s, got %+v", result.ID, result.Result.Usage)
+		}
+	}
+}
+
+func TestHTTPAnalyzers_ReportAPIUsage(t *testing.T) {
+	t.Setenv("TEST_API_KEY", "secret")
+	tests := []struct {
+		driverType string
+		response   map[string]interface{}
+		wantInput  int
+		wantOutput int
+		estimated  bool
+	}{
+		{
+			driverType: DriverTypeAnthropic,
+			response: map[string]interface{}{
+				"content": []map[string]string{{"type": "text", "text": `"summary": "s", "type": "bug"}`}},
+				"usage":   map[string]int{"input_tokens": 120, "output_tokens": 15},
+			},
+			wantInput: 120, wantOutput: 15,
+		},
+		{
+			driverType: DriverTypeOpenAI,
+			response: map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": map[string]string{"content": `{"summary": "s", "type": "bug"}`}}},
+				"usage":   map[string]int{"prompt_tokens": 80, "completion_tokens": 12},
+			},
+			wantInput: 80, wantOutput: 12,
+		},
+		{
+			driverType: DriverTypeOpenAI,
+			response: map[string]interface{}{
+				"choices": []map[string]interface{}{{"message": map[string]string{"content": `{"summary": "s", "type": "bug"}`}}},
+			},
+			wantInput: EstimateTokens("analyze this"), wantOutput: EstimateTokens(`{"summary": "s", "type": "bug"}`), estimated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driverType, func(t *testing.T) {
+			// Arrange
+			stub := newAPIStub(t, func(w http.ResponseWriter, body map[string]interface{}) {
+				writeJSON(w, tt.response)
+			})
+			driver := newConfiguredDriver(t, "api", config.DriverConfig{
+				Type:      tt.driverType,
+				Model:     "model",
+				Endpoint:  stub.server.URL,
+				APIKeyEnv: "TEST_API_KEY",
+			})
+
+			// Act
+			result, err := driver.AnalyzeComment(context.Background(), "analyze this")
+
+			// Assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			usage := result.Usage
+			if usage.InputTokens != tt.wantInput || usage.OutputTokens != tt.wantOutput || usage.Estimated() != tt.estimated {
+				t.Errorf("unexpected usage: %+v", usage)
+			}
+			if usage.Latency <= 0 {
+				t.Errorf("expected the latency to be measured, got %s", usage.Latency)
+			}
+		})
+	}
+}
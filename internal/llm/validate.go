@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// 分析結果のスキーマの制限値
+const (
+	// MaxSummaryLength は要約の最大文字数です
+	MaxSummaryLength = 2000
+	// MaxTags はタグの最大数です（プロンプトの "max-5-tags" に対応）
+	MaxTags = 5
+	// MaxTagLength はタグ1つの最大文字数です
+	MaxTagLength = 40
+)
+
+// ValidationError は分析結果がスキーマに違反していることを表すエラーです
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid analysis result: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate は分析結果をスキーマ（種別・関連度・タグ・要約・重要度）と照合します
+//
+// 違反がある場合は全ての違反を含む *ValidationError を返します。
+func (r *AnalysisResult) Validate() error {
+	var problems []string
+
+	summary := strings.TrimSpace(r.Summary)
+	if summary == "" {
+		problems = append(problems, `"summary" must not be empty`)
+	} else if n := utf8.RuneCountInString(summary); n > MaxSummaryLength {
+		problems = append(problems, fmt.Sprintf(`"summary" must be at most %d characters, got %d`, MaxSummaryLength, n))
+	}
+
+	if !models.IsValidCommentType(r.Type) {
+		problems = append(problems, fmt.Sprintf(`"type" must be one of %s, got %q`, commentTypeList(), r.Type))
+	}
+
+	if r.RelevanceScore < 0 || r.RelevanceScore > 1 {
+		problems = append(problems, fmt.Sprintf(`"relevance_score" must be between 0.0 and 1.0, got %v`, r.RelevanceScore))
+	}
+
+	if len(r.Tags) > MaxTags {
+		problems = append(problems, fmt.Sprintf(`"tags" must contain at most %d tags, got %d`, MaxTags, len(r.Tags)))
+	}
+	seen := make(map[string]bool, len(r.Tags))
+	for _, tag := range r.Tags {
+		switch {
+		case strings.TrimSpace(tag) == "":
+			problems = append(problems, `"tags" must not contain empty tags`)
+		case utf8.RuneCountInString(tag) > MaxTagLength:
+			problems = append(problems, fmt.Sprintf(`tag %q must be at most %d characters`, tag, MaxTagLength))
+		case seen[tag]:
+			problems = append(problems, fmt.Sprintf(`tag %q is duplicated`, tag))
+		}
+		seen[tag] = true
+	}
+
+	if r.Severity != "" && !models.IsValidSeverity(r.Severity) {
+		problems = append(problems, fmt.Sprintf(`"severity" must be one of blocker, major, minor, nit, got %q`, r.Severity))
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// commentTypeList は分類体系の種別を "a, b, c" の形式で返します
+func commentTypeList() string {
+	types := make([]string, len(models.CommentTypes))
+	for i, commentType := range models.CommentTypes {
+		types[i] = string(commentType)
+	}
+	return strings.Join(types, ", ")
+}
+
+// buildRepairPrompt はスキーマ違反を指摘して出力の修正を依頼するプロンプトを作成します
+func buildRepairPrompt(prompt string, output []byte, err *ValidationError) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(prompt))
+	b.WriteString("\n\nYour previous response was:\n")
+	b.WriteString(strings.TrimSpace(string(output)))
+	b.WriteString("\n\nIt does not match the required schema:\n")
+	for _, problem := range err.Problems {
+		b.WriteString("- " + problem + "\n")
+	}
+	b.WriteString("\nReturn the corrected JSON only, keeping the parts that were valid, with no other text.\n")
+	return b.String()
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAnalysisResult_Validate(t *testing.T) {
+	valid := AnalysisResult{Summary: "Check errors.", Type: "bug", Tags: []string{"errors"}, RelevanceScore: 0.8, Severity: "major"}
+
+	tests := []struct {
+		name     string
+		modify   func(r *AnalysisResult)
+		problems int
+		contains string
+	}{
+		{"valid", func(r *AnalysisResult) {}, 0, ""},
+		{"type outside taxonomy", func(r *AnalysisResult) { r.Type = "domain" }, 1, `"type" must be one of`},
+		{"score above range", func(r *AnalysisResult) { r.RelevanceScore = 7 }, 1, `"relevance_score"`},
+		{"negative score", func(r *AnalysisResult) { r.RelevanceScore = -1 }, 1, `"relevance_score"`},
+		{"too many tags", func(r *AnalysisResult) {
+			r.Tags = []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+		}, 1, "at most 5 tags"},
+		{"duplicate and empty tags", func(r *AnalysisResult) { r.Tags = []string{"go", "go", " "} }, 2, "duplicated"},
+		{"long tag", func(r *AnalysisResult) { r.Tags = []string{strings.Repeat("x", MaxTagLength+1)} }, 1, "at most 40 characters"},
+		{"empty summary", func(r *AnalysisResult) { r.Summary = "  " }, 1, `"summary" must not be empty`},
+		{"long summary", func(r *AnalysisResult) { r.Summary = strings.Repeat("あ", MaxSummaryLength+1) }, 1, "at most 2000 characters"},
+		{"unknown severity", func(r *AnalysisResult) { r.Severity = "critical" }, 1, `"severity"`},
+		{"multiple problems", func(r *AnalysisResult) { r.Summary, r.Type, r.RelevanceScore = "", "", 1.5 }, 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := valid
+			result.Tags = append([]string(nil), valid.Tags...)
+			tt.modify(&result)
+
+			err := result.Validate()
+
+			if tt.problems == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected ValidationError, got %v", err)
+			}
+			if len(invalid.Problems) != tt.problems {
+				t.Errorf("expected %d problems, got %v", tt.problems, invalid.Problems)
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("expected error to contain %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestAnalyzeComment_RepairsInvalidOutput(t *testing.T) {
+	// Arrange
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		if strings.Contains(input, "Your previous response was") {
+			return `{"summary": "Check errors.", "type": "bug", "relevance_score": 0.7}`, nil
+		}
+		return `{"summary": "Check errors.", "type": "domain", "relevance_score": 7}`, nil
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetMaxRepairs(2)
+	var repairs []int
+	driver.SetRepairHandler(func(d *Driver, repair, maxRepairs int, err error) {
+		repairs = append(repairs, repair)
+	})
+
+	// Act
+	result, err := driver.AnalyzeComment(context.Background(), "analyze this")
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != "bug" || result.RelevanceScore != 0.7 || result.Repairs != 1 {
+		t.Errorf("expected the repaired result, got %+v", result)
+	}
+	if len(repairs) != 1 || repairs[0] != 1 {
+		t.Errorf("expected one repair notification, got %v", repairs)
+	}
+	if len(executor.inputs) != 2 {
+		t.Fatalf("expected 2 LLM calls, got %d", len(executor.inputs))
+	}
+	repairPrompt := executor.inputs[1]
+	for _, want := range []string{"analyze this", `"type": "domain"`, `- "type" must be one of`, `- "relevance_score" must be between 0.0 and 1.0, got 7`} {
+		if !strings.Contains(repairPrompt, want) {
+			t.Errorf("expected repair prompt to contain %q:\n%s", want, repairPrompt)
+		}
+	}
+}
+
+func TestAnalyzeComment_GivesUpAfterMaxRepairs(t *testing.T) {
+	// Arrange
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		return `{"summary": "", "type": "bug"}`, nil
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+	driver.SetMaxRepairs(2)
+
+	// Act
+	_, err := driver.AnalyzeComment(context.Background(), "analyze this")
+
+	// Assert
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if len(executor.inputs) != 3 {
+		t.Errorf("expected 1 call and 2 repairs, got %d calls", len(executor.inputs))
+	}
+}
+
+func TestAnalyzeBatch_InvalidEntryIsAnalyzedIndividually(t *testing.T) {
+	// Arrange
+	executor := &promptExecutor{respond: func(input string) (string, error) {
+		if input == "single c2" {
+			return `{"summary": "s2", "type": "testing"}`, nil
+		}
+		return `[
+			{"id": "c1", "summary": "s1", "type": "bug"},
+			{"id": "c2", "summary": "s2", "type": "tests", "tags": ["a", "b", "c", "d", "e", "f"]},
+			{"id": "c3", "summary": "s3", "type": "bug"}
+		]`, nil
+	}}
+	driver := NewDriver("claude", []string{"-p"})
+	driver.SetExecutor(executor)
+
+	// Act
+	results := driver.AnalyzeBatch(context.Background(), "instructions", batchItems())
+
+	// Assert
+	if results[1].Err != nil || results[1].Result.Type != "testing" || !results[1].Single {
+		t.Errorf("expected c2 to be analyzed individually, got %+v", results[1])
+	}
+	if results[0].Single || results[2].Single {
+		t.Errorf("expected valid entries to be taken from the batch, got %+v", results)
+	}
+}
@@ -0,0 +1,299 @@
+package prompt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+
+	"github.com/pankona/knowledges/pkg/config"
+	"github.com/pankona/knowledges/pkg/models"
+)
+
+// DefaultName は組み込みテンプレートの名前です
+const DefaultName = "default"
+
+//go:embed templates/default.tmpl
+var defaultSource string
+
+// requiredTemplates はプロンプトの組み立てに必要なテンプレート定義です
+var requiredTemplates = []string{"context", "instructions", "single"}
+
+// CommentData はテンプレートに渡すコメントごとの情報です
+type CommentData struct {
+	Repository      string
+	PRNumber        int
+	PRTitle         string
+	FilePath        string
+	LineNumber      int
+	Symbol          string
+	Language        string
+	Author          string
+	Role            string
+	RoleDescription string
+	SeverityMarker  string
+	Comment         string
+}
+
+// Comment はプロンプトを組み立てるコメントとその情報です
+//
+// 収集時・再分析・評価で同じプロンプトになるよう、テンプレートに渡す CommentData は Data で作成します。
+type Comment struct {
+	Repository string
+	PRNumber   int
+	PRTitle    string
+	FilePath   string
+	LineNumber int
+	Symbol     string
+	Language   string
+	Author     string
+	Role       string
+	// Severity は本文の明示的な重要度マーカーです（collector.DetectSeverity、ない場合は空）
+	Severity string
+	Body     string
+}
+
+// Data はテンプレートに渡すコメントの情報を作成します
+func (c Comment) Data() CommentData {
+	return CommentData{
+		Repository:      c.Repository,
+		PRNumber:        c.PRNumber,
+		PRTitle:         c.PRTitle,
+		FilePath:        c.FilePath,
+		LineNumber:      c.LineNumber,
+		Symbol:          symbolDescription(c.Symbol),
+		Language:        c.Language,
+		Author:          c.Author,
+		Role:            c.Role,
+		RoleDescription: roleDescription(c.Role),
+		SeverityMarker:  severityMarkerDescription(c.Severity),
+		Comment:         c.Body,
+	}
+}
+
+// roleDescription はプロンプトに含めるコメント投稿者の役割の説明を返します
+func roleDescription(role string) string {
+	switch role {
+	case models.CommentRolePRAuthor:
+		return "reply by the PR author, not reviewer guidance"
+	case models.CommentRoleThirdParty:
+		return "reply by a participant who did not start the thread"
+	default:
+		return "review feedback from a reviewer"
+	}
+}
+
+// symbolDescription はプロンプトに含めるシンボルの説明を返します
+func symbolDescription(symbol string) string {
+	if symbol == "" {
+		return "unknown"
+	}
+	return symbol
+}
+
+// severityMarkerDescription はプロンプトに含める重要度マーカーの説明を返します
+func severityMarkerDescription(severity string) string {
+	if severity == "" {
+		return "none (infer from tone)"
+	}
+	return severity + " (explicitly marked by the reviewer)"
+}
+
+// Template はコメント分析のプロンプトテンプレートです
+type Template struct {
+	name         string
+	version      string
+	tmpl         *template.Template
+	instructions string
+}
+
+var defaultTemplate = mustParse(DefaultName, "")
+
+// Default は組み込みのテンプレートを返します
+func Default() *Template {
+	return defaultTemplate
+}
+
+// Load はテンプレートファイルを読み込みます
+//
+// ファイルで定義しなかったテンプレート（context / instructions / single）は組み込みの定義を使用します。
+func Load(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return Parse(filepath.Base(path), string(data))
+}
+
+// Parse は組み込みの定義を source で上書きしたテンプレートを作成します
+//
+// バージョンは組み込みの定義と source から計算するため、どちらかが変わると変わります。
+// 全ての定義を空のデータで実行できるか検証します。
+func Parse(name, source string) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(defaultSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse built-in prompt template: %w", err)
+	}
+	if source != "" {
+		if tmpl, err = tmpl.Parse(source); err != nil {
+			return nil, fmt.Errorf("failed to parse prompt template %s: %w", name, err)
+		}
+	}
+
+	for _, required := range requiredTemplates {
+		if tmpl.Lookup(required) == nil {
+			return nil, fmt.Errorf("prompt template %s does not define %q", name, required)
+		}
+	}
+
+	t := &Template{name: name, tmpl: tmpl, version: version(defaultSource, source)}
+	if t.instructions, err = t.execute("instructions", nil); err != nil {
+		return nil, err
+	}
+	if _, err := t.Single(CommentData{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// mustParse はParseに失敗した場合にpanicします（組み込みテンプレート用）
+func mustParse(name, source string) *Template {
+	t, err := Parse(name, source)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// version はテンプレートのソースからバージョンのハッシュを計算します
+func version(sources ...string) string {
+	hash := sha256.New()
+	for _, source := range sources {
+		// ソースの境界が変わっても同じハッシュにならないよう長さを含める
+		fmt.Fprintf(hash, "%d:%s", len(source), source)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// Name はテンプレートの名前を返します（組み込みは "default"、ファイルはファイル名）
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Version はテンプレートのバージョン（ソースのハッシュ）を返します
+func (t *Template) Version() string {
+	return t.version
+}
+
+// Instructions は分析方法の指示を返します
+func (t *Template) Instructions() string {
+	return t.instructions
+}
+
+// Context はコメントごとの情報を返します
+func (t *Template) Context(data CommentData) (string, error) {
+	return t.execute("context", data)
+}
+
+// Single は1件のコメントを分析するプロンプトを返します
+func (t *Template) Single(data CommentData) (string, error) {
+	return t.execute("single", data)
+}
+
+// execute は名前を指定してテンプレートを実行します
+func (t *Template) execute(name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("failed to execute prompt template %s: %w", t.name, err)
+	}
+	return buf.String(), nil
+}
+
+// Set はリポジトリ・言語ごとに使うテンプレートの組です
+type Set struct {
+	fallback     *Template
+	repositories map[string]*Template
+	languages    map[string]*Template
+}
+
+// NewSet は設定ファイルの prompts からテンプレートを読み込みます
+//
+// 同じファイルを複数の箇所で指定した場合は1度だけ読み込みます。
+func NewSet(cfg config.PromptsConfig) (*Set, error) {
+	loaded := make(map[string]*Template)
+	load := func(path string) (*Template, error) {
+		if t, ok := loaded[path]; ok {
+			return t, nil
+		}
+		t, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		loaded[path] = t
+		return t, nil
+	}
+
+	set := &Set{
+		fallback:     Default(),
+		repositories: make(map[string]*Template),
+		languages:    make(map[string]*Template),
+	}
+	if cfg.Default != "" {
+		t, err := load(cfg.Default)
+		if err != nil {
+			return nil, err
+		}
+		set.fallback = t
+	}
+	for repository, path := range cfg.Repositories {
+		t, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		set.repositories[repository] = t
+	}
+	for language, path := range cfg.Languages {
+		t, err := load(path)
+		if err != nil {
+			return nil, err
+		}
+		set.languages[language] = t
+	}
+	return set, nil
+}
+
+// Select はコメントに使うテンプレートを返します
+//
+// リポジトリ、言語、既定（prompts.default または組み込み）の順に優先します。
+func (s *Set) Select(repository, language string) *Template {
+	if t, ok := s.repositories[repository]; ok {
+		return t
+	}
+	if t, ok := s.languages[language]; ok {
+		return t
+	}
+	return s.fallback
+}
+
+// Templates は使用する全てのテンプレートを名前順に返します
+func (s *Set) Templates() []*Template {
+	seen := map[*Template]bool{s.fallback: true}
+	templates := []*Template{s.fallback}
+	for _, group := range []map[string]*Template{s.repositories, s.languages} {
+		for _, t := range group {
+			if !seen[t] {
+				seen[t] = true
+				templates = append(templates, t)
+			}
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].name < templates[j].name
+	})
+	return templates
+}
@@ -0,0 +1,186 @@
+package prompt_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pankona/knowledges/internal/prompt"
+	"github.com/pankona/knowledges/pkg/config"
+)
+
+func testCommentData() prompt.CommentData {
+	return prompt.CommentData{
+		Repository:      "owner/repo",
+		PRNumber:        42,
+		PRTitle:         "Add refunds",
+		FilePath:        "services/payment/refund.go",
+		LineNumber:      17,
+		Symbol:          "RefundService.Refund",
+		Language:        "go",
+		Author:          "reviewer1",
+		Role:            "reviewer",
+		RoleDescription: "review feedback from a reviewer",
+		SeverityMarker:  "none (infer from tone)",
+		Comment:         "Wrap this error with %w.",
+	}
+}
+
+func TestDefault_RendersSinglePrompt(t *testing.T) {
+	// Arrange
+	template := prompt.Default()
+
+	// Act
+	single, err := template.Single(testCommentData())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"Analyze this code review comment",
+		"- PR #42: Add refunds",
+		"- File: services/payment/refund.go (line 17)",
+		"Comment:\nWrap this error with %w.\n",
+		template.Instructions(),
+		"Return only the JSON, no other text.",
+	} {
+		if !strings.Contains(single, want) {
+			t.Errorf("expected prompt to contain %q:\n%s", want, single)
+		}
+	}
+	if template.Name() != prompt.DefaultName || len(template.Version()) != 12 {
+		t.Errorf("unexpected identity: %s (%s)", template.Name(), template.Version())
+	}
+}
+
+func TestComment_Data(t *testing.T) {
+	// Arrange
+	comment := prompt.Comment{
+		Repository: "owner/repo",
+		PRNumber:   42,
+		PRTitle:    "Add refunds",
+		FilePath:   "services/payment/refund.go",
+		LineNumber: 17,
+		Symbol:     "RefundService.Refund",
+		Language:   "go",
+		Author:     "reviewer1",
+		Role:       "reviewer",
+		Body:       "Wrap this error with %w.",
+	}
+
+	// Act
+	data := comment.Data()
+	marked := prompt.Comment{Role: "pr_author", Severity: "nit"}.Data()
+
+	// Assert
+	if data != testCommentData() {
+		t.Errorf("expected %+v, got %+v", testCommentData(), data)
+	}
+	if marked.Symbol != "unknown" || marked.RoleDescription != "reply by the PR author, not reviewer guidance" ||
+		marked.SeverityMarker != "nit (explicitly marked by the reviewer)" {
+		t.Errorf("unexpected descriptions: %+v", marked)
+	}
+}
+
+func TestParse_OverridesDefinitions(t *testing.T) {
+	// Arrange
+	source := `{{define "instructions"}}Focus on payment safety.{{end}}`
+
+	// Act
+	template, err := prompt.Parse("payment.tmpl", source)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if template.Instructions() != "Focus on payment safety." {
+		t.Errorf("expected overridden instructions, got %q", template.Instructions())
+	}
+	context, err := template.Context(testCommentData())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaultContext, _ := prompt.Default().Context(testCommentData())
+	if context != defaultContext {
+		t.Errorf("expected the built-in context, got %q", context)
+	}
+	if template.Version() == prompt.Default().Version() {
+		t.Error("expected a different version for a different template")
+	}
+	again, _ := prompt.Parse("other-name.tmpl", source)
+	if again.Version() != template.Version() {
+		t.Error("expected the version to depend only on the template source")
+	}
+}
+
+func TestParse_InvalidTemplate(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"syntax error", `{{define "context"}}{{.Comment}{{end}}`},
+		{"unknown field", `{{define "context"}}{{.Reviewer}}{{end}}`},
+		{"per-comment data in instructions", `{{define "instructions"}}Language: {{.Language}}{{end}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := prompt.Parse("broken.tmpl", tt.source); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestNewSet_SelectsByRepositoryAndLanguage(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	write := func(name, instructions string) string {
+		path := filepath.Join(dir, name)
+		source := `{{define "instructions"}}` + instructions + `{{end}}`
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatalf("failed to write template: %v", err)
+		}
+		return path
+	}
+	cfg := config.PromptsConfig{
+		Default:      write("base.tmpl", "base"),
+		Repositories: map[string]string{"owner/payments": write("payments.tmpl", "payments")},
+		Languages:    map[string]string{"go": write("go.tmpl", "go"), "golang": filepath.Join(dir, "go.tmpl")},
+	}
+
+	// Act
+	set, err := prompt.NewSet(cfg)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		repository, language, want string
+	}{
+		{"owner/payments", "go", "payments"},
+		{"owner/repo", "go", "go"},
+		{"owner/repo", "python", "base"},
+	}
+	for _, tt := range tests {
+		if got := set.Select(tt.repository, tt.language).Instructions(); got != tt.want {
+			t.Errorf("Select(%s, %s) = %q, want %q", tt.repository, tt.language, got, tt.want)
+		}
+	}
+	if set.Select("owner/repo", "go") != set.Select("owner/repo", "golang") {
+		t.Error("expected the same file to be loaded once")
+	}
+	if len(set.Templates()) != 3 {
+		t.Errorf("expected 3 distinct templates, got %d", len(set.Templates()))
+	}
+}
+
+func TestNewSet_MissingFile(t *testing.T) {
+	_, err := prompt.NewSet(config.PromptsConfig{Languages: map[string]string{"go": "/nonexistent/go.tmpl"}})
+	if err == nil {
+		t.Fatal("expected error for a missing template file")
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"time"
@@ -14,6 +15,11 @@ type Config struct {
 	LLM        LLMConfig        `yaml:"llm"`
 	Database   DatabaseConfig   `yaml:"database"`
 	Collection CollectionConfig `yaml:"collection"`
+	Filter     FilterConfig     `yaml:"filter"`
+	Languages  LanguagesConfig  `yaml:"languages"`
+	Projects   ProjectsConfig   `yaml:"projects"`
+	Prompts    PromptsConfig    `yaml:"prompts"`
+	Pricing    PricingConfig    `yaml:"pricing"`
 	Server     ServerConfig     `yaml:"server"`
 }
 
@@ -25,11 +31,30 @@ type GitHubConfig struct {
 // LLMConfig はLLM関連の設定
 type LLMConfig struct {
 	Primary  string                 `yaml:"primary"`
+	// Fallback はprimaryが失敗したときに順に試すドライバー名
+	Fallback []string               `yaml:"fallback"`
 	Parallel int                    `yaml:"parallel"`
 	Retry    RetryConfig            `yaml:"retry"`
+	// MaxRepairs はスキーマに違反する出力の修正を依頼する最大回数（-1で無効）
+	MaxRepairs int                  `yaml:"max_repairs"`
+	Cache    CacheConfig            `yaml:"cache"`
 	Drivers  map[string]DriverConfig `yaml:"drivers"`
 }
 
+// DriverChain はprimaryとfallbackを試す順に並べたドライバー名を返します
+func (c LLMConfig) DriverChain() []string {
+	chain := []string{c.Primary}
+	seen := map[string]bool{c.Primary: true}
+	for _, name := range c.Fallback {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		chain = append(chain, name)
+	}
+	return chain
+}
+
 // RetryConfig はリトライ設定
 type RetryConfig struct {
 	MaxAttempts  int           `yaml:"max_attempts"`
@@ -37,12 +62,43 @@ type RetryConfig struct {
 	MaxDelay     time.Duration `yaml:"max_delay"`
 }
 
+// CacheConfig はLLMの分析結果のキャッシュ設定
+type CacheConfig struct {
+	// Disabled はキャッシュを使わない場合に true
+	Disabled bool `yaml:"disabled"`
+	// TTL はキャッシュした結果の有効期間
+	TTL time.Duration `yaml:"ttl"`
+}
+
 // DriverConfig はLLMドライバー設定
 type DriverConfig struct {
+	// Type はドライバーの種類（command, anthropic, openai, ollama）。空の場合は command
+	Type    string   `yaml:"type"`
 	Command string   `yaml:"command"`
 	Args    []string `yaml:"args"`
+	// Model はドキュメントに記録するモデル名（model_flag があればコマンドにも渡す、HTTP APIでは必須）
+	Model     string `yaml:"model"`
+	ModelFlag string `yaml:"model_flag"`
+
+	// 以下はHTTP APIのドライバーの設定
+	// Endpoint はAPIのベースURL（空の場合は種類ごとの既定値）
+	Endpoint string `yaml:"endpoint"`
+	// APIKeyEnv はAPIキーを読む環境変数名（空の場合は種類ごとの既定値）
+	APIKeyEnv string `yaml:"api_key_env"`
+	// Temperature はサンプリング温度（未設定の場合はAPIの既定値）
+	Temperature *float64 `yaml:"temperature"`
+	// MaxTokens は応答の最大トークン数（0の場合はAPIの既定値）
+	MaxTokens int `yaml:"max_tokens"`
+	// ResponseFormat はAPIのJSONモードを使うかどうか（json, text）。空の場合は json
+	ResponseFormat string `yaml:"response_format"`
+
+	// Timeout は1回の呼び出しのタイムアウト（コマンドでは0の場合は無制限、HTTP APIでは既定値）
+	Timeout time.Duration `yaml:"timeout"`
 }
 
+// DefaultDriverName はドライバーが設定されていない場合に使うドライバー名です
+const DefaultDriverName = "claude"
+
 // DatabaseConfig はデータベース設定
 type DatabaseConfig struct {
 	Path string `yaml:"path"`
@@ -52,6 +108,81 @@ type DatabaseConfig struct {
 type CollectionConfig struct {
 	BatchSize     int `yaml:"batch_size"`
 	MaxPRsPerRun  int `yaml:"max_prs_per_run"`
+
+	// PR作成者の返信の扱い（keep, drop, downweight）。未設定の場合は keep
+	AuthorReplies string `yaml:"author_replies"`
+	// AuthorReplyWeight は downweight で関連度に掛ける重み（0.0〜1.0、未設定の場合は0.5）
+	AuthorReplyWeight *float64 `yaml:"author_reply_weight"`
+
+	// ニアデュプリケートとみなすSimHashのハミング距離（0〜64、0は完全一致のみ、-1で無効、未設定の場合は3）
+	DuplicateDistance *int `yaml:"duplicate_distance"`
+}
+
+// FilterConfig はコメントフィルタ設定
+type FilterConfig struct {
+	// LearnedPatterns は過去のnoise分類から学習した除外フレーズ（knoiseで追記）
+	LearnedPatterns []string `yaml:"learned_patterns"`
+}
+
+// LanguagesConfig は言語判定の追加ルール（既定のルールより優先）
+type LanguagesConfig struct {
+	Extensions map[string]string    `yaml:"extensions"`
+	Filenames  map[string]string    `yaml:"filenames"`
+	Paths      []LanguagePathConfig `yaml:"paths"`
+}
+
+// LanguagePathConfig はパスパターンによる言語判定ルール
+type LanguagePathConfig struct {
+	Pattern  string `yaml:"pattern"`
+	Language string `yaml:"language"`
+}
+
+// ProjectsConfig はモノレポのプロジェクト判定の設定
+type ProjectsConfig struct {
+	// Markers はプロジェクトのルートを示すファイル名（空の場合は既定値）
+	Markers []string `yaml:"markers"`
+	// Prefixes はプロジェクトとして扱うディレクトリのglobパターン（例: services/*）
+	Prefixes []string `yaml:"prefixes"`
+}
+
+// PromptsConfig はコメント分析のプロンプトテンプレートの設定
+//
+// テンプレートファイルは組み込みのテンプレートの定義（context / instructions / single）を上書きします。
+type PromptsConfig struct {
+	// Default は既定のテンプレートファイル（空の場合は組み込みのテンプレート）
+	Default string `yaml:"default"`
+	// Repositories はリポジトリ（owner/repo）ごとのテンプレートファイル（言語より優先）
+	Repositories map[string]string `yaml:"repositories"`
+	// Languages は言語ごとのテンプレートファイル
+	Languages map[string]string `yaml:"languages"`
+}
+
+// PricingConfig はLLMの料金表（100万トークンあたりの価格）
+//
+// モデル名の価格をドライバー名の価格より優先します。
+type PricingConfig struct {
+	// Currency は価格の通貨（表示用）
+	Currency string           `yaml:"currency"`
+	Models   map[string]Price `yaml:"models"`
+	Drivers  map[string]Price `yaml:"drivers"`
+}
+
+// Price は100万トークンあたりの入力と出力の価格
+type Price struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// Cost はドライバー・モデルのトークン数から料金を計算します（価格が未設定の場合は false）
+func (p PricingConfig) Cost(driver, model string, inputTokens, outputTokens int) (float64, bool) {
+	price, ok := p.Models[model]
+	if !ok || model == "" {
+		price, ok = p.Drivers[driver]
+	}
+	if !ok {
+		return 0, false
+	}
+	return (float64(inputTokens)*price.Input + float64(outputTokens)*price.Output) / 1_000_000, true
 }
 
 // ServerConfig はサーバー設定
@@ -75,7 +206,12 @@ func Load(path string) (*Config, error) {
 
 	// デフォルト値を設定
 	if cfg.LLM.Primary == "" {
-		cfg.LLM.Primary = "claude"
+		cfg.LLM.Primary = DefaultDriverName
+	}
+	if len(cfg.LLM.Drivers) == 0 {
+		cfg.LLM.Drivers = map[string]DriverConfig{
+			DefaultDriverName: {Command: "claude", Args: []string{"-p"}},
+		}
 	}
 	if cfg.LLM.Parallel == 0 {
 		cfg.LLM.Parallel = 3
@@ -86,6 +222,29 @@ func Load(path string) (*Config, error) {
 	if cfg.Collection.MaxPRsPerRun == 0 {
 		cfg.Collection.MaxPRsPerRun = 100
 	}
+	if cfg.Collection.AuthorReplies == "" {
+		cfg.Collection.AuthorReplies = "keep"
+	}
+	if cfg.Collection.AuthorReplyWeight == nil {
+		weight := 0.5
+		cfg.Collection.AuthorReplyWeight = &weight
+	}
+	if cfg.Collection.DuplicateDistance == nil {
+		distance := 3
+		cfg.Collection.DuplicateDistance = &distance
+	}
+
+	switch cfg.Collection.AuthorReplies {
+	case "keep", "drop", "downweight":
+	default:
+		return nil, fmt.Errorf("invalid collection.author_replies: %q (expected keep, drop or downweight)", cfg.Collection.AuthorReplies)
+	}
+	if weight := *cfg.Collection.AuthorReplyWeight; weight < 0 || weight > 1 {
+		return nil, fmt.Errorf("invalid collection.author_reply_weight: %v (expected 0.0 to 1.0)", weight)
+	}
+	if distance := *cfg.Collection.DuplicateDistance; distance < -1 || distance > 64 {
+		return nil, fmt.Errorf("invalid collection.duplicate_distance: %d (expected 0 to 64, or -1 to disable)", distance)
+	}
 	if cfg.Server.Port == 0 {
 		cfg.Server.Port = 8080
 	}
@@ -96,6 +255,13 @@ func Load(path string) (*Config, error) {
 		cfg.Server.WriteTimeout = 30
 	}
 
+	if err := validateDrivers(cfg.LLM); err != nil {
+		return nil, err
+	}
+	if err := validatePricing(cfg.Pricing); err != nil {
+		return nil, err
+	}
+
 	// Retry設定のデフォルト値
 	if cfg.LLM.Retry.MaxAttempts == 0 {
 		cfg.LLM.Retry.MaxAttempts = 3
@@ -106,6 +272,130 @@ func Load(path string) (*Config, error) {
 	if cfg.LLM.Retry.MaxDelay == 0 {
 		cfg.LLM.Retry.MaxDelay = 10 * time.Second
 	}
+	if cfg.LLM.MaxRepairs == 0 {
+		cfg.LLM.MaxRepairs = 2
+	}
+	if cfg.Pricing.Currency == "" {
+		cfg.Pricing.Currency = "USD"
+	}
+	if cfg.LLM.Cache.TTL == 0 {
+		cfg.LLM.Cache.TTL = 30 * 24 * time.Hour
+	}
 
 	return cfg, nil
-}
\ No newline at end of file
+}
+
+// validateDrivers はprimaryとfallbackのドライバーが定義されているか検証します
+func validateDrivers(llm LLMConfig) error {
+	for _, name := range llm.DriverChain() {
+		driver, ok := llm.Drivers[name]
+		if !ok {
+			return fmt.Errorf("llm driver %q is not defined in llm.drivers", name)
+		}
+		switch driver.Type {
+		case "", "command":
+			if driver.Command == "" {
+				return fmt.Errorf("llm.drivers.%s.command is required", name)
+			}
+		case "anthropic", "openai", "ollama":
+			if driver.Model == "" {
+				return fmt.Errorf("llm.drivers.%s.model is required for %s drivers", name, driver.Type)
+			}
+		default:
+			return fmt.Errorf("invalid llm.drivers.%s.type: %q (expected command, anthropic, openai or ollama)", name, driver.Type)
+		}
+		switch driver.ResponseFormat {
+		case "", "json", "text":
+		default:
+			return fmt.Errorf("invalid llm.drivers.%s.response_format: %q (expected json or text)", name, driver.ResponseFormat)
+		}
+	}
+	return nil
+}
+
+// validatePricing は料金表に負の価格がないか検証します
+func validatePricing(pricing PricingConfig) error {
+	for section, prices := range map[string]map[string]Price{"models": pricing.Models, "drivers": pricing.Drivers} {
+		for name, price := range prices {
+			if price.Input < 0 || price.Output < 0 {
+				return fmt.Errorf("invalid pricing.%s.%s: prices must not be negative", section, name)
+			}
+		}
+	}
+	return nil
+}
+
+// AddLearnedPatterns は設定ファイルの filter.learned_patterns にフレーズを追記します
+//
+// yaml.Nodeを直接編集するため、既存のコメントや他のセクションはそのまま残ります。
+func AddLearnedPatterns(path string, patterns []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config root is not a mapping")
+	}
+
+	filter := mappingValue(root, "filter", yaml.MappingNode)
+	learned := mappingValue(filter, "learned_patterns", yaml.SequenceNode)
+
+	existing := make(map[string]bool)
+	for _, node := range learned.Content {
+		existing[node.Value] = true
+	}
+	for _, pattern := range patterns {
+		if existing[pattern] {
+			continue
+		}
+		existing[pattern] = true
+		learned.Content = append(learned.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: pattern})
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// mappingValue はマッピングノードから指定キーの値ノードを取得し、なければ作成します
+func mappingValue(mapping *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value := mapping.Content[i+1]
+			// null の場合は空のノードに置き換える
+			if value.Kind != kind {
+				*value = yaml.Node{Kind: kind}
+			}
+			return value
+		}
+	}
+
+	value := &yaml.Node{Kind: kind}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+	return value
+}
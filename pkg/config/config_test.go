@@ -1,9 +1,12 @@
 package config_test
 
 import (
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pankona/knowledges/pkg/config"
 )
@@ -183,7 +186,412 @@ database:
 	if cfg.Collection.BatchSize != 5 {
 		t.Errorf("expected default batch size 5, got %d", cfg.Collection.BatchSize)
 	}
+	if cfg.LLM.MaxRepairs != 2 {
+		t.Errorf("expected default max repairs 2, got %d", cfg.LLM.MaxRepairs)
+	}
+	if cfg.LLM.Cache.TTL != 30*24*time.Hour || cfg.LLM.Cache.Disabled {
+		t.Errorf("expected the LLM cache to be enabled for 30 days by default, got %s", cfg.LLM.Cache.TTL)
+	}
+	if cfg.Pricing.Currency != "USD" {
+		t.Errorf("expected default currency USD, got %q", cfg.Pricing.Currency)
+	}
 	if cfg.Server.Port != 8080 {
 		t.Errorf("expected default port 8080, got %d", cfg.Server.Port)
 	}
-}
\ No newline at end of file
+}
+
+func TestLoad_LLMCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		wantTTL      time.Duration
+		wantDisabled bool
+	}{
+		{"custom ttl", "llm:\n  cache:\n    ttl: 24h\n", 24 * time.Hour, false},
+		{"disabled", "llm:\n  cache:\n    disabled: true\n", 30 * 24 * time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := config.Load(configPath)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.LLM.Cache.TTL != tt.wantTTL || cfg.LLM.Cache.Disabled != tt.wantDisabled {
+				t.Errorf("expected ttl %s (disabled %v), got %+v", tt.wantTTL, tt.wantDisabled, cfg.LLM.Cache)
+			}
+		})
+	}
+}
+
+func TestLoad_Pricing(t *testing.T) {
+	// Arrange
+	yaml := `pricing:
+  currency: JPY
+  models:
+    claude-sonnet-4: {input: 450, output: 2250}
+  drivers:
+    claude: {input: 300, output: 1500}
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	cfg, err := config.Load(configPath)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Pricing.Currency != "JPY" {
+		t.Errorf("expected currency JPY, got %q", cfg.Pricing.Currency)
+	}
+	tests := []struct {
+		driver, model string
+		want          float64
+		wantOK        bool
+	}{
+		{"claude", "claude-sonnet-4", 0.45 + 2.25, true},
+		{"claude", "", 0.3 + 1.5, true},
+		{"claude", "unknown-model", 0.3 + 1.5, true},
+		{"gemini", "", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := cfg.Pricing.Cost(tt.driver, tt.model, 1000, 1000)
+		if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Cost(%q, %q) = %v, %v, want %v, %v", tt.driver, tt.model, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLoad_NegativePrice(t *testing.T) {
+	// Arrange
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("pricing:\n  drivers:\n    claude: {input: -1, output: 15}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	_, err := config.Load(configPath)
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "pricing.drivers.claude") {
+		t.Errorf("expected an error for the negative price, got %v", err)
+	}
+}
+
+func TestLoad_AuthorReplies(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaml       string
+		wantPolicy string
+		wantWeight float64
+		wantErr    bool
+	}{
+		{
+			name:       "defaults",
+			yaml:       "collection:\n  batch_size: 5\n",
+			wantPolicy: "keep",
+			wantWeight: 0.5,
+		},
+		{
+			name:       "drop",
+			yaml:       "collection:\n  author_replies: drop\n",
+			wantPolicy: "drop",
+			wantWeight: 0.5,
+		},
+		{
+			name:       "custom weight",
+			yaml:       "collection:\n  author_replies: downweight\n  author_reply_weight: 0.2\n",
+			wantPolicy: "downweight",
+			wantWeight: 0.2,
+		},
+		{
+			name:       "explicit zero weight",
+			yaml:       "collection:\n  author_replies: downweight\n  author_reply_weight: 0\n",
+			wantPolicy: "downweight",
+			wantWeight: 0,
+		},
+		{
+			name:    "invalid policy",
+			yaml:    "collection:\n  author_replies: ignore\n",
+			wantErr: true,
+		},
+		{
+			name:    "weight above 1",
+			yaml:    "collection:\n  author_replies: downweight\n  author_reply_weight: 1.5\n",
+			wantErr: true,
+		},
+		{
+			name:    "negative weight",
+			yaml:    "collection:\n  author_reply_weight: -0.1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := config.Load(configPath)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error for invalid author_replies or author_reply_weight")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Collection.AuthorReplies != tt.wantPolicy {
+				t.Errorf("expected author_replies %q, got %q", tt.wantPolicy, cfg.Collection.AuthorReplies)
+			}
+			if *cfg.Collection.AuthorReplyWeight != tt.wantWeight {
+				t.Errorf("expected author_reply_weight %v, got %v", tt.wantWeight, *cfg.Collection.AuthorReplyWeight)
+			}
+		})
+	}
+}
+
+func TestLoad_DuplicateDistance(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    int
+		wantErr bool
+	}{
+		{name: "default", yaml: "collection:\n  batch_size: 5\n", want: 3},
+		{name: "exact matches only", yaml: "collection:\n  duplicate_distance: 0\n", want: 0},
+		{name: "disabled", yaml: "collection:\n  duplicate_distance: -1\n", want: -1},
+		{name: "maximum", yaml: "collection:\n  duplicate_distance: 64\n", want: 64},
+		{name: "above the SimHash size", yaml: "collection:\n  duplicate_distance: 65\n", wantErr: true},
+		{name: "below -1", yaml: "collection:\n  duplicate_distance: -2\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := config.Load(configPath)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error for invalid duplicate_distance")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *cfg.Collection.DuplicateDistance != tt.want {
+				t.Errorf("expected duplicate_distance %d, got %d", tt.want, *cfg.Collection.DuplicateDistance)
+			}
+		})
+	}
+}
+
+func TestAddLearnedPatterns(t *testing.T) {
+	// Arrange
+	configYAML := `# knowledge base config
+github:
+  repositories:
+    - owner/repo # main repository
+
+filter:
+  learned_patterns:
+    - nice catch
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	err := config.AddLearnedPatterns(configPath, []string{"nice catch", "will follow up"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	want := []string{"nice catch", "will follow up"}
+	if !equalStrings(cfg.Filter.LearnedPatterns, want) {
+		t.Errorf("expected learned patterns %v, got %v", want, cfg.Filter.LearnedPatterns)
+	}
+	if len(cfg.GitHub.Repositories) != 1 || cfg.GitHub.Repositories[0] != "owner/repo" {
+		t.Errorf("expected other sections to be preserved, got %v", cfg.GitHub.Repositories)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "# main repository") {
+		t.Errorf("expected comments to be preserved, got:\n%s", data)
+	}
+}
+
+func TestAddLearnedPatterns_CreatesFilterSection(t *testing.T) {
+	// Arrange
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("database:\n  path: ./test.db\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	if err := config.AddLearnedPatterns(configPath, []string{"thanks for the quick"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Assert
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if !equalStrings(cfg.Filter.LearnedPatterns, []string{"thanks for the quick"}) {
+		t.Errorf("unexpected learned patterns: %v", cfg.Filter.LearnedPatterns)
+	}
+	if cfg.Database.Path != "./test.db" {
+		t.Errorf("expected database path to be preserved, got %q", cfg.Database.Path)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLoad_LLMDrivers(t *testing.T) {
+	tests := []struct {
+		name      string
+		yaml      string
+		wantChain []string
+		wantErr   string
+	}{
+		{
+			name:      "default driver",
+			yaml:      "database:\n  path: ./test.db\n",
+			wantChain: []string{"claude"},
+		},
+		{
+			name: "primary with fallback chain",
+			yaml: `
+llm:
+  primary: claude
+  fallback: [gemini, claude, local]
+  drivers:
+    claude:
+      command: claude
+      args: [-p]
+      model: sonnet
+      model_flag: --model
+    gemini:
+      command: gemini
+    local:
+      command: ollama
+      args: [run, llama3]
+`,
+			wantChain: []string{"claude", "gemini", "local"},
+		},
+		{
+			name:    "undefined primary",
+			yaml:    "llm:\n  primary: gemini\n",
+			wantErr: `llm driver "gemini" is not defined`,
+		},
+		{
+			name:    "undefined fallback",
+			yaml:    "llm:\n  fallback: [gemini]\n  drivers:\n    claude:\n      command: claude\n",
+			wantErr: `llm driver "gemini" is not defined`,
+		},
+		{
+			name:    "missing command",
+			yaml:    "llm:\n  drivers:\n    claude:\n      args: [-p]\n",
+			wantErr: "llm.drivers.claude.command is required",
+		},
+		{
+			name: "http drivers",
+			yaml: `
+llm:
+  primary: api
+  fallback: [local]
+  drivers:
+    api:
+      type: anthropic
+      model: claude-sonnet-4-5
+      temperature: 0
+      timeout: 30s
+    local:
+      type: ollama
+      model: llama3
+      endpoint: http://gpu-box:11434
+      response_format: text
+`,
+			wantChain: []string{"api", "local"},
+		},
+		{
+			name:    "http driver without model",
+			yaml:    "llm:\n  drivers:\n    claude:\n      type: openai\n",
+			wantErr: "llm.drivers.claude.model is required for openai drivers",
+		},
+		{
+			name:    "unknown driver type",
+			yaml:    "llm:\n  drivers:\n    claude:\n      type: grpc\n      command: claude\n",
+			wantErr: `invalid llm.drivers.claude.type: "grpc"`,
+		},
+		{
+			name:    "unknown response format",
+			yaml:    "llm:\n  drivers:\n    claude:\n      type: ollama\n      model: llama3\n      response_format: xml\n",
+			wantErr: `invalid llm.drivers.claude.response_format: "xml"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(configPath, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			cfg, err := config.Load(configPath)
+
+			// Assert
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cfg.LLM.DriverChain(); strings.Join(got, ",") != strings.Join(tt.wantChain, ",") {
+				t.Errorf("expected chain %v, got %v", tt.wantChain, got)
+			}
+		})
+	}
+}
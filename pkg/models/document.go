@@ -11,9 +11,15 @@ type Document struct {
 	
 	// ファイル情報
 	FilePath        string    `json:"file_path"`
+	CurrentPath     string    `json:"current_path,omitempty"`
 	DirectoryPath   string    `json:"directory_path"`
+	Project         string    `json:"project,omitempty"`
 	Language        string    `json:"language"`
+	FileRole        string    `json:"file_role,omitempty"`
+	Owners          []string  `json:"owners,omitempty"`
 	LineNumber      *int      `json:"line_number,omitempty"`
+	Symbol          string    `json:"symbol,omitempty"`
+	Staleness       string    `json:"staleness,omitempty"`
 	
 	// PR情報
 	Repository      string    `json:"repository"`
@@ -21,12 +27,32 @@ type Document struct {
 	PRTitle         string    `json:"pr_title"`
 	PRURL           string    `json:"pr_url"`
 	CommentURL      string    `json:"comment_url"`
+	PRAuthor        string    `json:"pr_author"`
 	
 	// メタデータ
 	Author          string    `json:"author"`
+	CommentRole     string    `json:"comment_role"`
 	CommentType     string    `json:"comment_type"`
 	Tags            []string  `json:"tags"`
 	RelevanceScore  float64   `json:"relevance_score"`
+	Severity        string    `json:"severity,omitempty"`
+	AnalysisMethod  string    `json:"analysis_method"`
+	LLMDriver       string    `json:"llm_driver,omitempty"`
+	LLMModel        string    `json:"llm_model,omitempty"`
+	PromptVersion   string    `json:"prompt_version,omitempty"`
+	// RepairCount はスキーマ違反の出力をLLMに修正させた回数です
+	RepairCount     int       `json:"repair_count,omitempty"`
+	// LLM分析に使ったトークン数と時間（再利用・キャッシュの場合はゼロ）
+	LLMInputTokens     int   `json:"llm_input_tokens,omitempty"`
+	LLMOutputTokens    int   `json:"llm_output_tokens,omitempty"`
+	// LLMTokensEstimated はトークン数が推定値（CLIのドライバー）かどうかです
+	LLMTokensEstimated bool  `json:"llm_tokens_estimated,omitempty"`
+	LLMLatencyMS       int64 `json:"llm_latency_ms,omitempty"`
+	
+	// ニアデュプリケート情報
+	TextHash        string    `json:"text_hash,omitempty"`
+	DuplicateGroup  string    `json:"duplicate_group,omitempty"`
+	DuplicateOf     *int64    `json:"duplicate_of,omitempty"`
 	
 	// タイムスタンプ
 	CommentedAt     time.Time `json:"commented_at"`
@@ -34,19 +60,121 @@ type Document struct {
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
-// CommentType の定義
+// CommentType の定義（LLMプロンプトの分類体系と一致させる）
 type CommentType string
 
 const (
-	CommentTypePerformance   CommentType = "performance"
-	CommentTypeSecurity      CommentType = "security"
-	CommentTypeReadability   CommentType = "readability"
-	CommentTypeDomain        CommentType = "domain"
-	CommentTypeTesting       CommentType = "testing"
-	CommentTypeArchitecture  CommentType = "architecture"
-	CommentTypeStyle         CommentType = "style"
-	CommentTypeBug           CommentType = "bug"
-	CommentTypeSuggestion    CommentType = "suggestion"
-	CommentTypeQuestion      CommentType = "question"
-	CommentTypeOther         CommentType = "other"
-)
\ No newline at end of file
+	CommentTypeImplementation CommentType = "implementation"
+	CommentTypeSecurity       CommentType = "security"
+	CommentTypeTesting        CommentType = "testing"
+	CommentTypeBusiness       CommentType = "business"
+	CommentTypeDesign         CommentType = "design"
+	CommentTypeMaintenance    CommentType = "maintenance"
+	CommentTypeExplanation    CommentType = "explanation"
+	CommentTypeBug            CommentType = "bug"
+	CommentTypeNoise          CommentType = "noise"
+)
+
+// CommentTypes は分類体系に含まれる全てのコメント種別です
+var CommentTypes = []CommentType{
+	CommentTypeImplementation,
+	CommentTypeSecurity,
+	CommentTypeTesting,
+	CommentTypeBusiness,
+	CommentTypeDesign,
+	CommentTypeMaintenance,
+	CommentTypeExplanation,
+	CommentTypeBug,
+	CommentTypeNoise,
+}
+
+// IsValidCommentType は分類体系に含まれる種別かどうかを判定します
+func IsValidCommentType(t string) bool {
+	for _, ct := range CommentTypes {
+		if string(ct) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalysisMethod の定義
+const (
+	AnalysisMethodLLM       = "llm"       // LLMによる分析
+	AnalysisMethodHeuristic = "heuristic" // LLM失敗時のルールベース分類（再分析対象）
+)
+
+// CommentRole の定義（PR内でのコメント投稿者の役割）
+const (
+	CommentRoleReviewer   = "reviewer"    // スレッドを起こしたレビュアー
+	CommentRolePRAuthor   = "pr_author"   // PR作成者による返信
+	CommentRoleThirdParty = "third_party" // 他者のスレッドに返信しただけの第三者
+)
+
+// Severity の定義（指摘の重要度、relevance_scoreとは独立）
+const (
+	SeverityBlocker = "blocker" // マージ前に必ず対応が必要
+	SeverityMajor   = "major"   // 対応すべき重要な指摘
+	SeverityMinor   = "minor"   // 任意・好みの範囲の指摘
+	SeverityNit     = "nit"     // 些細な指摘
+)
+
+// Severities は重要度の高い順に並べた全ての重要度です
+var Severities = []string{SeverityBlocker, SeverityMajor, SeverityMinor, SeverityNit}
+
+// IsValidSeverity は定義済みの重要度かどうかを判定します
+func IsValidSeverity(s string) bool {
+	for _, severity := range Severities {
+		if severity == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FileRole の定義（コメント対象ファイルの役割）
+const (
+	FileRoleSource    = "source"    // 通常のソースコード
+	FileRoleTest      = "test"      // テストコード
+	FileRoleConfig    = "config"    // 設定ファイル
+	FileRoleGenerated = "generated" // 自動生成ファイル
+	FileRoleVendored  = "vendored"  // ベンダリングされた外部コード
+	FileRoleMigration = "migration" // DBマイグレーション
+	FileRoleDocs      = "docs"      // ドキュメント
+)
+
+// FileRoles は全てのファイル役割です
+var FileRoles = []string{
+	FileRoleSource, FileRoleTest, FileRoleConfig, FileRoleGenerated,
+	FileRoleVendored, FileRoleMigration, FileRoleDocs,
+}
+
+// IsValidFileRole は定義済みのファイル役割かどうかを判定します
+func IsValidFileRole(r string) bool {
+	for _, role := range FileRoles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Staleness の定義（ドキュメントの参照先がローカルの作業ツリーに残っているか）
+const (
+	StalenessCurrent  = "current"  // 記録時のパスにファイル（とシンボル）が存在する
+	StalenessMoved    = "moved"    // リネームなどで別のパスに移動している
+	StalenessObsolete = "obsolete" // 参照先のファイル・シンボルが存在しない
+)
+
+// Stalenesses は全ての鮮度ステータスです
+var Stalenesses = []string{StalenessCurrent, StalenessMoved, StalenessObsolete}
+
+// IsValidStaleness は定義済みの鮮度ステータスかどうかを判定します
+func IsValidStaleness(s string) bool {
+	for _, staleness := range Stalenesses {
+		if staleness == s {
+			return true
+		}
+	}
+	return false
+}